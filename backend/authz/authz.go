@@ -0,0 +1,99 @@
+// Package authz maps user roles to the permissions they grant and decides
+// whether a user may act on a resource
+package authz
+
+import (
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Permission names an action, as "resource:action"
+type Permission string
+
+// Permissions checked by the API
+const (
+	// HousingWrite allows creating listings and editing them, their photos
+	// and their tour availability
+	HousingWrite Permission = "housing:write"
+	// HousingAssign allows assigning listings to agent accounts and
+	// unlinking them
+	HousingAssign Permission = "housing:assign"
+	// HousingDelete allows deleting listings
+	HousingDelete Permission = "housing:delete"
+	// HousingStats allows reading listing statistics such as favorite counts
+	HousingStats Permission = "housing:stats"
+	// RequestsCreate allows requesting listings
+	RequestsCreate Permission = "requests:create"
+	// RequestsReview allows reading property requests and changing their status
+	RequestsReview Permission = "requests:review"
+	// RequestsDelete allows permanently removing property requests
+	RequestsDelete Permission = "requests:delete"
+	// ToursBook allows booking listing tours
+	ToursBook Permission = "tours:book"
+	// ToursManage allows reading and cancelling tours booked by other users
+	ToursManage Permission = "tours:manage"
+	// UsersManage allows listing users and changing their role and status
+	UsersManage Permission = "users:manage"
+)
+
+// Scope is how far a granted permission reaches
+type Scope int
+
+// Permission scopes, from narrowest to widest
+const (
+	// None denies the permission
+	None Scope = iota
+	// Own grants the permission on the resources the user owns, such as the
+	// listings an agent manages
+	Own
+	// Any grants the permission on every resource
+	Any
+)
+
+// roles lists the permissions granted to each role. Permissions missing
+// from a role are denied, as are all permissions of unknown roles
+var roles = map[string]map[Permission]Scope{
+	models.RoleAdmin: {
+		HousingWrite:   Any,
+		HousingAssign:  Any,
+		HousingDelete:  Any,
+		HousingStats:   Any,
+		RequestsReview: Any,
+		RequestsDelete: Any,
+		ToursManage:    Any,
+		UsersManage:    Any,
+	},
+	models.RoleAgent: {
+		HousingWrite:   Own,
+		RequestsReview: Own,
+		RequestsCreate: Any,
+		ToursBook:      Any,
+	},
+	models.RoleUser: {
+		RequestsCreate: Any,
+		ToursBook:      Any,
+	},
+}
+
+// ScopeOf returns the scope in which user holds p
+func ScopeOf(user models.Users, p Permission) Scope {
+	return roles[user.Role][p]
+}
+
+// Can reports whether user holds p in any scope
+func Can(user models.Users, p Permission) bool {
+	return ScopeOf(user, p) != None
+}
+
+// Allows reports whether user may exercise p on a resource owned by owner.
+// Resources without an owner have a zero owner and need the Any scope
+func Allows(user models.Users, p Permission, owner primitive.ObjectID) bool {
+	switch ScopeOf(user, p) {
+	case Any:
+		return true
+	case Own:
+		return !owner.IsZero() && owner == user.ID
+	default:
+		return false
+	}
+}
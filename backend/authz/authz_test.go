@@ -0,0 +1,73 @@
+package authz
+
+import (
+	"testing"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestScopeOf(t *testing.T) {
+	tests := []struct {
+		role       string
+		permission Permission
+		want       Scope
+	}{
+		{models.RoleAdmin, HousingWrite, Any},
+		{models.RoleAdmin, HousingDelete, Any},
+		{models.RoleAdmin, UsersManage, Any},
+		{models.RoleAdmin, RequestsCreate, None},
+		{models.RoleAgent, HousingWrite, Own},
+		{models.RoleAgent, RequestsReview, Own},
+		{models.RoleAgent, RequestsCreate, Any},
+		{models.RoleAgent, HousingAssign, None},
+		{models.RoleAgent, HousingDelete, None},
+		{models.RoleAgent, UsersManage, None},
+		{models.RoleUser, RequestsCreate, Any},
+		{models.RoleUser, ToursBook, Any},
+		{models.RoleUser, HousingWrite, None},
+		{models.RoleUser, RequestsReview, None},
+		{"", RequestsCreate, None},
+		{"superuser", HousingWrite, None},
+		{models.RoleAdmin, "housing:unknown", None},
+	}
+
+	for _, tt := range tests {
+		user := models.Users{Role: tt.role}
+		if got := ScopeOf(user, tt.permission); got != tt.want {
+			t.Errorf("ScopeOf(%q, %s) = %d, want %d", tt.role, tt.permission, got, tt.want)
+		}
+		if got := Can(user, tt.permission); got != (tt.want != None) {
+			t.Errorf("Can(%q, %s) = %v, want %v", tt.role, tt.permission, got, tt.want != None)
+		}
+	}
+}
+
+func TestAllows(t *testing.T) {
+	agent := models.Users{ID: primitive.NewObjectID(), Role: models.RoleAgent}
+	admin := models.Users{ID: primitive.NewObjectID(), Role: models.RoleAdmin}
+	user := models.Users{ID: primitive.NewObjectID(), Role: models.RoleUser}
+	other := primitive.NewObjectID()
+
+	tests := []struct {
+		name  string
+		user  models.Users
+		owner primitive.ObjectID
+		want  bool
+	}{
+		{"agent on their own listing", agent, agent.ID, true},
+		{"agent on another agent's listing", agent, other, false},
+		{"agent on a listing without an owner", agent, primitive.NilObjectID, false},
+		{"admin on any listing", admin, other, true},
+		{"admin on a listing without an owner", admin, primitive.NilObjectID, true},
+		{"user on a listing they own", user, user.ID, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Allows(tt.user, HousingWrite, tt.owner); got != tt.want {
+				t.Errorf("Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
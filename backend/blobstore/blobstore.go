@@ -0,0 +1,21 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrInvalidKey is returned for keys that are empty or escape the store
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// BlobStore stores binary objects such as uploaded images under
+// slash-separated keys
+type BlobStore interface {
+	// Put stores the contents of r under key, replacing any existing blob
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Delete removes the blob stored under key; missing blobs are ignored
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL the blob is served from
+	URL(key string) string
+}
@@ -0,0 +1,125 @@
+// Package calendar writes events in the iCalendar format (RFC 5545) read by
+// calendar applications
+package calendar
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType is the media type of iCalendar files
+const ContentType = "text/calendar; charset=utf-8"
+
+// prodID identifies the application that produced a calendar
+const prodID = "-//GatorSwamp//Tours//EN"
+
+// maxLineOctets is the longest content line allowed before folding
+const maxLineOctets = 75
+
+// Event is a calendar event. Clients recognise an event by its UID and
+// replace their copy when they see a higher Sequence
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	// Stamp is when the event was last changed
+	Stamp     time.Time
+	Sequence  int
+	Cancelled bool
+}
+
+// Calendar is a named list of events
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Write writes cal to w as an iCalendar object
+func Write(w io.Writer, cal Calendar) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", prodID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if cal.Name != "" {
+		line("X-WR-CALNAME", escape(cal.Name))
+	}
+
+	for _, event := range cal.Events {
+		status := "CONFIRMED"
+		if event.Cancelled {
+			status = "CANCELLED"
+		}
+
+		line("BEGIN", "VEVENT")
+		line("UID", escape(event.UID))
+		line("DTSTAMP", formatTime(event.Stamp))
+		line("DTSTART", formatTime(event.Start))
+		line("DTEND", formatTime(event.End))
+		line("SEQUENCE", strconv.Itoa(event.Sequence))
+		line("STATUS", status)
+		line("SUMMARY", escape(event.Summary))
+		if event.Location != "" {
+			line("LOCATION", escape(event.Location))
+		}
+		if event.Description != "" {
+			line("DESCRIPTION", escape(event.Description))
+		}
+		if event.URL != "" {
+			line("URL", event.URL)
+		}
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// formatTime formats t as a UTC date-time
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escape escapes the characters with a meaning in text values
+func escape(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(text)
+}
+
+// writeLine writes a content line ending in CRLF, folding it into lines of
+// at most maxLineOctets without splitting a UTF-8 character
+func writeLine(w *bufio.Writer, content string) {
+	limit := maxLineOctets
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		w.WriteString(content[:cut])
+		w.WriteString("\r\n ")
+		content = content[cut:]
+		// Continuation lines start with a space that counts towards the limit
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(content)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,94 @@
+// Command migrate converts legacy string-typed housing documents to the
+// numeric schema, backfills their GeoJSON location, marks users from before
+// email verification as verified, lowercases user emails and links the
+// embedded agent of listings to an agent account. It is safe to run more
+// than once.
+//
+//	go run ./cmd/migrate [-dry-run]
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"gatorswamp/config"
+	"gatorswamp/migrations"
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report what would change without writing")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: no .env file found")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	// Only the Mongo settings are needed, so the server's validation is skipped
+	cfg := config.Current()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.Mongo.URI))
+	if err != nil {
+		log.Fatal("Error connecting to MongoDB:", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	db := client.Database(cfg.Mongo.Database)
+	collection := db.Collection("housing")
+
+	report, err := migrations.MigrateHousingNumeric(ctx, collection, *dryRun)
+	if err != nil {
+		log.Fatal("Migration failed:", err)
+	}
+
+	for _, f := range report.Failures {
+		log.Printf("could not migrate %s: field %q value %v: %s", f.ID.Hex(), f.Field, f.Value, f.Reason)
+	}
+	log.Printf("scanned %d, migrated %d, failed %d (dry run: %t)",
+		report.Scanned, report.Migrated, len(report.Failures), *dryRun)
+
+	located, err := migrations.BackfillHousingLocation(ctx, collection, *dryRun)
+	if err != nil {
+		log.Fatal("Location backfill failed:", err)
+	}
+	log.Printf("backfilled location on %d listings (dry run: %t)", located, *dryRun)
+
+	cleared, err := migrations.ClearUnsetHousingLocation(ctx, collection, *dryRun)
+	if err != nil {
+		log.Fatal("Location cleanup failed:", err)
+	}
+	log.Printf("cleared location of %d listings without coordinates (dry run: %t)", cleared, *dryRun)
+
+	verified, err := migrations.VerifyLegacyUsers(ctx, db.Collection("users"), *dryRun)
+	if err != nil {
+		log.Fatal("User verification backfill failed:", err)
+	}
+	log.Printf("marked %d users from before email verification as verified (dry run: %t)", verified, *dryRun)
+
+	emails, err := migrations.NormalizeUserEmails(ctx, db.Collection("users"), *dryRun)
+	if err != nil {
+		log.Fatal("Email normalization failed:", err)
+	}
+	for _, id := range emails.Conflicts {
+		log.Printf("could not lowercase the email of user %s: another user has it in a different case", id.Hex())
+	}
+	log.Printf("lowercased the email of %d users, %d conflicts (dry run: %t)", emails.Normalized, len(emails.Conflicts), *dryRun)
+
+	agents, err := migrations.MigrateEmbeddedAgents(ctx, db, *dryRun)
+	if err != nil {
+		log.Fatal("Agent migration failed:", err)
+	}
+	log.Printf("linked %d listings to %d existing and %d placeholder agents, moved %d tours (dry run: %t)",
+		agents.Linked, agents.Reused, agents.Created, agents.Tours, *dryRun)
+
+	if len(report.Failures) > 0 {
+		os.Exit(1)
+	}
+}
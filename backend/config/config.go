@@ -1,14 +1,177 @@
 package config
 
-import "os"
+import (
+	"strconv"
+	"sync"
+	"time"
+)
 
-// EnvMongoURI returns the MongoDB connection string from environment variables
+// Config holds every setting of the server. It is built by Load from
+// defaults, an optional YAML file and the environment, in that order
+type Config struct {
+	// Env is the deployment environment; "production" enables secure cookies
+	Env  string `yaml:"env"`
+	Port int    `yaml:"port"`
+	// StaticRoot is the directory of the built frontend
+	StaticRoot string `yaml:"staticRoot"`
+	// AllowedOrigins lists the origins allowed to make credentialed CORS requests
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+	// Storage is the storage backend to run on: "mongo" or "memory"
+	Storage string      `yaml:"storage"`
+	Mongo   MongoConfig `yaml:"mongo"`
+	// JWTSecret signs access tokens
+	JWTSecret string `yaml:"jwtSecret"`
+	// AppBaseURL is the public URL of the frontend used in emailed links
+	AppBaseURL string        `yaml:"appBaseURL"`
+	HTTP       HTTPConfig    `yaml:"http"`
+	Uploads    UploadConfig  `yaml:"uploads"`
+	Mail       MailConfig    `yaml:"mail"`
+	Metrics    MetricsConfig `yaml:"metrics"`
+	Alerts     AlertConfig   `yaml:"alerts"`
+}
+
+// MongoConfig configures the MongoDB connection
+type MongoConfig struct {
+	URI      string `yaml:"uri"`
+	Database string `yaml:"database"`
+}
+
+// HTTPConfig holds the server timeouts and proxy settings
+type HTTPConfig struct {
+	// ReadTimeout bounds reading a whole request, body included
+	ReadTimeout time.Duration `yaml:"readTimeout"`
+	// WriteTimeout bounds writing a response, counted from the end of the
+	// request headers
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+	// IdleTimeout bounds how long a keep-alive connection waits for the next request
+	IdleTimeout time.Duration `yaml:"idleTimeout"`
+	// ShutdownTimeout bounds how long in-flight requests may drain on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	// TrustProxy takes the client address from the X-Forwarded-For header
+	// set by a reverse proxy. Enable it only behind a proxy that sets it
+	TrustProxy bool `yaml:"trustProxy"`
+}
+
+// UploadConfig configures where uploaded files are kept and served from
+type UploadConfig struct {
+	Dir       string `yaml:"dir"`
+	URLPrefix string `yaml:"urlPrefix"`
+}
+
+// MailConfig configures outgoing email
+type MailConfig struct {
+	// Driver is the mailer implementation to use: "smtp" or "log"
+	Driver string `yaml:"driver"`
+	From   string `yaml:"from"`
+	// OutboxDir is the directory the log mailer writes messages to, if any
+	OutboxDir string     `yaml:"outboxDir"`
+	SMTP      SMTPConfig `yaml:"smtp"`
+}
+
+// SMTPConfig configures the SMTP mailer
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// MetricsConfig configures how /metrics is exposed
+type MetricsConfig struct {
+	// Addr is the address of a separate admin listener serving /metrics
+	Addr string `yaml:"addr"`
+	// Token is the bearer token protecting /metrics on the main port
+	Token string `yaml:"token"`
+}
+
+// AlertConfig configures saved search alerts
+type AlertConfig struct {
+	// DigestInterval is how often daily digest searches are sent their alerts
+	DigestInterval time.Duration `yaml:"digestInterval"`
+}
+
+// IsProduction reports whether the server runs in production
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+var (
+	current   *Config
+	currentMu sync.Mutex
+)
+
+// Set installs cfg as the process-wide configuration
+func Set(cfg *Config) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = cfg
+}
+
+// Current returns the process-wide configuration. Until Set is called it is
+// read from the environment without a file or validation
+func Current() *Config {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	if current == nil {
+		cfg := Defaults()
+		// Malformed numbers and durations keep their defaults
+		_ = cfg.applyEnv()
+		current = cfg
+	}
+	return current
+}
+
+// EnvMongoURI returns the MongoDB connection string
 func EnvMongoURI() string {
-	uri := os.Getenv("MONGO_URI")
-	return uri
+	return Current().Mongo.URI
 }
 
-// JwtSecretKey returns the JWT secret key from environment variables
+// JwtSecretKey returns the JWT secret key
 func JwtSecretKey() string {
-	return os.Getenv("JWT_SECRET")
+	return Current().JWTSecret
+}
+
+// AppBaseURL returns the public URL of the frontend used in emailed links
+func AppBaseURL() string {
+	return Current().AppBaseURL
+}
+
+// IsProduction reports whether the server runs in production
+func IsProduction() bool {
+	return Current().IsProduction()
+}
+
+// MailDriver returns the mailer implementation to use: "smtp" or "log"
+func MailDriver() string {
+	return Current().Mail.Driver
+}
+
+// MailFrom returns the sender address for outgoing email
+func MailFrom() string {
+	return Current().Mail.From
+}
+
+// MailOutboxDir returns the directory the log mailer writes messages to, if any
+func MailOutboxDir() string {
+	return Current().Mail.OutboxDir
+}
+
+// SMTPHost returns the SMTP server host
+func SMTPHost() string {
+	return Current().Mail.SMTP.Host
+}
+
+// SMTPPort returns the SMTP server port
+func SMTPPort() string {
+	return strconv.Itoa(Current().Mail.SMTP.Port)
+}
+
+// SMTPUsername returns the SMTP login user
+func SMTPUsername() string {
+	return Current().Mail.SMTP.Username
+}
+
+// SMTPPassword returns the SMTP login password
+func SMTPPassword() string {
+	return Current().Mail.SMTP.Password
 }
@@ -0,0 +1,322 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MinJWTSecretLength is the shortest JWT secret the server starts with
+const MinJWTSecretLength = 32
+
+// exampleJWTSecret is the placeholder secret config.example.yaml once
+// shipped with. It is long enough to pass, but is public
+const exampleJWTSecret = "change-me-to-at-least-32-random-characters"
+
+// redacted replaces secrets in printed configuration
+const redacted = "REDACTED"
+
+// Defaults returns the configuration used for settings that are not set
+func Defaults() *Config {
+	return &Config{
+		Env:        "development",
+		Port:       5500,
+		StaticRoot: defaultStaticRoot(),
+		AllowedOrigins: []string{
+			"http://localhost:5173",
+			"http://localhost:3000",
+			"http://localhost:5500",
+			"https://gatorswamp.onrender.com",
+		},
+		Storage:    "mongo",
+		Mongo:      MongoConfig{Database: "Gator-Homes"},
+		AppBaseURL: "http://localhost:5173",
+		HTTP: HTTPConfig{
+			// Leaves room for a full photo gallery upload
+			ReadTimeout:     60 * time.Second,
+			WriteTimeout:    90 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			ShutdownTimeout: 20 * time.Second,
+		},
+		Uploads: UploadConfig{Dir: "uploads", URLPrefix: "/uploads"},
+		Mail: MailConfig{
+			Driver: "log",
+			From:   "no-reply@gatorswamp.local",
+			SMTP:   SMTPConfig{Port: 587},
+		},
+		Alerts: AlertConfig{DigestInterval: 24 * time.Hour},
+	}
+}
+
+// defaultStaticRoot finds the frontend build from the backend directory or,
+// in production, from the directory the binary is started in
+func defaultStaticRoot() string {
+	if _, err := os.Stat("../frontend/dist"); os.IsNotExist(err) {
+		return "../../frontend/dist"
+	}
+	return "../frontend/dist"
+}
+
+// Load builds the configuration from defaults, the YAML file at path when it
+// is not empty and the environment, which takes precedence. A configuration
+// that fails validation is returned together with the error
+func Load(path string) (*Config, error) {
+	cfg := Defaults()
+	if path != "" {
+		if err := cfg.applyFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// applyFile overlays the settings found in a YAML file. Unknown keys are
+// rejected so that typos do not go unnoticed
+func (c *Config) applyFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overlays the settings found in environment variables
+func (c *Config) applyEnv() error {
+	var errs []error
+	envString(&c.Env, "GO_ENV")
+	errs = append(errs, envInt(&c.Port, "PORT"))
+	envString(&c.StaticRoot, "STATIC_ROOT")
+	envList(&c.AllowedOrigins, "CORS_ALLOWED_ORIGINS")
+	envString(&c.Storage, "STORAGE")
+	envString(&c.Mongo.URI, "MONGO_URI")
+	envString(&c.Mongo.Database, "DB_NAME")
+	envString(&c.JWTSecret, "JWT_SECRET")
+	envString(&c.AppBaseURL, "APP_BASE_URL")
+	errs = append(errs,
+		envDuration(&c.HTTP.ReadTimeout, "HTTP_READ_TIMEOUT"),
+		envDuration(&c.HTTP.WriteTimeout, "HTTP_WRITE_TIMEOUT"),
+		envDuration(&c.HTTP.IdleTimeout, "HTTP_IDLE_TIMEOUT"),
+		envDuration(&c.HTTP.ShutdownTimeout, "SHUTDOWN_TIMEOUT"),
+		envBool(&c.HTTP.TrustProxy, "TRUST_PROXY"),
+	)
+	envString(&c.Uploads.Dir, "UPLOAD_DIR")
+	envString(&c.Uploads.URLPrefix, "UPLOAD_URL_PREFIX")
+	envString(&c.Mail.Driver, "MAIL_DRIVER")
+	envString(&c.Mail.From, "MAIL_FROM")
+	envString(&c.Mail.OutboxDir, "MAIL_OUTBOX_DIR")
+	envString(&c.Mail.SMTP.Host, "SMTP_HOST")
+	errs = append(errs, envInt(&c.Mail.SMTP.Port, "SMTP_PORT"))
+	envString(&c.Mail.SMTP.Username, "SMTP_USERNAME")
+	envString(&c.Mail.SMTP.Password, "SMTP_PASSWORD")
+	envString(&c.Metrics.Addr, "METRICS_ADDR")
+	envString(&c.Metrics.Token, "METRICS_TOKEN")
+	errs = append(errs, envDuration(&c.Alerts.DigestInterval, "ALERT_DIGEST_INTERVAL"))
+	return errors.Join(errs...)
+}
+
+// envString sets dst from key when it is set
+func envString(dst *string, key string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = value
+	}
+}
+
+// envList sets dst from a comma-separated list in key when it is set
+func envList(dst *[]string, key string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+// envInt sets dst from key when it is set
+func envInt(dst *int, key string) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a number", key, value)
+	}
+	*dst = n
+	return nil
+}
+
+// envBool sets dst from a boolean such as "true" or "0" in key when it is set
+func envBool(dst *bool, key string) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a boolean", key, value)
+	}
+	*dst = b
+	return nil
+}
+
+// envDuration sets dst from a Go duration such as "30s" in key when it is set
+func envDuration(dst *time.Duration, key string) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a duration", key, value)
+	}
+	*dst = d
+	return nil
+}
+
+// Validate reports every setting the server cannot start with
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.JWTSecret == "" {
+		fail("JWT_SECRET is required")
+	} else if len(c.JWTSecret) < MinJWTSecretLength {
+		fail("JWT_SECRET must be at least %d characters", MinJWTSecretLength)
+	} else if c.JWTSecret == exampleJWTSecret {
+		fail("JWT_SECRET is the placeholder from config.example.yaml; generate a random secret")
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		fail("PORT %d is not a valid port", c.Port)
+	}
+
+	switch c.Storage {
+	case "mongo":
+		if c.Mongo.URI == "" {
+			fail("MONGO_URI is required with mongo storage")
+		}
+		if c.Mongo.Database == "" {
+			fail("DB_NAME is required with mongo storage")
+		}
+	case "memory":
+	default:
+		fail("STORAGE %q must be \"mongo\" or \"memory\"", c.Storage)
+	}
+
+	for _, origin := range c.AllowedOrigins {
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("CORS origin %q must be a URL such as https://example.com", origin)
+		}
+	}
+	if u, err := url.Parse(c.AppBaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		fail("APP_BASE_URL %q must be an absolute URL", c.AppBaseURL)
+	}
+
+	for _, timeout := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", c.HTTP.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.HTTP.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.HTTP.IdleTimeout},
+		{"SHUTDOWN_TIMEOUT", c.HTTP.ShutdownTimeout},
+		{"ALERT_DIGEST_INTERVAL", c.Alerts.DigestInterval},
+	} {
+		if timeout.value <= 0 {
+			fail("%s must be positive", timeout.key)
+		}
+	}
+
+	if c.Uploads.Dir == "" {
+		fail("UPLOAD_DIR is required")
+	}
+	if !strings.HasPrefix(c.Uploads.URLPrefix, "/") || strings.HasSuffix(c.Uploads.URLPrefix, "/") {
+		fail("UPLOAD_URL_PREFIX %q must start and not end with /", c.Uploads.URLPrefix)
+	}
+
+	switch c.Mail.Driver {
+	case "smtp":
+		if c.Mail.SMTP.Host == "" {
+			fail("SMTP_HOST is required with the smtp mail driver")
+		}
+		if c.Mail.SMTP.Port < 1 || c.Mail.SMTP.Port > 65535 {
+			fail("SMTP_PORT %d is not a valid port", c.Mail.SMTP.Port)
+		}
+	case "log":
+	default:
+		fail("MAIL_DRIVER %q must be \"smtp\" or \"log\"", c.Mail.Driver)
+	}
+	if c.Mail.From == "" {
+		fail("MAIL_FROM is required")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy of the configuration with its secrets hidden
+func (c *Config) Redacted() *Config {
+	out := *c
+	out.AllowedOrigins = append([]string(nil), c.AllowedOrigins...)
+	out.JWTSecret = redact(c.JWTSecret)
+	out.Mail.SMTP.Password = redact(c.Mail.SMTP.Password)
+	out.Metrics.Token = redact(c.Metrics.Token)
+	out.Mongo.URI = redactURL(c.Mongo.URI)
+	return &out
+}
+
+// Print writes the configuration as YAML with its secrets hidden
+func (c *Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// redact hides a secret while still showing whether it is set
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactURL hides the password of a connection string, or all of it when it
+// cannot be parsed
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	return u.String()
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateJWTSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr string
+	}{
+		{"missing", "", "JWT_SECRET is required"},
+		{"short", "too-short", "at least 32 characters"},
+		{"example placeholder", exampleJWTSecret, "placeholder"},
+		{"random", "3f9c1e7a52b84d06a1c9e2f47b8d3a65", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Defaults()
+			cfg.Storage = "memory"
+			cfg.JWTSecret = tt.secret
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadExampleNeedsSecret(t *testing.T) {
+	t.Setenv("JWT_SECRET", "")
+	if _, err := Load("../config.example.yaml"); err == nil || !strings.Contains(err.Error(), "JWT_SECRET is required") {
+		t.Errorf("Load(config.example.yaml) error = %v, want the secret to be required", err)
+	}
+}
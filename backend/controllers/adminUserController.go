@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"gatorswamp/authz"
+	"gatorswamp/middlewares"
+	"gatorswamp/models"
+	"gatorswamp/problem"
+	"gatorswamp/services"
+	"github.com/gorilla/mux"
+)
+
+// AdminUserController handles admin-only user management requests
+type AdminUserController struct {
+	userService    *services.UserService
+	requestService *services.PropertyRequestService
+	housingService *services.HousingService
+}
+
+// UpdateRoleBody represents the request body for changing a user's role
+type UpdateRoleBody struct {
+	Role string `json:"role" validate:"required,oneof=admin agent user"`
+}
+
+// ClaimAgentBody represents the request body for handing a placeholder
+// agent account to the agent
+type ClaimAgentBody struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// NewAdminUserController creates a new admin user controller
+func NewAdminUserController(userService *services.UserService, requestService *services.PropertyRequestService, housingService *services.HousingService) *AdminUserController {
+	return &AdminUserController{
+		userService:    userService,
+		requestService: requestService,
+		housingService: housingService,
+	}
+}
+
+// ListUsers returns a page of users, optionally filtered by search text,
+// role and disabled state
+func (c *AdminUserController) ListUsers(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+
+	query := services.UserQuery{
+		Search: queryParams.Get("q"),
+		Role:   queryParams.Get("role"),
+	}
+
+	for key, target := range map[string]*int{"page": &query.Page, "limit": &query.Limit} {
+		if raw := queryParams.Get(key); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil || value <= 0 {
+				problem.Error(w, r, invalidParam(key))
+				return
+			}
+			*target = value
+		}
+	}
+
+	if raw := queryParams.Get("disabled"); raw != "" {
+		disabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			problem.Error(w, r, invalidParam("disabled"))
+			return
+		}
+		query.Disabled = &disabled
+	}
+
+	// Use the service to list users
+	page, err := c.userService.ListUsers(query)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// GetUser returns a user's profile together with their property requests
+func (c *AdminUserController) GetUser(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	user, err := c.userService.GetUserByID(id)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	requests, err := c.requestService.GetRequestsByUser(id)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// Enrich requests with property data
+	enrichedRequests, err := enrichRequests(c.housingService, requests)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	if enrichedRequests == nil {
+		enrichedRequests = []EnrichedPropertyRequest{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user":     services.ToUserResponse(*user),
+		"requests": enrichedRequests,
+	})
+}
+
+// UpdateUserRole promotes or demotes a user
+func (c *AdminUserController) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	var body UpdateRoleBody
+	if err := decodeBody(r, &body); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// Admins cannot give up managing users and lock everyone out
+	if isSelf(r, id) && !authz.Can(models.Users{Role: body.Role}, authz.UsersManage) {
+		problem.Write(w, r, http.StatusBadRequest, "self_role_change", "You cannot change your own role")
+		return
+	}
+
+	user, err := c.userService.SetUserRole(id, body.Role)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services.ToUserResponse(*user))
+}
+
+// DisableUser disables a user's account
+func (c *AdminUserController) DisableUser(w http.ResponseWriter, r *http.Request) {
+	c.setDisabled(w, r, true)
+}
+
+// EnableUser re-enables a disabled user's account
+func (c *AdminUserController) EnableUser(w http.ResponseWriter, r *http.Request) {
+	c.setDisabled(w, r, false)
+}
+
+// setDisabled updates a user's disabled flag
+func (c *AdminUserController) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	if isSelf(r, id) && disabled {
+		problem.Write(w, r, http.StatusBadRequest, "self_disable", "You cannot disable your own account")
+		return
+	}
+
+	user, err := c.userService.SetUserDisabled(id, disabled)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services.ToUserResponse(*user))
+}
+
+// ForcePasswordReset requires a user to reset their password before signing in again
+func (c *AdminUserController) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	user, err := c.userService.RequirePasswordReset(id)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services.ToUserResponse(*user))
+}
+
+// UnlockUser lifts a login lockout by forgetting the user's failed logins
+func (c *AdminUserController) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	if err := c.userService.UnlockLogin(id); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User login unlocked"})
+}
+
+// ClaimAgent gives a placeholder agent account the agent's real email and
+// emails them a link to choose a password
+func (c *AdminUserController) ClaimAgent(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	var body ClaimAgentBody
+	if err := decodeBody(r, &body); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	user, err := c.userService.ClaimPlaceholderAgent(id, body.Email)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services.ToUserResponse(*user))
+}
+
+// isSelf reports whether id is the authenticated user's ID
+func isSelf(r *http.Request, id string) bool {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	return ok && user.ID.Hex() == id
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"gatorswamp/middlewares"
+	"gatorswamp/models"
+	"gatorswamp/problem"
+	"gatorswamp/services"
+)
+
+// AgentController handles the dashboard of agents
+type AgentController struct {
+	housingService *services.HousingService
+	requestService *services.PropertyRequestService
+}
+
+// AgentListing is a listing managed by an agent with the number of its
+// requests in each status
+type AgentListing struct {
+	models.Housing
+	Requests map[string]int `json:"requests"`
+}
+
+// NewAgentController creates a new agent controller
+func NewAgentController(housingService *services.HousingService, requestService *services.PropertyRequestService) *AgentController {
+	return &AgentController{
+		housingService: housingService,
+		requestService: requestService,
+	}
+}
+
+// GetMyListings lists the listings the authenticated agent manages, newest first
+func (c *AgentController) GetMyListings(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	properties, err := c.housingService.GetPropertiesByAgent(user.ID.Hex())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	requests, err := c.requestService.GetRequestsForAgent(user.ID.Hex())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	c.housingService.AddPriceDrops(services.ListingsOf(properties)...)
+
+	listings := make([]AgentListing, len(properties))
+	index := make(map[string]int, len(properties))
+	for i, property := range properties {
+		listings[i] = AgentListing{Housing: property, Requests: map[string]int{}}
+		index[property.ID.Hex()] = i
+	}
+	for _, request := range requests {
+		if i, ok := index[request.PropertyID.Hex()]; ok {
+			listings[i].Requests[request.Status]++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listings)
+}
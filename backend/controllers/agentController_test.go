@@ -0,0 +1,142 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"gatorswamp/controllers"
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// listingUpdate is a PUT /api/housing/{id} body for a listing
+func listingUpdate(name string, agentID string) map[string]any {
+	update := map[string]any{"name": name, "type": "house", "county": "Alachua", "address": "1 Main St", "priceCents": 100000}
+	if agentID != "" {
+		update["agentId"] = agentID
+	}
+	return update
+}
+
+func TestAgentListingOwnership(t *testing.T) {
+	srv := newServer(t)
+	annID, ann := srv.user(t, "ann@ufl.edu", models.RoleAgent)
+	bobID, bob := srv.user(t, "bob@ufl.edu", models.RoleAgent)
+	_, admin := srv.user(t, "admin@ufl.edu", models.RoleAdmin)
+
+	// Agents manage the listings they create, whatever agentId they send
+	var created models.Housing
+	if status := srv.call(t, "POST", "/api/housing/create", ann, listingUpdate("Pool House", bobID), &created); status != http.StatusCreated {
+		t.Fatalf("POST create: status %d", status)
+	}
+	if created.AgentID == nil || created.AgentID.Hex() != annID {
+		t.Fatalf("created agentId = %v, want Ann", created.AgentID)
+	}
+	path := "/api/housing/" + created.ID.Hex()
+
+	tests := []struct {
+		name   string
+		token  string
+		body   map[string]any
+		status int
+	}{
+		{name: "own listing", token: ann, body: listingUpdate("Pool House", ""), status: http.StatusOK},
+		{name: "own ID sent back", token: ann, body: listingUpdate("Pool House", annID), status: http.StatusOK},
+		{name: "handed to another agent", token: ann, body: listingUpdate("Pool House", bobID), status: http.StatusForbidden},
+		{name: "another agent's listing", token: bob, body: listingUpdate("Pool House", ""), status: http.StatusForbidden},
+		{name: "admin", token: admin, body: listingUpdate("Pool House", ""), status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var problem problemBody
+			status := srv.call(t, "PUT", path, tt.token, tt.body, &problem)
+			if status != tt.status {
+				t.Fatalf("PUT: status %d, want %d", status, tt.status)
+			}
+			if status == http.StatusForbidden && problem.Code != "permission_denied" {
+				t.Errorf("code = %q, want permission_denied", problem.Code)
+			}
+		})
+	}
+
+	var property models.Housing
+	if status := srv.call(t, "GET", path, "", nil, &property); status != http.StatusOK {
+		t.Fatalf("GET: status %d", status)
+	}
+	if property.AgentID == nil || property.AgentID.Hex() != annID {
+		t.Errorf("agentId after updates = %v, want Ann", property.AgentID)
+	}
+}
+
+func TestAgentReassignAndUnassign(t *testing.T) {
+	srv := newServer(t)
+	annID, ann := srv.user(t, "ann@ufl.edu", models.RoleAgent)
+	bobID, bob := srv.user(t, "bob@ufl.edu", models.RoleAgent)
+	_, admin := srv.user(t, "admin@ufl.edu", models.RoleAdmin)
+	agent, _ := primitive.ObjectIDFromHex(annID)
+	pool := srv.listing(t, models.Housing{Name: "Pool House", Type: "house", County: "Alachua", AgentID: &agent})
+	path := "/api/housing/" + pool.ID.Hex()
+
+	// Admins hand listings to other agents, who then manage them
+	if status := srv.call(t, "PUT", path, admin, listingUpdate("Pool House", bobID), nil); status != http.StatusOK {
+		t.Fatalf("admin reassigning: status %d", status)
+	}
+	if status := srv.call(t, "PUT", path, ann, listingUpdate("Pool House", ""), nil); status != http.StatusForbidden {
+		t.Errorf("previous agent updating: status %d, want 403", status)
+	}
+	if status := srv.call(t, "PUT", path, bob, listingUpdate("Pool House", ""), nil); status != http.StatusOK {
+		t.Errorf("new agent updating: status %d, want 200", status)
+	}
+
+	// Only admins unlink listings, which then need the Any scope to edit
+	if status := srv.call(t, "DELETE", path+"/agent", bob, nil, nil); status != http.StatusForbidden {
+		t.Errorf("agent unassigning: status %d, want 403", status)
+	}
+	var unlinked models.Housing
+	if status := srv.call(t, "DELETE", path+"/agent", admin, nil, &unlinked); status != http.StatusOK {
+		t.Fatalf("admin unassigning: status %d", status)
+	}
+	if unlinked.AgentID != nil || unlinked.Agent.Name == "" {
+		t.Errorf("unlinked listing agent = %v %+v, want no account and the contact kept", unlinked.AgentID, unlinked.Agent)
+	}
+	if status := srv.call(t, "PUT", path, bob, listingUpdate("Pool House", ""), nil); status != http.StatusForbidden {
+		t.Errorf("agent updating an unlinked listing: status %d, want 403", status)
+	}
+}
+
+func TestGetMyListings(t *testing.T) {
+	srv := newServer(t)
+	annID, ann := srv.user(t, "ann@ufl.edu", models.RoleAgent)
+	bobID, _ := srv.user(t, "bob@ufl.edu", models.RoleAgent)
+	_, user := srv.user(t, "user@ufl.edu", models.RoleUser)
+
+	annObjID, _ := primitive.ObjectIDFromHex(annID)
+	bobObjID, _ := primitive.ObjectIDFromHex(bobID)
+	pool := srv.listing(t, models.Housing{Name: "Pool House", AgentID: &annObjID})
+	srv.listing(t, models.Housing{Name: "Loft", AgentID: &bobObjID})
+	srv.listing(t, models.Housing{Name: "Cabin"})
+
+	request := map[string]any{"propertyId": pool.ID.Hex()}
+	if status := srv.call(t, "POST", "/api/requests/create", user, request, nil); status != http.StatusCreated {
+		t.Fatalf("POST request: status %d", status)
+	}
+
+	var listings []controllers.AgentListing
+	if status := srv.call(t, "GET", "/api/agent/listings", ann, nil, &listings); status != http.StatusOK {
+		t.Fatalf("GET: status %d", status)
+	}
+	if len(listings) != 1 || listings[0].Name != "Pool House" {
+		t.Fatalf("listings = %+v, want only Pool House", listings)
+	}
+	if got := listings[0].Requests[models.StatusPending]; got != 1 {
+		t.Errorf("pending requests = %d, want 1", got)
+	}
+
+	if status := srv.call(t, "GET", "/api/agent/listings", user, nil, nil); status != http.StatusForbidden {
+		t.Errorf("user: status %d, want 403", status)
+	}
+	if status := srv.call(t, "GET", "/api/agent/listings", "", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("anonymous: status %d, want 401", status)
+	}
+}
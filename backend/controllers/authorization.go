@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"net/http"
+
+	"gatorswamp/authz"
+	"gatorswamp/middlewares"
+	"gatorswamp/models"
+	"gatorswamp/problem"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// authorizeListing writes an error response and returns false unless the
+// authenticated user may write the listing, either any listing or one they
+// manage as its agent
+func authorizeListing(w http.ResponseWriter, r *http.Request, housingService *services.HousingService, propertyID string) (models.Users, bool) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return user, false
+	}
+
+	// Users allowed to write any listing need not load it
+	if authz.ScopeOf(user, authz.HousingWrite) == authz.Any {
+		return user, true
+	}
+
+	property, err := housingService.GetPropertyByID(propertyID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return user, false
+	}
+
+	if !authz.Allows(user, authz.HousingWrite, listingOwner(*property)) {
+		middlewares.PermissionDenied(w, r, user, authz.HousingWrite)
+		return user, false
+	}
+	return user, true
+}
+
+// authorizeRequestReview writes an error response and returns false unless
+// the authenticated user may review the request, either any request or one
+// for a listing they manage as its agent
+func authorizeRequestReview(w http.ResponseWriter, r *http.Request, requestService *services.PropertyRequestService, housingService *services.HousingService, requestID string) (models.Users, bool) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return user, false
+	}
+
+	if authz.ScopeOf(user, authz.RequestsReview) == authz.Any {
+		return user, true
+	}
+
+	request, err := requestService.GetRequestByID(requestID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return user, false
+	}
+
+	// Requests for deleted listings have no owner left
+	var owner primitive.ObjectID
+	if property, err := housingService.GetPropertyByID(request.PropertyID.Hex()); err == nil {
+		owner = listingOwner(*property)
+	}
+
+	if !authz.Allows(user, authz.RequestsReview, owner) {
+		middlewares.PermissionDenied(w, r, user, authz.RequestsReview)
+		return user, false
+	}
+	return user, true
+}
+
+// listingOwner returns the agent account managing a listing, or the zero ID
+// for listings without one
+func listingOwner(property models.Housing) primitive.ObjectID {
+	if property.AgentID == nil {
+		return primitive.NilObjectID
+	}
+	return *property.AgentID
+}
@@ -0,0 +1,134 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRoutePermissions(t *testing.T) {
+	srv := newServer(t)
+	_, admin := srv.user(t, "admin@ufl.edu", models.RoleAdmin)
+	_, agent := srv.user(t, "agent@ufl.edu", models.RoleAgent)
+	_, user := srv.user(t, "user@ufl.edu", models.RoleUser)
+	tokens := map[string]string{"admin": admin, "agent": agent, "user": user, "anonymous": ""}
+
+	tests := []struct {
+		name   string
+		method string
+		// path is appended to the path of a fresh listing when it starts
+		// with a slash and used as is otherwise
+		path string
+		want map[string]int
+	}{
+		{
+			name: "delete listing", method: "DELETE", path: "",
+			want: map[string]int{"admin": http.StatusOK, "agent": http.StatusForbidden, "user": http.StatusForbidden, "anonymous": http.StatusUnauthorized},
+		},
+		{
+			name: "unassign agent", method: "DELETE", path: "/agent",
+			want: map[string]int{"admin": http.StatusOK, "agent": http.StatusForbidden, "user": http.StatusForbidden, "anonymous": http.StatusUnauthorized},
+		},
+		{
+			name: "favorite count", method: "GET", path: "/favorites/count",
+			want: map[string]int{"admin": http.StatusOK, "agent": http.StatusForbidden, "user": http.StatusForbidden, "anonymous": http.StatusUnauthorized},
+		},
+		{
+			name: "list users", method: "GET", path: "api/users/admin/users",
+			want: map[string]int{"admin": http.StatusOK, "agent": http.StatusForbidden, "user": http.StatusForbidden, "anonymous": http.StatusUnauthorized},
+		},
+		{
+			name: "agent dashboard", method: "GET", path: "api/agent/listings",
+			want: map[string]int{"admin": http.StatusOK, "agent": http.StatusOK, "user": http.StatusForbidden, "anonymous": http.StatusUnauthorized},
+		},
+	}
+
+	for _, tt := range tests {
+		for role, want := range tt.want {
+			t.Run(tt.name+"/"+role, func(t *testing.T) {
+				path := "/" + tt.path
+				if tt.path == "" || tt.path[0] == '/' {
+					property := srv.listing(t, models.Housing{Name: "Pool House"})
+					path = "/api/housing/" + property.ID.Hex() + tt.path
+				}
+
+				var problem problemBody
+				out := any(&problem)
+				if want == http.StatusOK {
+					out = nil
+				}
+				if status := srv.call(t, tt.method, path, tokens[role], nil, out); status != want {
+					t.Fatalf("%s %s: status %d, want %d", tt.method, path, status, want)
+				}
+				if want == http.StatusForbidden && problem.Code != "permission_denied" {
+					t.Errorf("code = %q, want permission_denied", problem.Code)
+				}
+			})
+		}
+	}
+}
+
+func TestReviewOwnListingRequests(t *testing.T) {
+	srv := newServer(t)
+	annID, ann := srv.user(t, "ann@ufl.edu", models.RoleAgent)
+	bobID, _ := srv.user(t, "bob@ufl.edu", models.RoleAgent)
+	_, admin := srv.user(t, "admin@ufl.edu", models.RoleAdmin)
+	_, student := srv.user(t, "student@ufl.edu", models.RoleUser)
+
+	annObjID, _ := primitive.ObjectIDFromHex(annID)
+	bobObjID, _ := primitive.ObjectIDFromHex(bobID)
+	listings := map[string]*models.Housing{
+		"ann":      srv.listing(t, models.Housing{Name: "Pool House", AgentID: &annObjID}),
+		"bob":      srv.listing(t, models.Housing{Name: "Loft", AgentID: &bobObjID}),
+		"unlinked": srv.listing(t, models.Housing{Name: "Cabin"}),
+	}
+	requests := map[string]string{}
+	for owner, property := range listings {
+		var request models.PropertyRequest
+		if status := srv.call(t, "POST", "/api/requests/create", student, map[string]string{"propertyId": property.ID.Hex()}, &request); status != http.StatusCreated {
+			t.Fatalf("requesting %s: status %d", property.Name, status)
+		}
+		requests[owner] = request.ID.Hex()
+	}
+
+	// Agents review the requests for the listings they manage only
+	tests := []struct {
+		listing string
+		token   string
+		want    int
+	}{
+		{"ann", ann, http.StatusOK},
+		{"bob", ann, http.StatusForbidden},
+		{"unlinked", ann, http.StatusForbidden},
+		{"unlinked", admin, http.StatusOK},
+		{"bob", student, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		path := "/api/requests/" + requests[tt.listing] + "/status"
+		if status := srv.call(t, "PUT", path, tt.token, map[string]string{"status": "approved"}, nil); status != tt.want {
+			t.Errorf("approving the %s request: status %d, want %d", tt.listing, status, tt.want)
+		}
+	}
+
+	// Each role sees the requests in its scope
+	counts := []struct {
+		role  string
+		token string
+		want  int
+	}{
+		{"admin", admin, 3},
+		{"agent", ann, 1},
+		{"user", student, 3},
+	}
+	for _, tt := range counts {
+		var mine []map[string]any
+		if status := srv.call(t, "GET", "/api/requests/my-requests", tt.token, nil, &mine); status != http.StatusOK {
+			t.Fatalf("%s my-requests: status %d", tt.role, status)
+		}
+		if len(mine) != tt.want {
+			t.Errorf("%s sees %d requests, want %d", tt.role, len(mine), tt.want)
+		}
+	}
+}
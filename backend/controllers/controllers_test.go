@@ -0,0 +1,135 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"gatorswamp/blobstore"
+	"gatorswamp/config"
+	"gatorswamp/models"
+	"gatorswamp/repositories/memoryrepo"
+	"gatorswamp/routes"
+	"gatorswamp/services"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMain(m *testing.M) {
+	cfg := config.Defaults()
+	cfg.Storage = "memory"
+	cfg.JWTSecret = "test-secret-0123456789abcdef0123"
+	config.Set(cfg)
+
+	os.Exit(m.Run())
+}
+
+// testServer serves the API on in-memory repositories
+type testServer struct {
+	*httptest.Server
+	svc   *services.Services
+	repos services.Repositories
+}
+
+// newServer starts the API routes the controller tests exercise
+func newServer(t *testing.T) *testServer {
+	t.Helper()
+	repos := memoryrepo.NewRepositories()
+	svc := services.New(repos, blobstore.NewLocalStore(t.TempDir(), "/uploads"))
+
+	r := mux.NewRouter()
+	api := r.PathPrefix("/api").Subrouter()
+	routes.SetupUserRoutes(api.PathPrefix("/users").Subrouter(), svc)
+	routes.SetupHousingRoutes(api.PathPrefix("/housing").Subrouter(), svc)
+	routes.SetupRequestRoutes(api.PathPrefix("/requests").Subrouter(), svc)
+	routes.SetupFavoriteRoutes(api.PathPrefix("/favorites").Subrouter(), svc)
+	routes.SetupAgentRoutes(api.PathPrefix("/agent").Subrouter(), svc)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return &testServer{Server: srv, svc: svc, repos: repos}
+}
+
+// user registers a verified user with the given role and returns its ID
+// and access token
+func (s *testServer) user(t *testing.T, email, role string) (string, string) {
+	t.Helper()
+	res, err := s.svc.Users.CreateUser(models.Users{FirstName: "Test", LastName: "User", Email: email, Password: "Str0ngPass"})
+	if err != nil {
+		t.Fatalf("CreateUser(%q) error = %v", email, err)
+	}
+	if role != models.RoleUser {
+		if _, err := s.svc.Users.SetUserRole(res.User.ID, role); err != nil {
+			t.Fatalf("SetUserRole(%q) error = %v", role, err)
+		}
+	}
+
+	id, _ := primitive.ObjectIDFromHex(res.User.ID)
+	verified := true
+	if _, err := s.repos.Users.Update(context.Background(), id, services.UserPatch{EmailVerified: &verified}); err != nil {
+		t.Fatalf("verifying %q: %v", email, err)
+	}
+	return res.User.ID, res.Token
+}
+
+// listing adds a listing and returns it
+func (s *testServer) listing(t *testing.T, property models.Housing) *models.Housing {
+	t.Helper()
+	if property.Address == "" {
+		property.Address = "1 Main St"
+	}
+	if property.PriceCents == 0 {
+		property.PriceCents = 100000
+	}
+	created, err := s.svc.Housing.CreateProperty(property)
+	if err != nil {
+		t.Fatalf("CreateProperty(%q) error = %v", property.Name, err)
+	}
+	return created
+}
+
+// call sends a request to the API and decodes the JSON response into out,
+// returning the status code
+func (s *testServer) call(t *testing.T, method, path, token string, body any, out any) int {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("encoding body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decoding response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// problemBody is the part of an error response the tests check
+type problemBody struct {
+	Code string `json:"code"`
+}
@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"fmt"
+
+	"gatorswamp/services"
+)
+
+// Errors reported by the controllers themselves
+var (
+	errInvalidBody  = services.NewError(services.ErrValidation, "invalid_body", "Invalid request body")
+	errUnauthorized = services.NewError(services.ErrUnauthorized, "unauthorized", "Unauthorized")
+)
+
+// invalidParam reports a query parameter that could not be parsed
+func invalidParam(name string) error {
+	return services.InvalidField(name, "invalid", "invalid value for %s", name)
+}
+
+// requiredParam reports a missing query parameter
+func requiredParam(name string, format string, args ...any) error {
+	return services.InvalidField(name, "required", "%s", fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"gatorswamp/middlewares"
+	"gatorswamp/models"
+	"gatorswamp/problem"
+	"gatorswamp/services"
+	"github.com/gorilla/mux"
+)
+
+// FavoriteController handles HTTP requests related to saved listings
+type FavoriteController struct {
+	favoriteService *services.FavoriteService
+	housingService  *services.HousingService
+}
+
+// NewFavoriteController creates a new favorite controller
+func NewFavoriteController(favoriteService *services.FavoriteService, housingService *services.HousingService) *FavoriteController {
+	return &FavoriteController{
+		favoriteService: favoriteService,
+		housingService:  housingService,
+	}
+}
+
+// EnrichedFavorite includes property details with a favorite
+type EnrichedFavorite struct {
+	models.Favorite
+	Property models.Housing `json:"property"`
+}
+
+// GetMyFavorites lists the authenticated user's saved listings, newest first
+func (c *FavoriteController) GetMyFavorites(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	favorites, err := c.favoriteService.GetFavoritesByUser(user.ID.Hex())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrichFavorites(c.housingService, favorites))
+}
+
+// AddFavorite saves a listing for the authenticated user. Saving a listing
+// that is already saved returns the existing favorite
+func (c *FavoriteController) AddFavorite(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	propertyID := mux.Vars(r)["propertyId"]
+
+	favorite, created, err := c.favoriteService.AddFavorite(user.ID.Hex(), propertyID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(favorite)
+}
+
+// RemoveFavorite removes a listing from the authenticated user's favorites
+func (c *FavoriteController) RemoveFavorite(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	propertyID := mux.Vars(r)["propertyId"]
+
+	if err := c.favoriteService.RemoveFavorite(user.ID.Hex(), propertyID); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Favorite removed successfully"})
+}
+
+// GetFavoriteCount returns how many users saved a listing (admin only)
+func (c *FavoriteController) GetFavoriteCount(w http.ResponseWriter, r *http.Request) {
+	propertyID := mux.Vars(r)["id"]
+
+	count, err := c.favoriteService.CountFavorites(propertyID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"propertyId":    propertyID,
+		"favoriteCount": count,
+	})
+}
+
+// enrichFavorites adds property data to favorites. Favorites whose listing
+// no longer exists are left out
+func enrichFavorites(housingService *services.HousingService, favorites []models.Favorite) []EnrichedFavorite {
+	enriched := []EnrichedFavorite{}
+
+	for _, favorite := range favorites {
+		property, err := housingService.GetPropertyByID(favorite.PropertyID.Hex())
+		if err != nil {
+			continue
+		}
+
+		enriched = append(enriched, EnrichedFavorite{
+			Favorite: favorite,
+			Property: *property,
+		})
+	}
+
+	listings := make([]*models.Housing, len(enriched))
+	for i := range enriched {
+		listings[i] = &enriched[i].Property
+	}
+	housingService.AddPriceDrops(listings...)
+	return enriched
+}
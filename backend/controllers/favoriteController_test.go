@@ -0,0 +1,55 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"gatorswamp/controllers"
+	"gatorswamp/models"
+)
+
+func TestFavoriteRoutes(t *testing.T) {
+	srv := newServer(t)
+	_, token := srv.user(t, "gator@ufl.edu", models.RoleUser)
+	_, adminToken := srv.user(t, "admin@ufl.edu", models.RoleAdmin)
+	pool := srv.listing(t, models.Housing{Name: "Pool House"})
+	path := "/api/favorites/" + pool.ID.Hex()
+
+	var favorite models.Favorite
+	if status := srv.call(t, "PUT", path, token, nil, &favorite); status != http.StatusCreated {
+		t.Fatalf("first PUT: status %d, want %d", status, http.StatusCreated)
+	}
+	if status := srv.call(t, "PUT", path, token, nil, &favorite); status != http.StatusOK {
+		t.Fatalf("second PUT: status %d, want %d", status, http.StatusOK)
+	}
+
+	var favorites []controllers.EnrichedFavorite
+	if status := srv.call(t, "GET", "/api/favorites", token, nil, &favorites); status != http.StatusOK {
+		t.Fatalf("GET: status %d", status)
+	}
+	if len(favorites) != 1 || favorites[0].Property.Name != "Pool House" {
+		t.Errorf("favorites = %+v, want the pool house with its details", favorites)
+	}
+
+	var count struct {
+		FavoriteCount int64 `json:"favoriteCount"`
+	}
+	countPath := "/api/housing/" + pool.ID.Hex() + "/favorites/count"
+	if status := srv.call(t, "GET", countPath, adminToken, nil, &count); status != http.StatusOK || count.FavoriteCount != 1 {
+		t.Errorf("admin count: status %d, count %d, want 1", status, count.FavoriteCount)
+	}
+	var problem problemBody
+	if status := srv.call(t, "GET", countPath, token, nil, &problem); status != http.StatusForbidden {
+		t.Errorf("user count: status %d, want %d", status, http.StatusForbidden)
+	}
+
+	if status := srv.call(t, "DELETE", path, token, nil, &problem); status != http.StatusOK {
+		t.Fatalf("DELETE: status %d", status)
+	}
+	if status := srv.call(t, "DELETE", path, token, nil, &problem); status != http.StatusNotFound || problem.Code != "favorite_not_found" {
+		t.Errorf("second DELETE: status %d, code %q, want %d favorite_not_found", status, problem.Code, http.StatusNotFound)
+	}
+	if status := srv.call(t, "GET", "/api/favorites", "", nil, &problem); status != http.StatusUnauthorized {
+		t.Errorf("GET without a token: status %d, want %d", status, http.StatusUnauthorized)
+	}
+}
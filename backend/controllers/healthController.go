@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// HealthController answers the orchestrator's liveness and readiness probes
+type HealthController struct {
+	ping  func(ctx context.Context) error
+	ready atomic.Bool
+}
+
+// NewHealthController creates a health controller. ping checks that the
+// database is reachable and may be nil when there is nothing to check. The
+// controller reports not ready until SetReady(true) is called
+func NewHealthController(ping func(ctx context.Context) error) *HealthController {
+	return &HealthController{ping: ping}
+}
+
+// SetReady marks whether startup has finished and the server should receive
+// traffic. It is cleared again while shutting down
+func (hc *HealthController) SetReady(ready bool) {
+	hc.ready.Store(ready)
+}
+
+// Healthz reports that the process is alive
+func (hc *HealthController) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz reports whether the server can serve traffic: indexes are ensured,
+// it is not shutting down and the database answers a ping
+func (hc *HealthController) Readyz(w http.ResponseWriter, r *http.Request) {
+	if !hc.ready.Load() {
+		writeHealth(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "error": "not ready"})
+		return
+	}
+
+	if hc.ping != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		if err := hc.ping(ctx); err != nil {
+			writeHealth(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "error": "database unreachable"})
+			return
+		}
+	}
+
+	writeHealth(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// writeHealth writes an uncached probe response
+func writeHealth(w http.ResponseWriter, status int, body map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
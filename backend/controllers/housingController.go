@@ -2,14 +2,19 @@ package controllers
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
 
+	"gatorswamp/authz"
 	"gatorswamp/middlewares"
 	"gatorswamp/models"
+	"gatorswamp/problem"
 	"gatorswamp/services"
+	"gatorswamp/validation"
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // HousingController handles HTTP requests related to housing properties
@@ -18,8 +23,7 @@ type HousingController struct {
 }
 
 // NewHousingController creates a new housing controller
-func NewHousingController(collection *mongo.Collection) *HousingController {
-	housingService := services.NewHousingService(collection)
+func NewHousingController(housingService *services.HousingService) *HousingController {
 	return &HousingController{
 		housingService: housingService,
 	}
@@ -27,64 +31,78 @@ func NewHousingController(collection *mongo.Collection) *HousingController {
 
 // CreateHousingRequest represents the request body for creating a housing property
 type CreateHousingRequest struct {
-	Type      string       `json:"type"`
-	Name      string       `json:"name"`
-	Image     string       `json:"image"`
-	County    string       `json:"county"`
-	Address   string       `json:"address"`
-	Bedrooms  string       `json:"bedrooms"`
-	Bathrooms string       `json:"bathrooms"`
-	Surface   string       `json:"surface"`
-	Year      string       `json:"year"`
-	Price     string       `json:"price"`
-	Latitude  float64      `json:"latitude"`
-	Longitude float64      `json:"longitude"`
-	Agent     models.Agent `json:"agent"`
-}
-
-// CreateHousing handles the creation of a new housing property
+	Type        string       `json:"type"`
+	Name        string       `json:"name"`
+	Image       string       `json:"image"`
+	County      string       `json:"county"`
+	Address     string       `json:"address"`
+	Description string       `json:"description"`
+	Bedrooms    int          `json:"bedrooms"`
+	Bathrooms   float64      `json:"bathrooms"`
+	Surface     float64      `json:"surface"`
+	Year        int          `json:"year"`
+	PriceCents  int64        `json:"priceCents"`
+	Currency    string       `json:"currency"`
+	Latitude    float64      `json:"latitude"`
+	Longitude   float64      `json:"longitude"`
+	Agent       models.Agent `json:"agent"`
+	// AgentID assigns the listing to an agent account, whose contact
+	// details replace Agent. Agents always manage the listings they save
+	AgentID *primitive.ObjectID `json:"agentId"`
+}
+
+// toModel copies the request fields onto a housing listing
+func (req CreateHousingRequest) toModel() models.Housing {
+	return models.Housing{
+		Type:        req.Type,
+		Name:        req.Name,
+		Image:       req.Image,
+		County:      req.County,
+		Address:     req.Address,
+		Description: req.Description,
+		Bedrooms:    req.Bedrooms,
+		Bathrooms:   req.Bathrooms,
+		Surface:     req.Surface,
+		Year:        req.Year,
+		PriceCents:  req.PriceCents,
+		Currency:    req.Currency,
+		Latitude:    req.Latitude,
+		Longitude:   req.Longitude,
+		Agent:       req.Agent,
+		AgentID:     req.AgentID,
+	}
+}
+
+// CreateHousing handles the creation of a new housing property. Users who
+// may only write their own listings become the listing's agent
 func (h *HousingController) CreateHousing(w http.ResponseWriter, r *http.Request) {
-	// Verify admin role
 	user, ok := middlewares.GetUserFromContext(r.Context())
-	if !ok || user.Role != "admin" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Admin access required"})
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
 		return
 	}
 
 	var req CreateHousingRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := decodeJSON(r, &req)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		problem.Error(w, r, err)
 		return
 	}
 
-	// Create a new housing model
-	housing := models.Housing{
-		Type:      req.Type,
-		Name:      req.Name,
-		Image:     req.Image,
-		County:    req.County,
-		Address:   req.Address,
-		Bedrooms:  req.Bedrooms,
-		Bathrooms: req.Bathrooms,
-		Surface:   req.Surface,
-		Year:      req.Year,
-		Price:     req.Price,
-		Latitude:  req.Latitude,
-		Longitude: req.Longitude,
-		Agent:     req.Agent,
+	// Create a new housing model and check it against the listing rules
+	property := req.toModel()
+	if authz.ScopeOf(user, authz.HousingWrite) == authz.Own {
+		property.AgentID = &user.ID
+	}
+	if err := validation.Struct(property); err != nil {
+		problem.Error(w, r, err)
+		return
 	}
 
 	// Use the service to create the housing
-	createdHousing, err := h.housingService.CreateProperty(housing)
+	createdHousing, err := h.housingService.CreateProperty(property)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Error(w, r, err)
 		return
 	}
 
@@ -93,21 +111,57 @@ func (h *HousingController) CreateHousing(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(createdHousing)
 }
 
-// GetAllHousing handles retrieving all housing properties
+// GetAllHousing handles retrieving a page of housing properties
 func (h *HousingController) GetAllHousing(w http.ResponseWriter, r *http.Request) {
-	// Use the service to get all properties
-	properties, err := h.housingService.GetAllProperties(bson.M{})
+	page, err := parsePageRequest(r.URL.Query())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// Use the service to get the requested page of properties
+	properties, err := h.housingService.GetAllProperties(services.HousingFilter{}, page)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Error(w, r, err)
 		return
 	}
+	h.housingService.AddPriceDrops(services.ListingsOf(properties.Items)...)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(properties)
 }
 
+// GetHousingFacets handles retrieving the counties and types listings have
+func (h *HousingController) GetHousingFacets(w http.ResponseWriter, r *http.Request) {
+	facets, err := h.housingService.GetFacets()
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(facets)
+}
+
+// parsePageRequest reads the limit, cursor and sort parameters
+func parsePageRequest(query url.Values) (services.PageRequest, error) {
+	page := services.PageRequest{
+		Cursor: query.Get("cursor"),
+		Sort:   query.Get("sort"),
+		Order:  query.Get("order"),
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return page, invalidParam("limit")
+		}
+		page.Limit = limit
+	}
+
+	return page.Normalize()
+}
+
 // GetHousingByID handles retrieving a housing property by its ID
 func (h *HousingController) GetHousingByID(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
@@ -116,64 +170,53 @@ func (h *HousingController) GetHousingByID(w http.ResponseWriter, r *http.Reques
 	// Use the service to get the property by ID
 	property, err := h.housingService.GetPropertyByID(id)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Error(w, r, err)
 		return
 	}
+	h.housingService.AddPriceDrops(property)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(property)
 }
 
-// UpdateHousing handles updating an existing housing property
+// UpdateHousing handles updating an existing housing property. Users who
+// may only write their own listings stay the listing's agent
 func (h *HousingController) UpdateHousing(w http.ResponseWriter, r *http.Request) {
-	// Verify admin role
-	user, ok := middlewares.GetUserFromContext(r.Context())
-	if !ok || user.Role != "admin" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Admin access required"})
-		return
-	}
-
 	params := mux.Vars(r)
 	id := params["id"]
 
+	user, ok := authorizeListing(w, r, h.housingService, id)
+	if !ok {
+		return
+	}
+
 	var req CreateHousingRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := decodeJSON(r, &req)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		problem.Error(w, r, err)
 		return
 	}
 
-	// Convert to bson.M for update operation
-	updates := bson.M{
-		"$set": bson.M{
-			"type":      req.Type,
-			"name":      req.Name,
-			"image":     req.Image,
-			"county":    req.County,
-			"address":   req.Address,
-			"bedrooms":  req.Bedrooms,
-			"bathrooms": req.Bathrooms,
-			"surface":   req.Surface,
-			"year":      req.Year,
-			"price":     req.Price,
-			"latitude":  req.Latitude,
-			"longitude": req.Longitude,
-			"agent":     req.Agent,
-		},
+	// Leaving out agentId keeps the listing's agent. Only users who may
+	// assign listings can hand them to another agent; the agent managing
+	// the listing may send their own ID back
+	changes := req.toModel()
+	if changes.AgentID != nil && !authz.Can(user, authz.HousingAssign) {
+		if *changes.AgentID != user.ID {
+			middlewares.PermissionDenied(w, r, user, authz.HousingAssign)
+			return
+		}
+		changes.AgentID = nil
+	}
+	if err := validation.Struct(changes); err != nil {
+		problem.Error(w, r, err)
+		return
 	}
 
 	// Use the service to update the property
-	updatedHousing, err := h.housingService.UpdateProperty(id, updates)
+	updatedHousing, err := h.housingService.UpdateProperty(id, changes, user.ID.Hex())
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Error(w, r, err)
 		return
 	}
 
@@ -182,30 +225,45 @@ func (h *HousingController) UpdateHousing(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(updatedHousing)
 }
 
-// DeleteHousing handles deleting a housing property
-func (h *HousingController) DeleteHousing(w http.ResponseWriter, r *http.Request) {
-	// Verify admin role
-	user, ok := middlewares.GetUserFromContext(r.Context())
-	if !ok || user.Role != "admin" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Admin access required"})
+// UnassignAgent unlinks a housing property from its agent account
+func (h *HousingController) UnassignAgent(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	property, err := h.housingService.UnassignAgent(id)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(property)
+}
+
+// GetPriceHistory lists the price changes of a housing property, newest first
+func (h *HousingController) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	history, err := h.housingService.GetPriceHistory(id)
+	if err != nil {
+		problem.Error(w, r, err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// DeleteHousing handles deleting a housing property
+func (h *HousingController) DeleteHousing(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id := params["id"]
 
 	// Use the service to delete the property
 	err := h.housingService.DeleteProperty(id)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		if err.Error() == "property not found" {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Error(w, r, err)
 		return
 	}
 
@@ -213,31 +271,289 @@ func (h *HousingController) DeleteHousing(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(map[string]string{"message": "Housing deleted successfully"})
 }
 
-// SearchHousing handles searching for housing properties
+// SearchHousing handles searching for housing properties, answering with a
+// page of the matches like GetAllHousing. With a q parameter the results are
+// instead ranked by relevance to the free text
 func (h *HousingController) SearchHousing(w http.ResponseWriter, r *http.Request) {
-	queryParams := r.URL.Query()
+	query := r.URL.Query()
+	if query.Has("q") {
+		h.searchHousingText(w, r)
+		return
+	}
 
-	// Extract search parameters
-	searchParams := make(map[string]interface{})
+	filter, err := parseHousingFilter(query)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
 
-	if county := queryParams.Get("county"); county != "" {
-		searchParams["county"] = county
+	page, err := parsePageRequest(query)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
 	}
 
-	if minBedroomsStr := queryParams.Get("minBedrooms"); minBedroomsStr != "" {
-		// Parse to int and add to search params (error handling omitted for brevity)
-		searchParams["minBedrooms"] = minBedroomsStr
+	// Use the service to get the requested page of matches
+	properties, err := h.housingService.GetAllProperties(filter, page)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
 	}
+	h.housingService.AddPriceDrops(services.ListingsOf(properties.Items)...)
 
-	// Add more parameters as needed
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(properties)
+}
+
+// searchHousingText runs a free-text search combined with the structured
+// filter, answering with the scored and highlighted matches
+func (h *HousingController) searchHousingText(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	text, err := services.ParseTextQuery(query.Get("q"))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	filter, limit, err := parseGeoOptions(query)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
 
-	// Use the service to search for properties
-	properties, err := h.housingService.SearchProperties(searchParams)
+	matches, err := h.housingService.SearchPropertiesText(text, filter, limit)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.Error(w, r, err)
 		return
 	}
 
+	listings := make([]*models.Housing, len(matches))
+	for i := range matches {
+		listings[i] = &matches[i].Housing
+	}
+	h.housingService.AddPriceDrops(listings...)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(matches)
+}
+
+// parseHousingFilter builds a search filter from the query string
+func parseHousingFilter(query url.Values) (services.HousingFilter, error) {
+	filter := services.HousingFilter{
+		County: query.Get("county"),
+		Type:   query.Get("type"),
+	}
+
+	if raw := query.Get("agentId"); raw != "" {
+		agentID, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			return filter, invalidParam("agentId")
+		}
+		filter.AgentID = &agentID
+	}
+
+	ranges := []struct {
+		name   string
+		target *services.NumericRange
+	}{
+		{"Price", &filter.Price},
+		{"Bedrooms", &filter.Bedrooms},
+		{"Bathrooms", &filter.Bathrooms},
+		{"Year", &filter.Year},
+		{"Surface", &filter.Surface},
+	}
+
+	for _, rg := range ranges {
+		min, err := parseFloatParam(query, "min"+rg.name)
+		if err != nil {
+			return filter, err
+		}
+		max, err := parseFloatParam(query, "max"+rg.name)
+		if err != nil {
+			return filter, err
+		}
+		if min != nil && max != nil && *min > *max {
+			return filter, services.InvalidField("min"+rg.name, "range", "min%s must not be greater than max%s", rg.name, rg.name)
+		}
+		*rg.target = services.NumericRange{Min: min, Max: max}
+	}
+
+	return filter, nil
+}
+
+// parseFloatParam parses an optional numeric query parameter
+func parseFloatParam(query url.Values, key string) (*float64, error) {
+	raw := query.Get(key)
+	if raw == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, invalidParam(key)
+	}
+	return &value, nil
+}
+
+// NearHousing handles radius searches around a point
+func (h *HousingController) NearHousing(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	origin, radius, err := parseNearParams(query)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	filter, limit, err := parseGeoOptions(query)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// Use the service to find properties around the point
+	properties, err := h.housingService.NearProperties(origin, radius, filter, limit)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	h.addGeoPriceDrops(properties)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(properties)
+}
+
+// HousingInBounds handles map viewport searches
+func (h *HousingController) HousingInBounds(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	box, origin, err := parseBoundsParams(query)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	filter, limit, err := parseGeoOptions(query)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// Use the service to find properties inside the viewport
+	properties, err := h.housingService.PropertiesInBounds(box, origin, filter, limit)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	h.addGeoPriceDrops(properties)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(properties)
 }
+
+// addGeoPriceDrops sets the price drops of the listings found by a geo search
+func (h *HousingController) addGeoPriceDrops(properties []services.HousingWithDistance) {
+	listings := make([]*models.Housing, len(properties))
+	for i := range properties {
+		listings[i] = &properties[i].Housing
+	}
+	h.housingService.AddPriceDrops(listings...)
+}
+
+// parseNearParams reads the point and radius of a radius search
+func parseNearParams(query url.Values) (services.LatLng, float64, error) {
+	origin, err := parseLatLng(query, "lat", "lng")
+	if err != nil {
+		return services.LatLng{}, 0, err
+	}
+	if origin == nil {
+		return services.LatLng{}, 0, requiredParam("lat", "lat and lng are required")
+	}
+
+	radius, err := parseFloatParam(query, "radiusMeters")
+	if err != nil {
+		return services.LatLng{}, 0, err
+	}
+	if radius == nil {
+		return services.LatLng{}, 0, requiredParam("radiusMeters", "radiusMeters is required")
+	}
+	if err := origin.Validate(); err != nil {
+		return services.LatLng{}, 0, err
+	}
+	if *radius <= 0 || *radius > services.MaxGeoRadiusMeters {
+		return services.LatLng{}, 0, services.InvalidField("radiusMeters", "out_of_range", "radiusMeters must be greater than 0 and at most %d", services.MaxGeoRadiusMeters)
+	}
+
+	return *origin, *radius, nil
+}
+
+// parseBoundsParams reads the viewport corners and the optional point
+// distances are measured from
+func parseBoundsParams(query url.Values) (services.BoundingBox, *services.LatLng, error) {
+	sw, err := parseLatLng(query, "swLat", "swLng")
+	if err != nil {
+		return services.BoundingBox{}, nil, err
+	}
+	ne, err := parseLatLng(query, "neLat", "neLng")
+	if err != nil {
+		return services.BoundingBox{}, nil, err
+	}
+	if sw == nil || ne == nil {
+		return services.BoundingBox{}, nil, requiredParam("bounds", "swLat, swLng, neLat and neLng are required")
+	}
+
+	box := services.BoundingBox{SouthWest: *sw, NorthEast: *ne}
+	if err := box.Validate(); err != nil {
+		return services.BoundingBox{}, nil, err
+	}
+
+	origin, err := parseLatLng(query, "lat", "lng")
+	if err != nil {
+		return services.BoundingBox{}, nil, err
+	}
+	if origin != nil {
+		if err := origin.Validate(); err != nil {
+			return services.BoundingBox{}, nil, err
+		}
+	}
+
+	return box, origin, nil
+}
+
+// parseLatLng reads an optional coordinate pair; both or neither must be set
+func parseLatLng(query url.Values, latKey, lngKey string) (*services.LatLng, error) {
+	lat, err := parseFloatParam(query, latKey)
+	if err != nil {
+		return nil, err
+	}
+	lng, err := parseFloatParam(query, lngKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if lat == nil && lng == nil {
+		return nil, nil
+	}
+	if lat == nil || lng == nil {
+		return nil, requiredParam(latKey, "%s and %s must be given together", latKey, lngKey)
+	}
+	return &services.LatLng{Lat: *lat, Lng: *lng}, nil
+}
+
+// parseGeoOptions reads the structured filter and result limit shared by geo
+// and text searches
+func parseGeoOptions(query url.Values) (services.HousingFilter, int, error) {
+	filter, err := parseHousingFilter(query)
+	if err != nil {
+		return filter, 0, err
+	}
+
+	limit := 0
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return filter, 0, invalidParam("limit")
+		}
+	}
+	return filter, limit, nil
+}
@@ -0,0 +1,311 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+)
+
+func TestGetAllHousingPages(t *testing.T) {
+	prices := []int64{300000, 100000, 500000, 200000, 400000}
+
+	tests := []struct {
+		query string
+		want  []int64
+	}{
+		{"sort=price&order=asc", []int64{100000, 200000, 300000, 400000, 500000}},
+		{"sort=price&order=desc", []int64{500000, 400000, 300000, 200000, 100000}},
+		// Newest first, in the reverse of the order they were created
+		{"", []int64{400000, 200000, 500000, 100000, 300000}},
+	}
+
+	srv := newServer(t)
+	for i, price := range prices {
+		srv.listing(t, models.Housing{Name: "Listing " + strconv.Itoa(i), PriceCents: price})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var got []int64
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > len(prices) {
+					t.Fatal("pages never ended")
+				}
+				var page services.PropertyPage
+				path := "/api/housing/all?limit=2&cursor=" + url.QueryEscape(cursor) + "&" + tt.query
+				if status := srv.call(t, "GET", path, "", nil, &page); status != http.StatusOK {
+					t.Fatalf("GET %s: status %d", path, status)
+				}
+				if page.Total != int64(len(prices)) {
+					t.Errorf("total = %d, want %d", page.Total, len(prices))
+				}
+				for _, property := range page.Items {
+					got = append(got, property.PriceCents)
+				}
+				if cursor = page.NextCursor; cursor == "" {
+					break
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("prices = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAllHousingRejects(t *testing.T) {
+	srv := newServer(t)
+	srv.listing(t, models.Housing{Name: "Pool House"})
+	srv.listing(t, models.Housing{Name: "Loft"})
+
+	var first services.PropertyPage
+	if status := srv.call(t, "GET", "/api/housing/all?limit=1&sort=price", "", nil, &first); status != http.StatusOK || first.NextCursor == "" {
+		t.Fatalf("first page: status %d, cursor %q", status, first.NextCursor)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"bad limit", "limit=zero"},
+		{"negative limit", "limit=-1"},
+		{"unknown sort", "sort=name"},
+		{"garbage cursor", "cursor=!!!"},
+		{"cursor of another sort", "sort=newest&cursor=" + url.QueryEscape(first.NextCursor)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var problem problemBody
+			if status := srv.call(t, "GET", "/api/housing/all?"+tt.query, "", nil, &problem); status != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestGetHousingFacets(t *testing.T) {
+	srv := newServer(t)
+
+	var empty services.HousingFacets
+	if status := srv.call(t, "GET", "/api/housing/facets", "", nil, &empty); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if len(empty.Counties) != 0 || len(empty.Types) != 0 {
+		t.Errorf("facets without listings = %+v, want none", empty)
+	}
+
+	srv.listing(t, models.Housing{Name: "Pool House", County: "Duval", Type: "house"})
+	srv.listing(t, models.Housing{Name: "Garden Flat", County: "Alachua", Type: "apartment"})
+	srv.listing(t, models.Housing{Name: "Loft", County: "Alachua", Type: "apartment"})
+	srv.listing(t, models.Housing{Name: "Unknown", Type: "house"})
+
+	var facets services.HousingFacets
+	if status := srv.call(t, "GET", "/api/housing/facets", "", nil, &facets); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	want := services.HousingFacets{Counties: []string{"Alachua", "Duval"}, Types: []string{"apartment", "house"}}
+	if !reflect.DeepEqual(facets, want) {
+		t.Errorf("facets = %+v, want %+v", facets, want)
+	}
+}
+
+func TestSearchHousingText(t *testing.T) {
+	srv := newServer(t)
+	srv.listing(t, models.Housing{Name: "Pool House", County: "Alachua", Description: "Bright house near campus."})
+	srv.listing(t, models.Housing{Name: "Garden Flat", County: "Alachua", Description: "Flat with a pool and a garden."})
+	srv.listing(t, models.Housing{Name: "Beach Condo", County: "Duval", Description: "Ocean views."})
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+		// Highlighted name of the first match
+		highlight string
+	}{
+		{"name ranks first", "q=pool", []string{"Pool House", "Garden Flat"}, "<mark>Pool</mark> House"},
+		{"phrase", "q=" + url.QueryEscape(`"pool and"`), []string{"Garden Flat"}, ""},
+		{"prefix", "q=gard*+flat", []string{"Garden Flat"}, "<mark>Garden</mark> <mark>Flat</mark>"},
+		{"filter", "q=pool&county=Duval", []string{}, ""},
+		{"no match", "q=mansion", []string{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var matches []services.HousingMatch
+			if status := srv.call(t, "GET", "/api/housing/search?"+tt.query, "", nil, &matches); status != http.StatusOK {
+				t.Fatalf("status = %d, want %d", status, http.StatusOK)
+			}
+			names := []string{}
+			for _, match := range matches {
+				names = append(names, match.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Fatalf("matches = %v, want %v", names, tt.want)
+			}
+			if tt.highlight != "" && matches[0].Highlights["name"] != tt.highlight {
+				t.Errorf("name highlight = %q, want %q", matches[0].Highlights["name"], tt.highlight)
+			}
+		})
+	}
+}
+
+func TestSearchHousingTextRejects(t *testing.T) {
+	srv := newServer(t)
+
+	for _, query := range []string{"q=", "q=%22%22", "q=a*"} {
+		var problem problemBody
+		if status := srv.call(t, "GET", "/api/housing/search?"+query, "", nil, &problem); status != http.StatusBadRequest {
+			t.Errorf("GET /api/housing/search?%s: status %d, want %d", query, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestSearchHousingPages(t *testing.T) {
+	srv := newServer(t)
+	srv.listing(t, models.Housing{Name: "Pool House", County: "Alachua", PriceCents: 150000})
+	srv.listing(t, models.Housing{Name: "Garden Flat", County: "Alachua", PriceCents: 90000})
+	srv.listing(t, models.Housing{Name: "Loft", County: "Alachua", PriceCents: 120000})
+	srv.listing(t, models.Housing{Name: "Beach Condo", County: "Duval", PriceCents: 110000})
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"county", "county=Alachua&sort=price", []string{"Garden Flat", "Loft", "Pool House"}},
+		{"price range", "minPrice=1000&maxPrice=1500&sort=price&order=desc", []string{"Pool House", "Loft", "Beach Condo"}},
+		{"nothing", "county=Orange", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := []string{}
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > len(tt.want) {
+					t.Fatal("pages never ended")
+				}
+				var page services.PropertyPage
+				path := "/api/housing/search?limit=1&cursor=" + url.QueryEscape(cursor) + "&" + tt.query
+				if status := srv.call(t, "GET", path, "", nil, &page); status != http.StatusOK {
+					t.Fatalf("GET %s: status %d", path, status)
+				}
+				if page.Total != int64(len(tt.want)) {
+					t.Errorf("total = %d, want %d", page.Total, len(tt.want))
+				}
+				if len(page.Items) > 1 {
+					t.Fatalf("page has %d listings, want at most 1", len(page.Items))
+				}
+				for _, property := range page.Items {
+					names = append(names, property.Name)
+				}
+				if cursor = page.NextCursor; cursor == "" {
+					break
+				}
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("listings = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchHousingRejects(t *testing.T) {
+	srv := newServer(t)
+
+	for _, query := range []string{"minPrice=2&maxPrice=1", "minPrice=cheap", "limit=0", "sort=name"} {
+		var problem problemBody
+		if status := srv.call(t, "GET", "/api/housing/search?"+query, "", nil, &problem); status != http.StatusBadRequest {
+			t.Errorf("GET /api/housing/search?%s: status %d, want %d", query, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestGeoSearchParams(t *testing.T) {
+	srv := newServer(t)
+	srv.listing(t, models.Housing{Name: "Downtown", Latitude: 29.6516, Longitude: -82.3248})
+
+	tests := []struct {
+		path  string
+		want  int
+		found int
+	}{
+		{"/api/housing/near?lat=29.65&lng=-82.32&radiusMeters=2000", http.StatusOK, 1},
+		{"/api/housing/near?lat=29.65&lng=-82.32&radiusMeters=2000&type=house", http.StatusOK, 0},
+		{"/api/housing/near?lat=29.65&radiusMeters=2000", http.StatusBadRequest, 0},
+		{"/api/housing/near?lat=29.65&lng=-82.32", http.StatusBadRequest, 0},
+		{"/api/housing/near?lat=north&lng=-82.32&radiusMeters=2000", http.StatusBadRequest, 0},
+		{"/api/housing/near?lat=29.65&lng=-82.32&radiusMeters=200000", http.StatusBadRequest, 0},
+		{"/api/housing/near?lat=29.65&lng=-82.32&radiusMeters=2000&limit=0", http.StatusBadRequest, 0},
+		{"/api/housing/within?swLat=29.6&swLng=-82.4&neLat=29.7&neLng=-82.3", http.StatusOK, 1},
+		{"/api/housing/within?swLat=29.6&swLng=-82.4&neLat=29.7&neLng=-82.3&lat=29.65&lng=-82.32", http.StatusOK, 1},
+		{"/api/housing/within?swLat=29.7&swLng=-82.3&neLat=29.6&neLng=-82.4", http.StatusBadRequest, 0},
+		{"/api/housing/within?swLat=29.6&swLng=-82.4", http.StatusBadRequest, 0},
+		{"/api/housing/within?swLat=29.6&swLng=-82.4&neLat=29.7&neLng=-82.3&lat=29.65", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if tt.want != http.StatusOK {
+				var problem problemBody
+				if status := srv.call(t, "GET", tt.path, "", nil, &problem); status != tt.want {
+					t.Errorf("status = %d, want %d", status, tt.want)
+				}
+				return
+			}
+
+			var properties []services.HousingWithDistance
+			if status := srv.call(t, "GET", tt.path, "", nil, &properties); status != tt.want {
+				t.Fatalf("status = %d, want %d", status, tt.want)
+			}
+			if len(properties) != tt.found {
+				t.Errorf("found %d listings, want %d", len(properties), tt.found)
+			}
+		})
+	}
+}
+
+func TestHousingPriceDrop(t *testing.T) {
+	srv := newServer(t)
+	_, token := srv.user(t, "admin@ufl.edu", models.RoleAdmin)
+	pool := srv.listing(t, models.Housing{Name: "Pool House", Type: "house", County: "Alachua", PriceCents: 100000})
+	path := "/api/housing/" + pool.ID.Hex()
+
+	update := map[string]any{"name": "Pool House", "type": "house", "county": "Alachua", "address": "1 Main St", "priceCents": 85000}
+	var updated models.Housing
+	if status := srv.call(t, "PUT", path, token, update, &updated); status != http.StatusOK {
+		t.Fatalf("PUT: status %d", status)
+	}
+
+	want := models.PriceDrop{Percent: 15, FromPriceCents: 100000, Days: services.PriceDropDays}
+	var property models.Housing
+	if status := srv.call(t, "GET", path, "", nil, &property); status != http.StatusOK {
+		t.Fatalf("GET: status %d", status)
+	}
+	if property.PriceDrop == nil || *property.PriceDrop != want {
+		t.Errorf("listing price drop = %+v, want %+v", property.PriceDrop, want)
+	}
+
+	var page services.PropertyPage
+	if status := srv.call(t, "GET", "/api/housing/all", "", nil, &page); status != http.StatusOK || len(page.Items) != 1 {
+		t.Fatalf("GET all: status %d, %d listings", status, len(page.Items))
+	}
+	if drop := page.Items[0].PriceDrop; drop == nil || *drop != want {
+		t.Errorf("page price drop = %+v, want %+v", drop, want)
+	}
+
+	var history []models.PriceChange
+	if status := srv.call(t, "GET", path+"/price-history", "", nil, &history); status != http.StatusOK {
+		t.Fatalf("GET price history: status %d", status)
+	}
+	if len(history) != 1 || history[0].OldPriceCents != 100000 || history[0].NewPriceCents != 85000 {
+		t.Errorf("price history = %+v, want one change from 100000 to 85000", history)
+	}
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"unicode/utf8"
+
+	"gatorswamp/problem"
+	"gatorswamp/services"
+	"github.com/gorilla/mux"
+)
+
+// maxFormOverhead allows for the multipart headers and captions sent along
+// with the images of an upload
+const maxFormOverhead = 1 << 20
+
+// ArrangeImagesRequest represents the request body for reordering and
+// captioning a gallery; images are listed in their new order
+type ArrangeImagesRequest struct {
+	Images []services.ImagePlacement `json:"images" validate:"required,dive"`
+}
+
+// UploadHousingImages handles adding photos to a listing's gallery. Files
+// are sent as multipart form fields named "images", with optional
+// "captions" fields in the same order. Parts are read one at a time, so
+// nothing beyond the images themselves is buffered
+func (h *HousingController) UploadHousingImages(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, ok := authorizeListing(w, r, h.housingService, id); !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxUploadBytes+maxFormOverhead)
+	uploads, err := readImageUploads(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			problem.Error(w, r, services.ErrUploadTooLarge)
+		case errors.Is(err, services.ErrTooLarge):
+			problem.Error(w, r, err)
+		default:
+			problem.Write(w, r, http.StatusBadRequest, "invalid_multipart_form", "Invalid multipart form")
+		}
+		return
+	}
+
+	property, err := h.housingService.AddImages(id, uploads)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(property)
+}
+
+// readImageUploads reads the images and captions of a multipart upload,
+// pairing captions with images in order. Other fields are skipped
+func readImageUploads(r *http.Request) ([]services.ImageUpload, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	var uploads []services.ImageUpload
+	var captions []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch part.FormName() {
+		case "images":
+			data, err := io.ReadAll(io.LimitReader(part, services.MaxImageBytes+1))
+			if err != nil {
+				return nil, err
+			}
+			if len(data) > services.MaxImageBytes {
+				return nil, services.ErrImageTooLarge
+			}
+			uploads = append(uploads, services.ImageUpload{Data: data})
+		case "captions":
+			// Longer captions are cut short here but still exceed the
+			// caption limit, which AddImages reports
+			caption, err := io.ReadAll(io.LimitReader(part, services.MaxCaptionLength*utf8.UTFMax+1))
+			if err != nil {
+				return nil, err
+			}
+			captions = append(captions, string(caption))
+		}
+		part.Close()
+	}
+
+	for i := range uploads {
+		if i < len(captions) {
+			uploads[i].Caption = captions[i]
+		}
+	}
+	return uploads, nil
+}
+
+// ArrangeHousingImages handles reordering and re-captioning a listing's gallery
+func (h *HousingController) ArrangeHousingImages(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, ok := authorizeListing(w, r, h.housingService, id); !ok {
+		return
+	}
+
+	var req ArrangeImagesRequest
+	if err := decodeBody(r, &req); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	property, err := h.housingService.ArrangeImages(id, req.Images)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(property)
+}
+
+// DeleteHousingImage handles removing one photo from a listing's gallery
+func (h *HousingController) DeleteHousingImage(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	if _, ok := authorizeListing(w, r, h.housingService, params["id"]); !ok {
+		return
+	}
+
+	property, err := h.housingService.DeleteImage(params["id"], params["imageId"])
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(property)
+}
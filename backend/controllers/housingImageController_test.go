@@ -0,0 +1,134 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+)
+
+// imagePart is a multipart field of an upload
+type imagePart struct {
+	field string
+	data  []byte
+}
+
+// upload posts parts as a multipart form to the gallery of a listing,
+// decoding the response into out, and returns the status code
+func (s *testServer) upload(t *testing.T, id, token string, parts []imagePart, out any) int {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for _, part := range parts {
+		var w io.Writer
+		var err error
+		if part.field == "images" {
+			w, err = form.CreateFormFile(part.field, "photo.png")
+		} else {
+			w, err = form.CreateFormField(part.field)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(part.data)
+	}
+	form.Close()
+
+	req, err := http.NewRequest("POST", s.URL+"/api/housing/"+id+"/images", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatalf("uploading: %v", err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return resp.StatusCode
+}
+
+// photo encodes a small PNG
+func photo(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 20, 10))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadHousingImages(t *testing.T) {
+	srv := newServer(t)
+	_, token := srv.user(t, "admin@ufl.edu", models.RoleAdmin)
+	property := srv.listing(t, models.Housing{Name: "Pool House"})
+
+	var updated models.Housing
+	status := srv.upload(t, property.ID.Hex(), token, []imagePart{
+		{"images", photo(t)},
+		{"captions", []byte("Front")},
+		{"images", photo(t)},
+		{"ignored", []byte("anything")},
+	}, &updated)
+	if status != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", status, http.StatusCreated)
+	}
+	if len(updated.Images) != 2 {
+		t.Fatalf("gallery has %d images, want 2", len(updated.Images))
+	}
+	if updated.Images[0].Caption != "Front" || updated.Images[1].Caption != "" {
+		t.Errorf("captions = %q, %q, want Front and none", updated.Images[0].Caption, updated.Images[1].Caption)
+	}
+}
+
+func TestUploadHousingImagesRejects(t *testing.T) {
+	// Five images just under the size limit exceed the request limit
+	nearLimit := make([]imagePart, 5)
+	for i := range nearLimit {
+		nearLimit[i] = imagePart{"images", make([]byte, services.MaxImageBytes-1)}
+	}
+
+	tests := []struct {
+		name     string
+		parts    []imagePart
+		want     int
+		wantCode string
+	}{
+		{"no images", []imagePart{{"captions", []byte("Front")}}, http.StatusBadRequest, "no_images"},
+		{"not an image", []imagePart{{"images", []byte("just text")}}, http.StatusUnsupportedMediaType, "unsupported_image"},
+		{"image too large", []imagePart{{"images", make([]byte, services.MaxImageBytes+1)}}, http.StatusRequestEntityTooLarge, "image_too_large"},
+		{"request too large", nearLimit, http.StatusRequestEntityTooLarge, "upload_too_large"},
+	}
+
+	srv := newServer(t)
+	_, token := srv.user(t, "admin@ufl.edu", models.RoleAdmin)
+	property := srv.listing(t, models.Housing{Name: "Pool House"})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var problem problemBody
+			if status := srv.upload(t, property.ID.Hex(), token, tt.parts, &problem); status != tt.want {
+				t.Fatalf("status = %d, want %d", status, tt.want)
+			}
+			if problem.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", problem.Code, tt.wantCode)
+			}
+		})
+	}
+
+	var problem problemBody
+	if status := srv.call(t, "POST", "/api/housing/"+property.ID.Hex()+"/images", token, map[string]string{}, &problem); status != http.StatusBadRequest {
+		t.Errorf("JSON body: status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
@@ -4,13 +4,13 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"gatorswamp/authz"
 	"gatorswamp/middlewares"
 	"gatorswamp/models"
+	"gatorswamp/problem"
 	"gatorswamp/services"
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // PropertyRequestController handles HTTP requests related to property requests
@@ -22,23 +22,25 @@ type PropertyRequestController struct {
 
 // CreateRequestBody represents the request body for creating a property request
 type CreateRequestBody struct {
-	PropertyID string `json:"propertyId" validate:"required"`
-	Message    string `json:"message"`
+	PropertyID string `json:"propertyId" validate:"required,mongodb"`
+	Message    string `json:"message" validate:"max=2000"`
 }
 
 // UpdateRequestBody represents the request body for updating a property request status
 type UpdateRequestBody struct {
-	Status string `json:"status" validate:"required,oneof=pending approved rejected"`
+	Status string `json:"status" validate:"required,oneof=approved rejected lease_signed cancelled"`
+	Reason string `json:"reason" validate:"required_if=Status rejected,max=1000"`
 }
 
-// NewPropertyRequestController creates a new property request controller
-func NewPropertyRequestController(collection *mongo.Collection, housingColl *mongo.Collection) *PropertyRequestController {
-	userColl := collection.Database().Collection("users")
-	userService := services.NewUserService(userColl)
-	housingService := services.NewHousingService(housingColl)
+// EditRequestBody represents the request body for editing a pending property request
+type EditRequestBody struct {
+	Message string `json:"message" validate:"max=2000"`
+}
 
+// NewPropertyRequestController creates a new property request controller
+func NewPropertyRequestController(requestService *services.PropertyRequestService, userService *services.UserService, housingService *services.HousingService) *PropertyRequestController {
 	return &PropertyRequestController{
-		requestService: services.NewPropertyRequestService(collection, userService, housingService),
+		requestService: requestService,
 		userService:    userService,
 		housingService: housingService,
 	}
@@ -49,46 +51,31 @@ func (c *PropertyRequestController) CreateRequest(w http.ResponseWriter, r *http
 	// Get user from context
 	user, ok := middlewares.GetUserFromContext(r.Context())
 	if !ok {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		problem.Error(w, r, errUnauthorized)
 		return
 	}
 
-	// Admin cannot create property requests
-	if user.Role == "admin" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Admins cannot create property requests"})
+	// Only users who have verified their email can request properties
+	if !user.EmailVerified {
+		problem.Write(w, r, http.StatusForbidden, "email_not_verified", "Please verify your email address before requesting a property")
 		return
 	}
 
 	var requestBody CreateRequestBody
-	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	err := decodeBody(r, &requestBody)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		problem.Error(w, r, err)
 		return
 	}
 
-	// Validate property ID
-	propertyID, err := primitive.ObjectIDFromHex(requestBody.PropertyID)
-	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid property ID"})
-		return
-	}
+	propertyID, _ := primitive.ObjectIDFromHex(requestBody.PropertyID)
 
 	// Check if user already has a pending request for this property
 	existingRequests, err := c.requestService.GetRequestsByUser(user.ID.Hex())
 	if err == nil {
 		for _, req := range existingRequests {
 			if req.PropertyID == propertyID && req.Status == models.StatusPending {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusConflict)
-				json.NewEncoder(w).Encode(map[string]string{"error": "You already have a pending request for this property"})
+				problem.Write(w, r, http.StatusConflict, "duplicate_request", "You already have a pending request for this property")
 				return
 			}
 		}
@@ -104,9 +91,7 @@ func (c *PropertyRequestController) CreateRequest(w http.ResponseWriter, r *http
 	// Use service to create request
 	createdRequest, err := c.requestService.CreateRequest(propertyRequest)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Error(w, r, err)
 		return
 	}
 
@@ -120,20 +105,21 @@ func (c *PropertyRequestController) GetMyRequests(w http.ResponseWriter, r *http
 	// Get user from context
 	user, ok := middlewares.GetUserFromContext(r.Context())
 	if !ok {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		problem.Error(w, r, errUnauthorized)
 		return
 	}
 
-	// Regular users can only see their own requests
-	if user.Role != "admin" {
+	switch authz.ScopeOf(user, authz.RequestsReview) {
+	case authz.Any:
+		// Reviewers of every request see all of them
+		c.getAllRequests(w, r)
+	case authz.Own:
+		// Agents see the requests for the listings they manage
+		c.getAgentRequests(w, r, user.ID)
+	default:
+		// Regular users can only see their own requests
 		c.getUserRequests(w, r, user.ID)
-		return
 	}
-
-	// Admin can see all requests
-	c.getAllRequests(w, r)
 }
 
 // getUserRequests gets requests for a specific user
@@ -141,18 +127,33 @@ func (c *PropertyRequestController) getUserRequests(w http.ResponseWriter, r *ht
 	// Use service to get user requests
 	requests, err := c.requestService.GetRequestsByUser(userID.Hex())
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Error(w, r, err)
 		return
 	}
 
 	// Enrich requests with property data
 	enrichedRequests, err := c.enrichRequests(requests)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrichedRequests)
+}
+
+// getAgentRequests gets the requests for an agent's listings
+func (c *PropertyRequestController) getAgentRequests(w http.ResponseWriter, r *http.Request, agentID primitive.ObjectID) {
+	requests, err := c.requestService.GetRequestsForAgent(agentID.Hex())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// Enrich requests with property data
+	enrichedRequests, err := c.enrichRequests(requests)
+	if err != nil {
+		problem.Error(w, r, err)
 		return
 	}
 
@@ -163,20 +164,16 @@ func (c *PropertyRequestController) getUserRequests(w http.ResponseWriter, r *ht
 // getAllRequests gets all requests (admin only)
 func (c *PropertyRequestController) getAllRequests(w http.ResponseWriter, r *http.Request) {
 	// Use service to get all requests
-	requests, err := c.requestService.GetAllRequests(bson.M{})
+	requests, err := c.requestService.GetAllRequests()
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Error(w, r, err)
 		return
 	}
 
 	// Enrich requests with property data
 	enrichedRequests, err := c.enrichRequests(requests)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Error(w, r, err)
 		return
 	}
 
@@ -192,10 +189,15 @@ type EnrichedPropertyRequest struct {
 
 // enrichRequests adds property data to requests
 func (c *PropertyRequestController) enrichRequests(requests []models.PropertyRequest) ([]EnrichedPropertyRequest, error) {
+	return enrichRequests(c.housingService, requests)
+}
+
+// enrichRequests adds property data to requests using the given housing service
+func enrichRequests(housingService *services.HousingService, requests []models.PropertyRequest) ([]EnrichedPropertyRequest, error) {
 	var enriched []EnrichedPropertyRequest
 
 	for _, req := range requests {
-		property, err := c.housingService.GetPropertyByID(req.PropertyID.Hex())
+		property, err := housingService.GetPropertyByID(req.PropertyID.Hex())
 		var housing models.Housing
 
 		if err != nil {
@@ -216,14 +218,43 @@ func (c *PropertyRequestController) enrichRequests(requests []models.PropertyReq
 	return enriched, nil
 }
 
-// UpdateRequestStatus updates the status of a property request (admin only)
+// UpdateRequestStatus updates the status of a property request. Agents
+// only review the requests for the listings they manage
 func (c *PropertyRequestController) UpdateRequestStatus(w http.ResponseWriter, r *http.Request) {
-	// Verify admin role
+	// Get request ID from URL
+	params := mux.Vars(r)
+	requestID := params["id"]
+
+	user, ok := authorizeRequestReview(w, r, c.requestService, c.housingService, requestID)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var updateBody UpdateRequestBody
+	err := decodeBody(r, &updateBody)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// Use service to update request status
+	updatedRequest, err := c.requestService.UpdateRequestStatus(requestID, updateBody.Status, user.ID.Hex(), updateBody.Reason)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedRequest)
+}
+
+// UpdateRequest lets the owner of a pending request change its message
+func (c *PropertyRequestController) UpdateRequest(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
 	user, ok := middlewares.GetUserFromContext(r.Context())
-	if !ok || user.Role != "admin" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Admin access required"})
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
 		return
 	}
 
@@ -231,29 +262,57 @@ func (c *PropertyRequestController) UpdateRequestStatus(w http.ResponseWriter, r
 	params := mux.Vars(r)
 	requestID := params["id"]
 
-	// Parse request body
-	var updateBody UpdateRequestBody
-	err := json.NewDecoder(r.Body).Decode(&updateBody)
+	var editBody EditRequestBody
+	err := decodeBody(r, &editBody)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		problem.Error(w, r, err)
 		return
 	}
 
-	// Use service to update request status
-	updatedRequest, err := c.requestService.UpdateRequestStatus(requestID, updateBody.Status, user.ID.Hex())
+	// Use service to update the message
+	updatedRequest, err := c.requestService.UpdateRequestMessage(requestID, user.ID.Hex(), editBody.Message)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		if err.Error() == "request not found" {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Error(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updatedRequest)
 }
+
+// DeleteRequest withdraws the user's own pending request, or permanently
+// removes any request when called by an admin
+func (c *PropertyRequestController) DeleteRequest(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	// Get request ID from URL
+	params := mux.Vars(r)
+	requestID := params["id"]
+
+	// Admins purge the request entirely
+	if authz.Can(user, authz.RequestsDelete) {
+		if err := c.requestService.DeleteRequest(requestID); err != nil {
+			problem.Error(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Request deleted successfully"})
+		return
+	}
+
+	// Tenants withdraw their request so its history is kept
+	withdrawnRequest, err := c.requestService.WithdrawRequest(requestID, user.ID.Hex())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(withdrawnRequest)
+}
@@ -0,0 +1,78 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"gatorswamp/models"
+)
+
+func TestUpdateRequestStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		// Statuses the request is moved through before the tested change
+		before   []string
+		reviewer string
+		body     map[string]string
+		want     int
+		wantCode string
+	}{
+		{"approve", nil, "admin", map[string]string{"status": "approved"}, http.StatusOK, ""},
+		{"reject", nil, "admin", map[string]string{"status": "rejected", "reason": "already let"}, http.StatusOK, ""},
+		{"reject without reason", nil, "admin", map[string]string{"status": "rejected"}, http.StatusBadRequest, "validation_failed"},
+		{"withdraw", nil, "admin", map[string]string{"status": "withdrawn"}, http.StatusBadRequest, "validation_failed"},
+		{"sign a pending request", nil, "admin", map[string]string{"status": "lease_signed"}, http.StatusConflict, "invalid_transition"},
+		{"sign an approved request", []string{"approved"}, "admin", map[string]string{"status": "lease_signed"}, http.StatusOK, ""},
+		{"approve a signed request", []string{"approved", "lease_signed"}, "admin", map[string]string{"status": "approved"}, http.StatusConflict, "invalid_transition"},
+		{"plain user", nil, "requester", map[string]string{"status": "approved"}, http.StatusForbidden, ""},
+		{"anonymous", nil, "", map[string]string{"status": "approved"}, http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newServer(t)
+			_, adminToken := srv.user(t, "admin@ufl.edu", models.RoleAdmin)
+			_, requesterToken := srv.user(t, "student@ufl.edu", models.RoleUser)
+			tokens := map[string]string{"admin": adminToken, "requester": requesterToken}
+			property := srv.listing(t, models.Housing{Name: "Pool House"})
+
+			var request models.PropertyRequest
+			status := srv.call(t, "POST", "/api/requests/create", requesterToken, map[string]string{"propertyId": property.ID.Hex()}, &request)
+			if status != http.StatusCreated {
+				t.Fatalf("creating the request: status %d", status)
+			}
+			path := "/api/requests/" + request.ID.Hex() + "/status"
+
+			for _, s := range tt.before {
+				if status := srv.call(t, "PUT", path, adminToken, map[string]string{"status": s}, nil); status != http.StatusOK {
+					t.Fatalf("moving the request to %s: status %d", s, status)
+				}
+			}
+
+			var problem problemBody
+			status = srv.call(t, "PUT", path, tokens[tt.reviewer], tt.body, &problem)
+			if status != tt.want {
+				t.Fatalf("status = %d, want %d", status, tt.want)
+			}
+			if tt.wantCode != "" && problem.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", problem.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCreateRequestRejectsDuplicates(t *testing.T) {
+	srv := newServer(t)
+	_, token := srv.user(t, "student@ufl.edu", models.RoleUser)
+	property := srv.listing(t, models.Housing{Name: "Pool House"})
+	body := map[string]string{"propertyId": property.ID.Hex()}
+
+	if status := srv.call(t, "POST", "/api/requests/create", token, body, nil); status != http.StatusCreated {
+		t.Fatalf("first request: status %d", status)
+	}
+
+	var problem problemBody
+	if status := srv.call(t, "POST", "/api/requests/create", token, body, &problem); status != http.StatusConflict || problem.Code != "duplicate_request" {
+		t.Errorf("second request: status %d, code %q, want %d duplicate_request", status, problem.Code, http.StatusConflict)
+	}
+}
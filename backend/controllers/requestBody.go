@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"gatorswamp/validation"
+)
+
+// decodeJSON decodes the JSON request body into dst
+func decodeJSON(r *http.Request, dst any) error {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		return errInvalidBody
+	}
+	return nil
+}
+
+// decodeBody decodes the JSON request body into dst and checks it against
+// its validate tags
+func decodeBody(r *http.Request, dst any) error {
+	if err := decodeJSON(r, dst); err != nil {
+		return err
+	}
+	return validation.Struct(dst)
+}
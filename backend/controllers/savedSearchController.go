@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"gatorswamp/middlewares"
+	"gatorswamp/models"
+	"gatorswamp/problem"
+	"gatorswamp/services"
+	"github.com/gorilla/mux"
+)
+
+// SavedSearchController handles HTTP requests related to saved searches
+type SavedSearchController struct {
+	savedSearchService *services.SavedSearchService
+	housingService     *services.HousingService
+}
+
+// SavedSearchBody represents the request body for creating or updating a saved search
+type SavedSearchBody struct {
+	Name     string                `json:"name" validate:"required,max=100"`
+	Criteria models.SearchCriteria `json:"criteria"`
+	Delivery string                `json:"delivery" validate:"omitempty,oneof=instant daily"`
+}
+
+// toModel converts the request body to a saved search
+func (b SavedSearchBody) toModel() models.SavedSearch {
+	return models.SavedSearch{
+		Name:     b.Name,
+		Criteria: b.Criteria,
+		Delivery: b.Delivery,
+	}
+}
+
+// EnrichedListingAlert includes property details with an alert
+type EnrichedListingAlert struct {
+	models.ListingAlert
+	Property models.Housing `json:"property"`
+}
+
+// NewSavedSearchController creates a new saved search controller
+func NewSavedSearchController(savedSearchService *services.SavedSearchService, housingService *services.HousingService) *SavedSearchController {
+	return &SavedSearchController{
+		savedSearchService: savedSearchService,
+		housingService:     housingService,
+	}
+}
+
+// GetMySavedSearches lists the authenticated user's saved searches, newest first
+func (c *SavedSearchController) GetMySavedSearches(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	searches, err := c.savedSearchService.GetSavedSearchesByUser(user.ID.Hex())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	if searches == nil {
+		searches = []models.SavedSearch{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(searches)
+}
+
+// CreateSavedSearch saves a search for the authenticated user
+func (c *SavedSearchController) CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	var body SavedSearchBody
+	if err := decodeBody(r, &body); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	search, err := c.savedSearchService.CreateSavedSearch(user.ID.Hex(), body.toModel())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(search)
+}
+
+// UpdateSavedSearch replaces the name, criteria and delivery of one of the
+// authenticated user's saved searches
+func (c *SavedSearchController) UpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	var body SavedSearchBody
+	if err := decodeBody(r, &body); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	search, err := c.savedSearchService.UpdateSavedSearch(id, user.ID.Hex(), body.toModel())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(search)
+}
+
+// DeleteSavedSearch removes one of the authenticated user's saved searches
+func (c *SavedSearchController) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	if err := c.savedSearchService.DeleteSavedSearch(id, user.ID.Hex()); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Saved search deleted successfully"})
+}
+
+// GetMyAlerts lists the authenticated user's most recent listing alerts
+func (c *SavedSearchController) GetMyAlerts(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	alerts, err := c.savedSearchService.GetAlertsByUser(user.ID.Hex())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrichAlerts(c.housingService, alerts))
+}
+
+// enrichAlerts adds property data to alerts. Alerts whose listing no longer
+// exists are left out
+func enrichAlerts(housingService *services.HousingService, alerts []models.ListingAlert) []EnrichedListingAlert {
+	enriched := []EnrichedListingAlert{}
+
+	for _, alert := range alerts {
+		property, err := housingService.GetPropertyByID(alert.PropertyID.Hex())
+		if err != nil {
+			continue
+		}
+
+		enriched = append(enriched, EnrichedListingAlert{
+			ListingAlert: alert,
+			Property:     *property,
+		})
+	}
+
+	listings := make([]*models.Housing, len(enriched))
+	for i := range enriched {
+		listings[i] = &enriched[i].Property
+	}
+	housingService.AddPriceDrops(listings...)
+	return enriched
+}
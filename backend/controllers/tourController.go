@@ -0,0 +1,338 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"gatorswamp/authz"
+	"gatorswamp/calendar"
+	"gatorswamp/middlewares"
+	"gatorswamp/models"
+	"gatorswamp/problem"
+	"gatorswamp/services"
+	"github.com/gorilla/mux"
+)
+
+// TourController handles HTTP requests related to listing tours and the
+// availability of agents
+type TourController struct {
+	tourService    *services.TourService
+	housingService *services.HousingService
+}
+
+// AvailabilityBody represents the request body for publishing an availability window
+type AvailabilityBody struct {
+	Start       time.Time `json:"start" validate:"required"`
+	End         time.Time `json:"end" validate:"required"`
+	SlotMinutes int       `json:"slotMinutes" validate:"omitempty,gte=15,lte=240"`
+}
+
+// BookTourBody represents the request body for booking a tour
+type BookTourBody struct {
+	PropertyID string    `json:"propertyId" validate:"required,mongodb"`
+	Start      time.Time `json:"start" validate:"required"`
+	Note       string    `json:"note" validate:"max=500"`
+}
+
+// RescheduleTourBody represents the request body for moving a tour to another slot
+type RescheduleTourBody struct {
+	Start time.Time `json:"start" validate:"required"`
+}
+
+// EnrichedTour includes property details with a tour
+type EnrichedTour struct {
+	models.Tour
+	Property models.Housing `json:"property"`
+}
+
+// NewTourController creates a new tour controller
+func NewTourController(tourService *services.TourService, housingService *services.HousingService) *TourController {
+	return &TourController{
+		tourService:    tourService,
+		housingService: housingService,
+	}
+}
+
+// GetAvailability lists a listing's availability windows and free slots
+// between the optional from and to RFC 3339 times
+func (c *TourController) GetAvailability(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	from, err := parseTimeParam(query, "from")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	to, err := parseTimeParam(query, "to")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	availability, err := c.tourService.GetAvailability(mux.Vars(r)["id"], from, to)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(availability)
+}
+
+// parseTimeParam reads an optional RFC 3339 time, returning the zero time
+// when it is absent
+func parseTimeParam(query url.Values, key string) (time.Time, error) {
+	raw := query.Get(key)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	value, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, invalidParam(key)
+	}
+	return value, nil
+}
+
+// AddAvailability publishes an availability window for a listing's agent
+// (admin or the listing's agent)
+func (c *TourController) AddAvailability(w http.ResponseWriter, r *http.Request) {
+	propertyID := mux.Vars(r)["id"]
+	user, ok := authorizeListing(w, r, c.housingService, propertyID)
+	if !ok {
+		return
+	}
+
+	var body AvailabilityBody
+	if err := decodeBody(r, &body); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	window, err := c.tourService.AddAvailability(propertyID, user.ID.Hex(), body.Start, body.End, body.SlotMinutes)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(window)
+}
+
+// DeleteAvailability removes an availability window of a listing (admin or
+// the listing's agent)
+func (c *TourController) DeleteAvailability(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	if _, ok := authorizeListing(w, r, c.housingService, params["id"]); !ok {
+		return
+	}
+	if err := c.tourService.RemoveAvailability(params["id"], params["windowId"]); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Availability window deleted successfully"})
+}
+
+// GetAgentFeedURL returns the subscribable calendar feed of the tours of a
+// listing's agent (admin or the listing's agent)
+func (c *TourController) GetAgentFeedURL(w http.ResponseWriter, r *http.Request) {
+	propertyID := mux.Vars(r)["id"]
+	if _, ok := authorizeListing(w, r, c.housingService, propertyID); !ok {
+		return
+	}
+
+	agentKey, token, err := c.tourService.AgentFeedToken(propertyID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"url": fmt.Sprintf("/api/tours/feed/%s.ics?token=%s", agentKey, url.QueryEscape(token)),
+	})
+}
+
+// BookTour books a tour of a listing for the authenticated user
+func (c *TourController) BookTour(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	// Only users who have verified their email can book tours
+	if !user.EmailVerified {
+		problem.Write(w, r, http.StatusForbidden, "email_not_verified", "Please verify your email address before booking a tour")
+		return
+	}
+
+	var body BookTourBody
+	if err := decodeBody(r, &body); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	tour, err := c.tourService.BookTour(user.ID.Hex(), body.PropertyID, body.Start, body.Note)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tour)
+}
+
+// GetMyTours lists the authenticated user's tours, or every tour for users
+// who manage tours
+func (c *TourController) GetMyTours(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	var tours []models.Tour
+	var err error
+	if authz.Can(user, authz.ToursManage) {
+		tours, err = c.tourService.GetAllTours()
+	} else {
+		tours, err = c.tourService.GetToursByUser(user.ID.Hex())
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrichTours(c.housingService, tours))
+}
+
+// enrichTours adds property data to tours. Tours of deleted listings keep
+// an empty listing so that their history is still shown
+func enrichTours(housingService *services.HousingService, tours []models.Tour) []EnrichedTour {
+	enriched := []EnrichedTour{}
+
+	for _, tour := range tours {
+		housing := models.Housing{Name: "Property not found"}
+		if property, err := housingService.GetPropertyByID(tour.PropertyID.Hex()); err == nil {
+			housing = *property
+		}
+
+		enriched = append(enriched, EnrichedTour{
+			Tour:     tour,
+			Property: housing,
+		})
+	}
+
+	return enriched
+}
+
+// RescheduleTour moves the authenticated user's tour to another slot
+func (c *TourController) RescheduleTour(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	var body RescheduleTourBody
+	if err := decodeBody(r, &body); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	tour, err := c.tourService.RescheduleTour(mux.Vars(r)["id"], user.ID.Hex(), body.Start)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tour)
+}
+
+// CancelTour cancels the authenticated user's tour, or any tour when called
+// by a user who manages tours
+func (c *TourController) CancelTour(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	tourID := mux.Vars(r)["id"]
+
+	var tour *models.Tour
+	var err error
+	if authz.Can(user, authz.ToursManage) {
+		tour, err = c.tourService.CancelTour(tourID)
+	} else {
+		tour, err = c.tourService.CancelOwnTour(tourID, user.ID.Hex())
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tour)
+}
+
+// DownloadTour returns a tour as an iCalendar file. Users can download their
+// own tours and users who manage tours any tour
+func (c *TourController) DownloadTour(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	tourID := mux.Vars(r)["id"]
+
+	var tour *models.Tour
+	var err error
+	if authz.Can(user, authz.ToursManage) {
+		tour, err = c.tourService.GetTourByID(tourID)
+	} else {
+		tour, err = c.tourService.GetOwnedTour(tourID, user.ID.Hex())
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="tour-%s.ics"`, tour.ID.Hex()))
+	writeCalendar(w, c.tourService.TourCalendar(*tour))
+}
+
+// GetAgentFeed serves the tours of an agent as a calendar feed. Calendar
+// applications cannot log in, so the signed token in the URL authorizes it
+func (c *TourController) GetAgentFeed(w http.ResponseWriter, r *http.Request) {
+	cal, err := c.tourService.AgentFeed(mux.Vars(r)["agentKey"], r.URL.Query().Get("token"))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// Subscribers poll the feed, so it must not be served stale
+	w.Header().Set("Cache-Control", "no-cache")
+	writeCalendar(w, cal)
+}
+
+// writeCalendar writes cal as an iCalendar response
+func writeCalendar(w http.ResponseWriter, cal *calendar.Calendar) {
+	w.Header().Set("Content-Type", calendar.ContentType)
+	if err := calendar.Write(w, *cal); err != nil {
+		// Headers are already sent, so the client only sees a truncated file
+		log.Println("Failed to write calendar:", err)
+	}
+}
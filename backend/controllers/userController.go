@@ -3,19 +3,21 @@ package controllers
 import (
 	// "context"
 	"encoding/json"
-	// "log"
+	"errors"
+	"log"
 	"net/http"
-	"os"
 
 	// "time"
 
+	"gatorswamp/config"
 	"gatorswamp/middlewares"
 	"gatorswamp/models"
+	"gatorswamp/problem"
 	"gatorswamp/services"
+	"gatorswamp/utils"
 
 	// "github.com/gorilla/mux"
 	// "go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // UserController handles HTTP requests related to users
@@ -31,23 +33,52 @@ type LoginRequest struct {
 
 // RegisterRequest for user registration data
 type RegisterRequest struct {
-	FirstName string `json:"firstName" validate:"required"`
-	LastName  string `json:"lastName" validate:"required"`
-	Email     string `json:"email" validate:"required,email"`
-	Phone     string `json:"phone"`
-	Password  string `json:"password" validate:"required,min=6"`
+	FirstName string `json:"firstName" validate:"required,max=100"`
+	LastName  string `json:"lastName" validate:"required,max=100"`
+	Email     string `json:"email" validate:"required,email,max=254"`
+	Phone     string `json:"phone" validate:"max=30"`
+	Password  string `json:"password" validate:"required,password"`
+}
+
+// ForgotPasswordRequest asks for a password reset email
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password using an emailed token
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,password"`
+}
+
+// VerifyEmailRequest confirms an email address using an emailed token
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// RefreshRequest carries a refresh token for clients that do not use cookies
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
 }
 
 // NewUserController creates a new user controller
-func NewUserController(collection *mongo.Collection) *UserController {
+func NewUserController(userService *services.UserService) *UserController {
 	return &UserController{
-		userService: services.NewUserService(collection),
+		userService: userService,
 	}
 }
 
+// refreshCookiePath limits the refresh token cookie to the user endpoints
+const refreshCookiePath = "/api/users"
+
 // setCookie sets a secure HTTP-only auth cookie
 func setCookie(w http.ResponseWriter, name, value string, maxAge int) {
-	isProduction := os.Getenv("GO_ENV") == "production"
+	setScopedCookie(w, name, value, "/", maxAge)
+}
+
+// setScopedCookie sets a secure HTTP-only cookie sent only for paths under path
+func setScopedCookie(w http.ResponseWriter, name, value, path string, maxAge int) {
+	isProduction := config.IsProduction()
 
 	http.SetCookie(w, &http.Cookie{
 		Name:     name,
@@ -56,16 +87,41 @@ func setCookie(w http.ResponseWriter, name, value string, maxAge int) {
 		Secure:   isProduction,
 		SameSite: http.SameSiteNoneMode, // Adjust based on your CORS needs
 		MaxAge:   maxAge,
-		Path:     "/",
+		Path:     path,
 	})
 }
 
+// setSessionCookies stores the access and refresh tokens of a session
+func setSessionCookies(w http.ResponseWriter, authResponse *services.AuthResponse) {
+	setCookie(w, "authToken", authResponse.Token, int(utils.AccessTokenTTL.Seconds()))
+	setScopedCookie(w, "refreshToken", authResponse.RefreshToken, refreshCookiePath, int(services.RefreshTokenTTL.Seconds()))
+}
+
+// clearSessionCookies removes the access and refresh token cookies
+func clearSessionCookies(w http.ResponseWriter) {
+	setCookie(w, "authToken", "", -1)
+	setScopedCookie(w, "refreshToken", "", refreshCookiePath, -1)
+}
+
+// refreshTokenFromRequest reads the refresh token from its cookie or the JSON body
+func refreshTokenFromRequest(r *http.Request) string {
+	if cookie, err := r.Cookie("refreshToken"); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	var body RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err == nil {
+		return body.RefreshToken
+	}
+	return ""
+}
+
 // GetAuthStatus checks if user is authenticated
 func (c *UserController) GetAuthStatus(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (set by auth middleware)
 	user, ok := middlewares.GetUserFromContext(r.Context())
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		problem.Error(w, r, errUnauthorized)
 		return
 	}
 
@@ -79,6 +135,7 @@ func (c *UserController) GetAuthStatus(w http.ResponseWriter, r *http.Request) {
 			"lastName":  user.LastName,
 			"email":     user.Email,
 			"role":      user.Role,
+			"emailVerified": user.EmailVerified,
 		},
 	})
 }
@@ -88,25 +145,21 @@ func (c *UserController) LoginUser(w http.ResponseWriter, r *http.Request) {
 	var loginRequest LoginRequest
 
 	// Parse request body
-	err := json.NewDecoder(r.Body).Decode(&loginRequest)
+	err := decodeBody(r, &loginRequest)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		problem.Error(w, r, err)
 		return
 	}
 
 	// Authenticate user
 	authResponse, err := c.userService.AuthenticateUser(loginRequest.Email, loginRequest.Password)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Error(w, r, err)
 		return
 	}
 
-	// Set auth cookie with the token
-	setCookie(w, "authToken", authResponse.Token, 24*60*60) // 24 hours
+	// Set auth cookies with the session tokens
+	setSessionCookies(w, authResponse)
 
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
@@ -120,29 +173,81 @@ func (c *UserController) LoginUser(w http.ResponseWriter, r *http.Request) {
 			"role":      authResponse.User.Role,
 			"token":     authResponse.Token,
 		},
+		"refreshToken": authResponse.RefreshToken,
 	})
 }
 
 // LogoutUser handles user logout
 func (c *UserController) LogoutUser(w http.ResponseWriter, r *http.Request) {
-	// Clear the cookie by setting an expired cookie
-	setCookie(w, "authToken", "", -1)
+	// Revoke the session so its tokens stop working immediately
+	if refreshToken := refreshTokenFromRequest(r); refreshToken != "" {
+		if err := c.userService.Sessions().RevokeSessionByRefreshToken(refreshToken, "logout"); err != nil {
+			log.Println("Failed to revoke session on logout:", err)
+		}
+	}
+
+	// Clear the cookies by setting expired cookies
+	clearSessionCookies(w)
 
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "User logged out successfully"})
 }
 
+// LogoutAllDevices revokes every session of the authenticated user
+func (c *UserController) LogoutAllDevices(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	revoked, err := c.userService.Sessions().RevokeAllForUser(user.ID, "logout all devices")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	clearSessionCookies(w)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":         "Logged out of all devices",
+		"revokedSessions": revoked,
+	})
+}
+
+// RefreshToken exchanges a refresh token for a new access and refresh token
+func (c *UserController) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	authResponse, err := c.userService.RefreshSession(refreshTokenFromRequest(r))
+	if err != nil {
+		// The session is gone or its account is locked; drop its cookies
+		if errors.Is(err, services.ErrUnauthorized) || errors.Is(err, services.ErrForbidden) {
+			clearSessionCookies(w)
+		}
+		problem.Error(w, r, err)
+		return
+	}
+
+	// Set auth cookies with the rotated tokens
+	setSessionCookies(w, authResponse)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user":         authResponse.User,
+		"token":        authResponse.Token,
+		"refreshToken": authResponse.RefreshToken,
+	})
+}
+
 // RegisterUser handles user registration
 func (c *UserController) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	var registerRequest RegisterRequest
 
 	// Parse request body
-	err := json.NewDecoder(r.Body).Decode(&registerRequest)
+	err := decodeBody(r, &registerRequest)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		problem.Error(w, r, err)
 		return
 	}
 
@@ -159,14 +264,12 @@ func (c *UserController) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	// Create user
 	authResponse, err := c.userService.CreateUser(user)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Error(w, r, err)
 		return
 	}
 
-	// Set auth cookie with the token
-	setCookie(w, "authToken", authResponse.Token, 24*60*60) // 24 hours
+	// Set auth cookies with the session tokens
+	setSessionCookies(w, authResponse)
 
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
@@ -179,7 +282,9 @@ func (c *UserController) RegisterUser(w http.ResponseWriter, r *http.Request) {
 			"lastName":  authResponse.User.LastName,
 			"email":     authResponse.User.Email,
 			"role":      authResponse.User.Role,
+			"token":     authResponse.Token,
 		},
+		"refreshToken": authResponse.RefreshToken,
 	})
 }
 
@@ -187,7 +292,7 @@ func (c *UserController) RegisterUser(w http.ResponseWriter, r *http.Request) {
 func (c *UserController) GetMyProfile(w http.ResponseWriter, r *http.Request) {
 	user, ok := middlewares.GetUserFromContext(r.Context())
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		problem.Error(w, r, errUnauthorized)
 		return
 	}
 
@@ -200,6 +305,91 @@ func (c *UserController) GetMyProfile(w http.ResponseWriter, r *http.Request) {
 			"email":     user.Email,
 			"phone":     user.Phone,
 			"role":      user.Role,
+			"emailVerified": user.EmailVerified,
 		},
 	})
-}
\ No newline at end of file
+}
+// ForgotPassword emails a password reset link. It always reports success so
+// that it cannot be used to discover registered emails
+func (c *UserController) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var forgotRequest ForgotPasswordRequest
+	err := decodeBody(r, &forgotRequest)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err := c.userService.RequestPasswordReset(forgotRequest.Email); err != nil {
+		log.Println("Failed to send password reset email:", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "If that email is registered, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password using a password reset token
+func (c *UserController) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var resetRequest ResetPasswordRequest
+	err := decodeBody(r, &resetRequest)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err := c.userService.ResetPassword(resetRequest.Token, resetRequest.Password); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// The reset signed the user out everywhere, including this browser
+	clearSessionCookies(w)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password has been reset, please log in"})
+}
+
+// VerifyEmail confirms the user's email address using a verification token
+func (c *UserController) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var verifyRequest VerifyEmailRequest
+	err := decodeBody(r, &verifyRequest)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	user, err := c.userService.VerifyEmail(verifyRequest.Token)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Email verified successfully",
+		"user":    services.ToUserResponse(*user),
+	})
+}
+
+// ResendVerificationEmail sends the authenticated user a new verification link
+func (c *UserController) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	user, ok := middlewares.GetUserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, errUnauthorized)
+		return
+	}
+
+	if user.EmailVerified {
+		problem.Error(w, r, services.ErrEmailAlreadyVerified)
+		return
+	}
+
+	if err := c.userService.SendVerificationEmail(&user); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Verification email sent"})
+}
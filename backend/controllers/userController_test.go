@@ -0,0 +1,49 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"gatorswamp/models"
+)
+
+func TestAccountEmailRateLimits(t *testing.T) {
+	srv := newServer(t)
+	// Only unverified users can have the verification email resent
+	res, err := srv.svc.Users.CreateUser(models.Users{FirstName: "Test", LastName: "User", Email: "gator@ufl.edu", Password: "Str0ngPass"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	token := res.Token
+
+	// Each step asks for an email and expects the status code; the steps
+	// share the client address of the test server
+	tests := []struct {
+		name  string
+		path  string
+		email string
+		token string
+		want  int
+	}{
+		{"first reset", "/api/users/password/forgot", "gator@ufl.edu", "", http.StatusAccepted},
+		{"second reset", "/api/users/password/forgot", "Gator@UFL.edu", "", http.StatusAccepted},
+		{"resend shares the address", "/api/users/verify-email/resend", "", token, http.StatusAccepted},
+		{"other address", "/api/users/password/forgot", "alligator@ufl.edu", "", http.StatusAccepted},
+		{"address exhausted", "/api/users/verify-email/resend", "", token, http.StatusTooManyRequests},
+		{"client exhausted", "/api/users/password/forgot", "croc@ufl.edu", "", http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		var body any
+		if tt.email != "" {
+			body = map[string]string{"email": tt.email}
+		}
+		var problem problemBody
+		if status := srv.call(t, "POST", tt.path, tt.token, body, &problem); status != tt.want {
+			t.Fatalf("%s: status = %d, want %d", tt.name, status, tt.want)
+		}
+		if tt.want == http.StatusTooManyRequests && problem.Code != "rate_limited" {
+			t.Errorf("%s: code = %q, want rate_limited", tt.name, problem.Code)
+		}
+	}
+}
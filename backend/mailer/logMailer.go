@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogMailer is a development mailer that logs messages and, when dir is
+// set, writes each one to an .eml file there instead of sending it
+type LogMailer struct {
+	dir  string
+	from string
+}
+
+// NewLogMailer creates a log mailer writing to dir, if not empty
+func NewLogMailer(dir, from string) *LogMailer {
+	return &LogMailer{dir: dir, from: from}
+}
+
+// Send logs msg and stores it in the outbox directory
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("mail to=%q subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+
+	if m.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	return os.WriteFile(filepath.Join(m.dir, name), formatMessage(m.from, msg), 0o644)
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"gatorswamp/config"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var (
+	defaultMailer Mailer
+	defaultOnce   sync.Once
+)
+
+// Default returns the process-wide mailer selected by MAIL_DRIVER
+func Default() Mailer {
+	defaultOnce.Do(func() {
+		switch config.MailDriver() {
+		case "smtp":
+			defaultMailer = NewSMTPMailer(config.SMTPHost(), config.SMTPPort(), config.SMTPUsername(), config.SMTPPassword(), config.MailFrom())
+		default:
+			defaultMailer = NewLogMailer(config.MailOutboxDir(), config.MailFrom())
+		}
+		log.Printf("Using %s mailer", config.MailDriver())
+	})
+	return defaultMailer
+}
@@ -2,62 +2,156 @@ package main
 
 import (
     "context"
+    "errors"
+    "flag"
     "log"
+    "log/slog"
     "net/http"
     "os"
+    "os/signal"
+    "strconv"
     "strings"
+    "syscall"
     "time"
 
+    "gatorswamp/blobstore"
     "gatorswamp/config"
+    "gatorswamp/controllers"
+    "gatorswamp/metrics"
+    "gatorswamp/middlewares"
+    "gatorswamp/problem"
+    "gatorswamp/repositories/instrumentedrepo"
+    "gatorswamp/repositories/memoryrepo"
+    "gatorswamp/repositories/mongorepo"
     "gatorswamp/routes"
+    "gatorswamp/services"
     "github.com/gorilla/handlers"
     "github.com/gorilla/mux"
     "github.com/joho/godotenv"
     "go.mongodb.org/mongo-driver/mongo"
     "go.mongodb.org/mongo-driver/mongo/options"
+    "go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 func main() {
+    // Log as JSON; the standard logger is routed through the same handler
+    slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
+    configPath := flag.String("config", "", "path to a YAML config file (default $CONFIG_FILE)")
+    printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+    flag.Parse()
+
     // Load .env
     if err := godotenv.Load(); err != nil {
         log.Println("Warning: no .env file found")
     }
 
-    // MongoDB setup
-    log.Println("Connecting to MongoDB…")
-    client, err := mongo.NewClient(options.Client().ApplyURI(config.EnvMongoURI()))
+    // Settings come from defaults, the optional config file and the environment
+    if *configPath == "" {
+        *configPath = os.Getenv("CONFIG_FILE")
+    }
+    cfg, err := config.Load(*configPath)
+    if *printConfig && cfg != nil {
+        // Printed even when it does not validate, to help find the problem
+        if err := cfg.Print(os.Stdout); err != nil {
+            log.Fatal("Error printing config:", err)
+        }
+    }
     if err != nil {
-        log.Fatal("Error creating client:", err)
+        log.Fatalf("Invalid configuration:\n%v", err)
     }
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-    defer cancel()
-    if err := client.Connect(ctx); err != nil {
-        log.Fatal("Error connecting to MongoDB:", err)
+    if *printConfig {
+        return
     }
-    defer client.Disconnect(ctx)
-    dbName := os.Getenv("DB_NAME")
-    if dbName == "" {
-        dbName = "Gator-Homes"
+    config.Set(cfg)
+
+    // Storage setup. ping backs the readiness probe
+    var repos services.Repositories
+    var ping func(ctx context.Context) error
+    switch cfg.Storage {
+    case "memory":
+        log.Println("Using in-memory storage; data will be lost on restart")
+        repos = memoryrepo.NewRepositories()
+    case "mongo":
+        log.Println("Connecting to MongoDB…")
+        client, err := mongo.NewClient(options.Client().ApplyURI(cfg.Mongo.URI))
+        if err != nil {
+            log.Fatal("Error creating client:", err)
+        }
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+        if err := client.Connect(ctx); err != nil {
+            log.Fatal("Error connecting to MongoDB:", err)
+        }
+        defer func() {
+            ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+            defer cancel()
+            if err := client.Disconnect(ctx); err != nil {
+                log.Println("Error disconnecting from MongoDB:", err)
+            }
+            log.Println("Disconnected from MongoDB")
+        }()
+        ping = func(ctx context.Context) error {
+            return client.Ping(ctx, readpref.Primary())
+        }
+        db := client.Database(cfg.Mongo.Database)
+        log.Printf("Using database %q", cfg.Mongo.Database)
+        repos = mongorepo.NewRepositories(db)
     }
-    db := client.Database(dbName)
-    log.Printf("Using database %q", dbName)
+
+    // Record latency and failures of every storage operation
+    repos = instrumentedrepo.Wrap(cfg.Storage, repos)
+
+    // Wire up services
+    uploads := blobstore.NewLocalStore(cfg.Uploads.Dir, cfg.Uploads.URLPrefix)
+    svc := services.New(repos, uploads)
+    health := controllers.NewHealthController(ping)
 
     // Build router
     r := mux.NewRouter()
-    r.Use(loggingMiddleware)
+    r.Use(middlewares.MetricsMiddleware)
+
+    // Liveness and readiness probes
+    routes.SetupHealthRoutes(r, health)
+
+    // Metrics go on a separate admin listener when one is configured, otherwise
+    // on the main port behind a bearer token
+    var metricsServer *http.Server
+    if addr := cfg.Metrics.Addr; addr != "" {
+        admin := http.NewServeMux()
+        admin.Handle("/metrics", metrics.Handler())
+        metricsServer = &http.Server{
+            Addr:              addr,
+            Handler:           admin,
+            ReadHeaderTimeout: 10 * time.Second,
+        }
+        go func() {
+            log.Printf("Metrics listening on %s", addr)
+            if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+                log.Fatal("Metrics server failed:", err)
+            }
+        }()
+    } else if token := cfg.Metrics.Token; token != "" {
+        r.Handle("/metrics", metrics.RequireToken(token, metrics.Handler())).Methods("GET")
+    } else {
+        log.Println("Metrics disabled: set METRICS_ADDR or METRICS_TOKEN to expose /metrics")
+    }
 
     // Register API subrouters
     api := r.PathPrefix("/api").Subrouter()
-    routes.SetupUserRoutes(api.PathPrefix("/users").Subrouter(), db)
-    routes.SetupHousingRoutes(api.PathPrefix("/housing").Subrouter(), db)
-    routes.SetupRequestRoutes(api.PathPrefix("/requests").Subrouter(), db)
+    routes.SetupUserRoutes(api.PathPrefix("/users").Subrouter(), svc)
+    routes.SetupHousingRoutes(api.PathPrefix("/housing").Subrouter(), svc)
+    routes.SetupRequestRoutes(api.PathPrefix("/requests").Subrouter(), svc)
+    routes.SetupFavoriteRoutes(api.PathPrefix("/favorites").Subrouter(), svc)
+    routes.SetupSavedSearchRoutes(api.PathPrefix("/saved-searches").Subrouter(), svc)
+    routes.SetupTourRoutes(api.PathPrefix("/tours").Subrouter(), svc)
+    routes.SetupAgentRoutes(api.PathPrefix("/agent").Subrouter(), svc)
+
+    // Serve uploaded listing photos
+    r.PathPrefix(cfg.Uploads.URLPrefix + "/").Handler(http.StripPrefix(cfg.Uploads.URLPrefix, uploads.Handler()))
 
     // Static file serving for the React app
-    staticRoot := "../frontend/dist"
-    if _, err := os.Stat(staticRoot); os.IsNotExist(err) {
-        // Try the alternative path for production
-        staticRoot = "../../frontend/dist"
-    }
+    staticRoot := cfg.StaticRoot
 
     // 1. Serve static assets directly
     r.PathPrefix("/assets/").Handler(http.StripPrefix("/assets/", http.FileServer(http.Dir(staticRoot+"/assets"))))
@@ -69,8 +163,9 @@ func main() {
 
     // 3. For all other non-API routes, serve the React index.html
     r.PathPrefix("/").Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-        // Skip API routes as they should be handled by the API handlers
+        // API paths that no handler matched are not part of the React app
         if strings.HasPrefix(req.URL.Path, "/api/") {
+            problem.Write(w, req, http.StatusNotFound, "route_not_found", "no API route matches this path")
             return
         }
         
@@ -92,32 +187,80 @@ func main() {
     }))
 
     // CORS wrapper
-    allowed := []string{
-        "http://localhost:5173",
-        "http://localhost:3000",
-        "http://localhost:5500",
-        "https://gatorswamp.onrender.com",
-    }
     corsHandler := handlers.CORS(
-        handlers.AllowedOrigins(allowed),
+        handlers.AllowedOrigins(cfg.AllowedOrigins),
         handlers.AllowCredentials(),
         handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-        handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
+        handlers.AllowedHeaders([]string{"Content-Type", "Authorization", middlewares.RequestIDHeader}),
+        handlers.ExposedHeaders([]string{middlewares.RequestIDHeader}),
     )
 
-    port := os.Getenv("PORT")
-    if port == "" {
-        port = "5500"  // Default to 5500
+    port := strconv.Itoa(cfg.Port)
+
+    requestLogger := middlewares.RequestLogger(slog.Default())
+    server := &http.Server{
+        Addr:              ":" + port,
+        Handler:           requestLogger(corsHandler(r)),
+        ReadHeaderTimeout: 10 * time.Second,
+        ReadTimeout:       cfg.HTTP.ReadTimeout,
+        WriteTimeout:      cfg.HTTP.WriteTimeout,
+        IdleTimeout:       cfg.HTTP.IdleTimeout,
     }
 
-    log.Printf("Server running on port %s", port)
-    log.Fatal(http.ListenAndServe(":"+port, corsHandler(r)))
-}
+    stop, cancelSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer cancelSignals()
 
-// loggingMiddleware logs all incoming requests
-func loggingMiddleware(next http.Handler) http.Handler {
-    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        log.Printf("%s %s %s", r.RemoteAddr, r.Method, r.URL)
-        next.ServeHTTP(w, r)
-    })
-}
\ No newline at end of file
+    // Start listening right away so the liveness probe answers while the
+    // indexes are built; the readiness probe waits for them
+    serverErr := make(chan error, 1)
+    go func() {
+        log.Printf("Server running on port %s", port)
+        if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+            serverErr <- err
+        }
+    }()
+
+    if err := svc.EnsureIndexes(); err != nil {
+        log.Fatal("Error creating indexes:", err)
+    }
+    health.SetReady(true)
+
+    // Match saved listings against saved searches until the server has
+    // drained, so that listings saved by in-flight requests are matched too
+    alerts, stopAlerts := context.WithCancel(context.Background())
+    defer stopAlerts()
+    alertsDone := make(chan struct{})
+    go func() {
+        defer close(alertsDone)
+        svc.Alerts.Run(alerts)
+    }()
+
+    // Wait for SIGINT or SIGTERM, then stop taking traffic and drain
+    // in-flight requests and the alert worker before the deferred database
+    // disconnect runs
+    select {
+    case err := <-serverErr:
+        log.Fatal("Server failed:", err)
+    case <-stop.Done():
+    }
+
+    log.Println("Shutting down, draining in-flight requests")
+    health.SetReady(false)
+    ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+    defer cancel()
+    if metricsServer != nil {
+        if err := metricsServer.Shutdown(ctx); err != nil {
+            log.Println("Error shutting down metrics server:", err)
+        }
+    }
+    if err := server.Shutdown(ctx); err != nil {
+        log.Println("Error shutting down server:", err)
+    }
+    stopAlerts()
+    select {
+    case <-alertsDone:
+    case <-ctx.Done():
+        log.Println("Alert worker did not stop within the shutdown timeout")
+    }
+    log.Println("Server stopped")
+}
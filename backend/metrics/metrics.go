@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name
+const namespace = "gatorswamp"
+
+// Registry holds the application metrics together with the Go runtime and
+// process collectors
+var Registry = prometheus.NewRegistry()
+
+// HTTP metrics, labelled by the mux route template rather than the raw path
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method and route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	HTTPInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+)
+
+// AuthFailures counts requests rejected by the auth middleware
+var AuthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "auth",
+	Name:      "failures_total",
+	Help:      "Requests rejected by the auth middleware by reason.",
+}, []string{"reason"})
+
+// PermissionDenials counts requests refused for lack of a permission
+var PermissionDenials = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "auth",
+	Name:      "permission_denials_total",
+	Help:      "Requests refused for lack of a permission by permission.",
+}, []string{"permission"})
+
+// RateLimited counts requests refused by a rate limiter
+var RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "http",
+	Name:      "rate_limited_total",
+	Help:      "Requests refused by a rate limiter by limiter.",
+}, []string{"limiter"})
+
+// AlertsDropped counts saved listings not matched against saved searches
+// because the alert queue was full
+var AlertsDropped = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "alerts",
+	Name:      "dropped_listings_total",
+	Help:      "Saved listings not matched against saved searches because the alert queue was full.",
+})
+
+// Storage metrics, labelled by backend, repository and method
+var (
+	DBDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "operation_duration_seconds",
+		Help:      "Repository operation latency.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"store", "repository", "operation"})
+
+	DBErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "operation_errors_total",
+		Help:      "Repository operations that failed. Lookups that find nothing are not errors.",
+	}, []string{"store", "repository", "operation"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		HTTPInFlight,
+		AuthFailures,
+		PermissionDenials,
+		RateLimited,
+		AlertsDropped,
+		DBDuration,
+		DBErrors,
+	)
+}
+
+// ObserveDB records the latency of a storage operation and whether it failed
+func ObserveDB(store, repository, operation string, start time.Time, failed bool) {
+	DBDuration.WithLabelValues(store, repository, operation).Observe(time.Since(start).Seconds())
+	if failed {
+		DBErrors.WithLabelValues(store, repository, operation).Inc()
+	}
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// RequireToken protects a handler with a static bearer token, as sent by
+// Prometheus' authorization scrape setting
+func RequireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, []byte("Bearer "+token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
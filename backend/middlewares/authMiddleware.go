@@ -2,16 +2,16 @@ package middlewares
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 	"net/http"
 	"strings"
 
+	"gatorswamp/metrics"
 	"gatorswamp/models"
+	"gatorswamp/problem"
+	"gatorswamp/services"
 	"gatorswamp/utils"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // Define a custom type for context keys
@@ -21,7 +21,7 @@ type contextKey string
 const ContextUserKey contextKey = "user"
 
 // AuthMiddleware verifies the token and attaches the user to the request context
-func AuthMiddleware(userCollection *mongo.Collection) func(next http.Handler) http.Handler {
+func AuthMiddleware(userService *services.UserService) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get token from Authorization header or cookie
@@ -49,12 +49,8 @@ func AuthMiddleware(userCollection *mongo.Collection) func(next http.Handler) ht
 			
 			// If still no token, return unauthorized
 			if token == "" {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusUnauthorized)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"isAuthenticated": false,
-					"message": "No token provided",
-				})
+				metrics.AuthFailures.WithLabelValues("no_token").Inc()
+				problem.Write(w, r, http.StatusUnauthorized, "no_token", "No token provided")
 				return
 			}
 			
@@ -62,55 +58,61 @@ func AuthMiddleware(userCollection *mongo.Collection) func(next http.Handler) ht
 			claims, err := utils.ValidateToken(token)
 			if err != nil {
 				log.Println("Invalid token:", err)
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusUnauthorized)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"isAuthenticated": false,
-					"message": "Invalid token",
-				})
+				metrics.AuthFailures.WithLabelValues("invalid_token").Inc()
+				problem.Write(w, r, http.StatusUnauthorized, "invalid_token", "Invalid token")
 				return
 			}
 			
 			// Extract user ID from claims
 			userIDStr, ok := (*claims)["userID"].(string)
 			if !ok {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusUnauthorized)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"isAuthenticated": false,
-					"message": "Invalid token format",
-				})
+				metrics.AuthFailures.WithLabelValues("invalid_claims").Inc()
+				problem.Write(w, r, http.StatusUnauthorized, "invalid_claims", "Invalid token format")
 				return
 			}
 			
-			// Convert string ID to ObjectID
-			userID, err := primitive.ObjectIDFromHex(userIDStr)
-			if err != nil {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusUnauthorized)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"isAuthenticated": false,
-					"message": "Invalid user ID",
-				})
+			// Reject tokens whose session has been revoked or has expired
+			sessionID, _ := (*claims)["sid"].(string)
+			active, err := userService.Sessions().IsActive(sessionID)
+			if err != nil || !active {
+				metrics.AuthFailures.WithLabelValues("session_revoked").Inc()
+				problem.Write(w, r, http.StatusUnauthorized, "session_revoked", "Session revoked")
+				return
+			}
+			
+			// Check the user ID is well formed
+			if _, err := primitive.ObjectIDFromHex(userIDStr); err != nil {
+				metrics.AuthFailures.WithLabelValues("invalid_user_id").Inc()
+				problem.Write(w, r, http.StatusUnauthorized, "invalid_user_id", "Invalid user ID")
 				return
 			}
 			
 			// Find user in database
-			var user models.Users
-			err = userCollection.FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user)
+			user, err := userService.GetUserByID(userIDStr)
 			if err != nil {
 				log.Println("User not found:", err)
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusNotFound)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"isAuthenticated": false,
-					"message": "User not found",
-				})
+				metrics.AuthFailures.WithLabelValues("user_not_found").Inc()
+				problem.Write(w, r, http.StatusNotFound, "user_not_found", "User not found")
+				return
+			}
+			
+			// Attribute the request to the user in the request log
+			setLogUserID(r.Context(), user.ID.Hex())
+
+			// Reject accounts an admin has disabled or locked pending a
+			// password reset, even if their token is still valid
+			if user.Disabled || user.PasswordResetRequired {
+				message, reason := "Account disabled", "account_disabled"
+				if !user.Disabled {
+					message, reason = "Password reset required", "password_reset_required"
+				}
+				metrics.AuthFailures.WithLabelValues(reason).Inc()
+				problem.Write(w, r, http.StatusForbidden, reason, message)
 				return
 			}
 			
 			// Add user to context
-			ctx := context.WithValue(r.Context(), ContextUserKey, user)
+			ctx := context.WithValue(r.Context(), ContextUserKey, *user)
 			
 			// Proceed to next handler
 			next.ServeHTTP(w, r.WithContext(ctx))
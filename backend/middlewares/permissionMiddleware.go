@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"log/slog"
+	"net/http"
+
+	"gatorswamp/authz"
+	"gatorswamp/metrics"
+	"gatorswamp/models"
+	"gatorswamp/problem"
+	"github.com/gorilla/mux"
+)
+
+// RequirePermission lets a request through only if the authenticated user
+// holds every permission in some scope. Handlers check ownership for
+// permissions the user only holds on their own resources. It must run after
+// AuthMiddleware
+func RequirePermission(permissions ...authz.Permission) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := GetUserFromContext(r.Context())
+			if !ok {
+				problem.Write(w, r, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+				return
+			}
+
+			for _, permission := range permissions {
+				if !authz.Can(user, permission) {
+					PermissionDenied(w, r, user, permission)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// PermissionDenied logs that user lacks permission for the request and
+// writes a 403 response
+func PermissionDenied(w http.ResponseWriter, r *http.Request, user models.Users, permission authz.Permission) {
+	route := r.URL.Path
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			route = template
+		}
+	}
+
+	metrics.PermissionDenials.WithLabelValues(string(permission)).Inc()
+	slog.Warn("permission denied",
+		slog.String("requestId", RequestIDFromContext(r.Context())),
+		slog.String("method", r.Method),
+		slog.String("route", route),
+		slog.String("userId", user.ID.Hex()),
+		slog.String("role", user.Role),
+		slog.String("permission", string(permission)),
+	)
+
+	problem.Write(w, r, http.StatusForbidden, "permission_denied", "Permission "+string(permission)+" required")
+}
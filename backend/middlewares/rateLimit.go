@@ -0,0 +1,108 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"gatorswamp/config"
+	"gatorswamp/metrics"
+	"gatorswamp/problem"
+	"gatorswamp/ratelimit"
+	"gatorswamp/services"
+)
+
+// maxPeekedBody bounds how much of a request body BodyEmail reads
+const maxPeekedBody = 64 << 10
+
+// RateLimit refuses requests with 429 once the bucket that key picks for
+// them is empty. Buckets are named after the limiter so that limiters can
+// share a store. Requests key returns no key for are let through, as are
+// all requests when the store fails
+func RateLimit(store ratelimit.Store, name string, limit ratelimit.Limit, key func(*http.Request) string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wait, err := store.Take(r.Context(), name+":"+k, limit, time.Now())
+			if err != nil {
+				slog.Error("rate limiter failed",
+					slog.String("requestId", RequestIDFromContext(r.Context())),
+					slog.String("limiter", name),
+					slog.String("error", err.Error()),
+				)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if wait > 0 {
+				metrics.RateLimited.WithLabelValues(name).Inc()
+				problem.Error(w, r, services.TooManyRequests("rate_limited", "too many requests, try again later", wait))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the address of the client that sent r. Behind a trusted
+// proxy it is the last address the proxy appended to X-Forwarded-For
+func ClientIP(r *http.Request) string {
+	if config.Current().HTTP.TrustProxy {
+		forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if ip := strings.TrimSpace(forwarded[len(forwarded)-1]); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// UserEmail returns the lowercased email of the authenticated user, or an
+// empty string. It must run after AuthMiddleware
+func UserEmail(r *http.Request) string {
+	user, ok := GetUserFromContext(r.Context())
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(user.Email))
+}
+
+// BodyEmail returns the lowercased email field of a JSON request body, or an
+// empty string. The body is left for the handler to read
+func BodyEmail(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+
+	peeked, err := io.ReadAll(io.LimitReader(r.Body, maxPeekedBody))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), r.Body), r.Body}
+	if err != nil {
+		return ""
+	}
+
+	var body struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(peeked, &body) != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(body.Email))
+}
@@ -0,0 +1,182 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RequestIDHeader carries the correlation ID of a request
+const RequestIDHeader = "X-Request-ID"
+
+// requestInfoKey stores the per-request log details in the context
+const requestInfoKey contextKey = "requestInfo"
+
+// requestInfo collects details about a request for its log line. It is
+// created before the handlers run so that they can fill in what they learn
+type requestInfo struct {
+	id     string
+	userID string
+}
+
+// RequestIDFromContext returns the ID of the request being served
+func RequestIDFromContext(ctx context.Context) string {
+	if info, ok := ctx.Value(requestInfoKey).(*requestInfo); ok {
+		return info.id
+	}
+	return ""
+}
+
+// setLogUserID records the authenticated user on the request's log line
+func setLogUserID(ctx context.Context, userID string) {
+	if info, ok := ctx.Value(requestInfoKey).(*requestInfo); ok {
+		info.userID = userID
+	}
+}
+
+// RequestLogger assigns every request an ID, echoes it in the X-Request-ID
+// response header and in JSON error bodies, and logs one structured line per
+// request once it completes. A valid incoming X-Request-ID is reused
+func RequestLogger(logger *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			info := &requestInfo{id: r.Header.Get(RequestIDHeader)}
+			if !validRequestID(info.id) {
+				info.id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, info.id)
+
+			rec := &responseRecorder{ResponseWriter: w, requestID: info.id}
+			next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), requestInfoKey, info)))
+			rec.finish()
+
+			level := slog.LevelInfo
+			switch {
+			case rec.status >= 500:
+				level = slog.LevelError
+			case rec.status >= 400:
+				level = slog.LevelWarn
+			}
+
+			attrs := []slog.Attr{
+				slog.String("requestId", info.id),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rec.status),
+				slog.Int("bytes", rec.bytes),
+				slog.Float64("durationMs", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remoteAddr", r.RemoteAddr),
+				slog.String("userAgent", r.UserAgent()),
+			}
+			if info.userID != "" {
+				attrs = append(attrs, slog.String("userId", info.userID))
+			}
+			logger.LogAttrs(r.Context(), level, "request", attrs...)
+		})
+	}
+}
+
+// validRequestID accepts client IDs of sensible length made of characters
+// that are safe to log and echo back
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 128-bit hex ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// responseRecorder captures the status and size of a response. JSON error
+// bodies are held back so the request ID can be added to them
+type responseRecorder struct {
+	http.ResponseWriter
+	requestID   string
+	status      int
+	bytes       int
+	wroteHeader bool
+	errorBody   *bytes.Buffer
+}
+
+// WriteHeader records the status, deferring JSON error responses until
+// their body is complete
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = status
+
+	if status >= 400 && isJSON(rec.Header().Get("Content-Type")) {
+		rec.errorBody = &bytes.Buffer{}
+		return
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the size of the body, buffering JSON error bodies
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.errorBody != nil {
+		return rec.errorBody.Write(b)
+	}
+
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// finish writes a held-back error body with the request ID added
+func (rec *responseRecorder) finish() {
+	if !rec.wroteHeader {
+		// Nothing was written; net/http will send an empty 200
+		rec.status = http.StatusOK
+		return
+	}
+	if rec.errorBody == nil {
+		return
+	}
+
+	body := rec.errorBody.Bytes()
+	if object := bytes.TrimSpace(body); len(object) >= 2 && object[0] == '{' && json.Valid(object) {
+		// Append the member before the closing brace to keep the field order
+		id, _ := json.Marshal(rec.requestID)
+		withID := append([]byte{}, object[:len(object)-1]...)
+		if len(bytes.TrimSpace(object[1:len(object)-1])) > 0 {
+			withID = append(withID, ',')
+		}
+		withID = append(withID, `"requestId":`...)
+		withID = append(withID, id...)
+		body = append(withID, '}', '\n')
+	}
+
+	rec.ResponseWriter.WriteHeader(rec.status)
+	n, _ := rec.ResponseWriter.Write(body)
+	rec.bytes += n
+}
+
+// isJSON reports whether contentType is plain JSON or problem details
+func isJSON(contentType string) bool {
+	return strings.HasPrefix(contentType, "application/json") ||
+		strings.HasPrefix(contentType, "application/problem+json")
+}
@@ -0,0 +1,179 @@
+package migrations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// EmbeddedAgentsReport summarises a run of MigrateEmbeddedAgents
+type EmbeddedAgentsReport struct {
+	// Linked is the number of listings given an agentId
+	Linked int `json:"linked"`
+	// Reused is the number of existing agent accounts listings were linked to
+	Reused int `json:"reused"`
+	// Created is the number of placeholder agent accounts created
+	Created int `json:"created"`
+	// Tours is the number of tours moved to the agent account's feed
+	Tours int64 `json:"tours"`
+}
+
+// MigrateEmbeddedAgents links housing documents that only embed their
+// agent's name and phone to an agent account. Listings are matched to
+// existing agent accounts by name and phone; a placeholder account is
+// created for every other agent. Placeholder accounts are disabled and must
+// reset their password, so they cannot be signed into until an admin
+// hands them to the agent by setting their real email, or reassigns the
+// listings to the agent's own account. Tours booked with the
+// agent are moved to the account's calendar feed. Listings that already
+// have an agentId are skipped, which makes the migration idempotent. When
+// dryRun is set nothing is written
+func MigrateEmbeddedAgents(ctx context.Context, db *mongo.Database, dryRun bool) (*EmbeddedAgentsReport, error) {
+	users := db.Collection("users")
+	housing := db.Collection("housing")
+	tours := db.Collection("tours")
+
+	agents, err := agentAccounts(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"agentId": bson.M{"$exists": false}}
+	cursor, err := housing.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	report := &EmbeddedAgentsReport{}
+	reused := map[primitive.ObjectID]bool{}
+	moved := map[string]bool{}
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Agent models.Agent       `bson:"agent"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return report, err
+		}
+
+		key := doc.Agent.Key()
+		if key == "" {
+			continue
+		}
+
+		agent, ok := agents[key]
+		if ok {
+			if !reused[agent.ID] && !agent.IsPlaceholderAgent() {
+				reused[agent.ID] = true
+				report.Reused++
+			}
+		} else {
+			agent, err = placeholderAgent(key, doc.Agent)
+			if err != nil {
+				return report, err
+			}
+			if !dryRun {
+				if _, err := users.InsertOne(ctx, agent); err != nil {
+					return report, err
+				}
+			}
+			agents[key] = agent
+			report.Created++
+		}
+
+		if !dryRun {
+			update := bson.M{"$set": bson.M{"agentId": agent.ID, "agent": agent.AgentContact()}}
+			if _, err := housing.UpdateOne(ctx, bson.M{"_id": doc.ID}, update); err != nil {
+				return report, err
+			}
+		}
+		report.Linked++
+
+		if moved[key] {
+			continue
+		}
+		moved[key] = true
+
+		toursFilter := bson.M{"agentKey": key}
+		if dryRun {
+			count, err := tours.CountDocuments(ctx, toursFilter)
+			if err != nil {
+				return report, err
+			}
+			report.Tours += count
+			continue
+		}
+		result, err := tours.UpdateMany(ctx, toursFilter, bson.M{"$set": bson.M{"agentKey": agent.ID.Hex()}})
+		if err != nil {
+			return report, err
+		}
+		report.Tours += result.ModifiedCount
+	}
+
+	return report, cursor.Err()
+}
+
+// agentAccounts returns the agent accounts keyed by their contact's agent
+// key. Placeholders created by an earlier run are included
+func agentAccounts(ctx context.Context, users *mongo.Collection) (map[string]models.Users, error) {
+	cursor, err := users.Find(ctx, bson.M{"role": models.RoleAgent})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	agents := map[string]models.Users{}
+	for cursor.Next(ctx) {
+		var user models.Users
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+
+		key := user.AgentContact().Key()
+		if _, taken := agents[key]; key == "" || taken {
+			continue
+		}
+		agents[key] = user
+	}
+
+	return agents, cursor.Err()
+}
+
+// placeholderAgent builds the account of an agent known only from the
+// contact details embedded in listings. Its password is random and unknown
+func placeholderAgent(key string, contact models.Agent) (models.Users, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return models.Users{}, err
+	}
+	password, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(secret)), bcrypt.DefaultCost)
+	if err != nil {
+		return models.Users{}, err
+	}
+
+	var firstName, lastName string
+	if names := strings.Fields(contact.Name); len(names) > 0 {
+		firstName = names[0]
+		lastName = strings.Join(names[1:], " ")
+	}
+
+	return models.Users{
+		ID:                    primitive.NewObjectID(),
+		FirstName:             firstName,
+		LastName:              lastName,
+		Email:                 "agent-" + key + "@" + models.PlaceholderAgentDomain,
+		Phone:                 strings.TrimSpace(contact.Phone),
+		Password:              string(password),
+		Role:                  models.RoleAgent,
+		Disabled:              true,
+		PasswordResetRequired: true,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package migrations
+
+import (
+	"context"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// BackfillHousingLocation sets the GeoJSON location of housing documents
+// that predate it from their latitude and longitude. Documents whose
+// coordinates are both zero are treated as unset and skipped. It returns
+// the number of documents updated
+func BackfillHousingLocation(ctx context.Context, collection *mongo.Collection, dryRun bool) (int, error) {
+	filter := bson.M{
+		"location":  bson.M{"$exists": false},
+		"latitude":  bson.M{"$type": "number", "$gte": -90, "$lte": 90},
+		"longitude": bson.M{"$type": "number", "$gte": -180, "$lte": 180},
+		"$nor":      bson.A{bson.M{"latitude": 0, "longitude": 0}},
+	}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	updated := 0
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID        primitive.ObjectID `bson:"_id"`
+			Latitude  float64            `bson:"latitude"`
+			Longitude float64            `bson:"longitude"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return updated, err
+		}
+
+		if !dryRun {
+			update := bson.M{"$set": bson.M{"location": models.NewGeoPoint(doc.Latitude, doc.Longitude)}}
+			if _, err := collection.UpdateOne(ctx, bson.M{"_id": doc.ID}, update); err != nil {
+				return updated, err
+			}
+		}
+		updated++
+	}
+
+	return updated, cursor.Err()
+}
+
+// ClearUnsetHousingLocation removes the GeoJSON location of housing
+// documents whose coordinates are both zero. Such listings have no
+// coordinates and were once indexed at 0,0, where they showed up in geo
+// searches. It returns the number of documents updated
+func ClearUnsetHousingLocation(ctx context.Context, collection *mongo.Collection, dryRun bool) (int64, error) {
+	filter := bson.M{
+		"location":  bson.M{"$exists": true},
+		"latitude":  0,
+		"longitude": 0,
+	}
+
+	if dryRun {
+		return collection.CountDocuments(ctx, filter)
+	}
+
+	result, err := collection.UpdateMany(ctx, filter, bson.M{"$unset": bson.M{"location": ""}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
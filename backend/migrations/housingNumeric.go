@@ -0,0 +1,178 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ParseFailure records a housing document field that could not be converted
+type ParseFailure struct {
+	ID     primitive.ObjectID `json:"id"`
+	Field  string             `json:"field"`
+	Value  interface{}        `json:"value"`
+	Reason string             `json:"reason"`
+}
+
+// HousingNumericReport summarises a run of MigrateHousingNumeric
+type HousingNumericReport struct {
+	Scanned  int            `json:"scanned"`
+	Migrated int            `json:"migrated"`
+	Failures []ParseFailure `json:"failures"`
+}
+
+// errEmpty is returned by parseNumber for blank strings
+var errEmpty = errors.New("empty value")
+
+// numberPattern accepts an optional currency sign, thousands separators and
+// a trailing unit such as "sq ft"
+var numberPattern = regexp.MustCompile(`^\$?\s*([0-9][0-9,]*(?:\.[0-9]+)?)\s*[A-Za-z.² ]*$`)
+
+// legacyHousingFilter matches documents that still carry the string schema.
+// Migrated documents no longer match, which makes the migration idempotent
+var legacyHousingFilter = bson.M{"$or": bson.A{
+	bson.M{"price": bson.M{"$exists": true}},
+	bson.M{"priceCents": bson.M{"$exists": false}},
+	bson.M{"bedrooms": bson.M{"$type": "string"}},
+	bson.M{"bathrooms": bson.M{"$type": "string"}},
+	bson.M{"surface": bson.M{"$type": "string"}},
+	bson.M{"year": bson.M{"$type": "string"}},
+}}
+
+// MigrateHousingNumeric converts the legacy string price, bedrooms,
+// bathrooms, surface and year fields of every housing document to numbers.
+// Documents with any unparseable field are left untouched and reported.
+// When dryRun is set nothing is written
+func MigrateHousingNumeric(ctx context.Context, collection *mongo.Collection, dryRun bool) (*HousingNumericReport, error) {
+	cursor, err := collection.Find(ctx, legacyHousingFilter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	report := &HousingNumericReport{Failures: []ParseFailure{}}
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return report, err
+		}
+		report.Scanned++
+
+		id, _ := doc["_id"].(primitive.ObjectID)
+		set, failures := convertHousing(id, doc)
+		if len(failures) > 0 {
+			report.Failures = append(report.Failures, failures...)
+			continue
+		}
+
+		if !dryRun {
+			update := bson.M{"$set": set, "$unset": bson.M{"price": ""}}
+			if _, err := collection.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+				return report, err
+			}
+		}
+		report.Migrated++
+	}
+
+	return report, cursor.Err()
+}
+
+// convertHousing builds the $set document for a single legacy housing document
+func convertHousing(id primitive.ObjectID, doc bson.M) (bson.M, []ParseFailure) {
+	set := bson.M{}
+	var failures []ParseFailure
+
+	fail := func(field string, value interface{}, err error) {
+		failures = append(failures, ParseFailure{ID: id, Field: field, Value: value, Reason: err.Error()})
+	}
+
+	// Price is mandatory; an existing priceCents is kept when price is gone
+	if raw, ok := doc["price"]; ok {
+		price, err := toNumber(raw)
+		if err != nil {
+			fail("price", raw, err)
+		} else {
+			set["priceCents"] = int64(math.Round(price * 100))
+		}
+	} else if _, ok := doc["priceCents"]; !ok {
+		fail("price", nil, errors.New("missing price"))
+	}
+
+	if currency, _ := doc["currency"].(string); currency == "" {
+		set["currency"] = models.DefaultCurrency
+	}
+
+	for _, field := range []string{"bedrooms", "year"} {
+		value, err := optionalNumber(doc[field])
+		switch {
+		case err != nil:
+			fail(field, doc[field], err)
+		case value != math.Trunc(value):
+			fail(field, doc[field], errors.New("not a whole number"))
+		default:
+			set[field] = int(value)
+		}
+	}
+
+	for _, field := range []string{"bathrooms", "surface"} {
+		value, err := optionalNumber(doc[field])
+		if err != nil {
+			fail(field, doc[field], err)
+			continue
+		}
+		set[field] = value
+	}
+
+	return set, failures
+}
+
+// optionalNumber converts raw to a number, treating missing or blank values as zero
+func optionalNumber(raw interface{}) (float64, error) {
+	if raw == nil {
+		return 0, nil
+	}
+	value, err := toNumber(raw)
+	if errors.Is(err, errEmpty) {
+		return 0, nil
+	}
+	return value, err
+}
+
+// toNumber converts a stored BSON value to a float64
+func toNumber(raw interface{}) (float64, error) {
+	switch v := raw.(type) {
+	case string:
+		return parseNumber(v)
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("unsupported type %T", raw)
+	}
+}
+
+// parseNumber parses a human-entered number such as "$1,500" or "950 sq ft"
+func parseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errEmpty
+	}
+
+	match := numberPattern.FindStringSubmatch(s)
+	if match == nil {
+		return 0, fmt.Errorf("cannot parse %q as a number", s)
+	}
+	return strconv.ParseFloat(strings.ReplaceAll(match[1], ",", ""), 64)
+}
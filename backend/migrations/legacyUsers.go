@@ -0,0 +1,87 @@
+package migrations
+
+import (
+	"context"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// VerifyLegacyUsers marks the users created before email verification
+// existed as verified. Their documents have no emailVerified field, which
+// decodes as unverified and would stop them requesting properties and
+// booking tours. Accounts created since always store the field. It returns
+// the number of users updated
+func VerifyLegacyUsers(ctx context.Context, users *mongo.Collection, dryRun bool) (int64, error) {
+	filter := bson.M{"emailVerified": bson.M{"$exists": false}}
+
+	if dryRun {
+		return users.CountDocuments(ctx, filter)
+	}
+
+	result, err := users.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"emailVerified": true}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// EmailCaseReport summarises a run of NormalizeUserEmails
+type EmailCaseReport struct {
+	// Normalized is the number of users whose email was lowercased
+	Normalized int `json:"normalized"`
+	// Conflicts lists the users whose email differs only in case from
+	// another user's. They are left alone for an admin to resolve, and
+	// cannot sign in until then
+	Conflicts []primitive.ObjectID `json:"conflicts"`
+}
+
+// NormalizeUserEmails stores the email of every user in the trimmed,
+// lowercase form that users are now looked up by, which the unique email
+// index relies on. Accounts registered before emails were normalized may
+// hold mixed case addresses. When dryRun is set nothing is written
+func NormalizeUserEmails(ctx context.Context, users *mongo.Collection, dryRun bool) (*EmailCaseReport, error) {
+	cursor, err := users.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"email": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	type account struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Email string             `bson:"email"`
+	}
+	var accounts []account
+	if err := cursor.All(ctx, &accounts); err != nil {
+		return nil, err
+	}
+
+	byEmail := map[string][]account{}
+	for _, a := range accounts {
+		email := models.NormalizeEmail(a.Email)
+		byEmail[email] = append(byEmail[email], a)
+	}
+
+	report := &EmailCaseReport{Conflicts: []primitive.ObjectID{}}
+	for email, group := range byEmail {
+		for _, a := range group {
+			if a.Email == email {
+				continue
+			}
+			if len(group) > 1 {
+				report.Conflicts = append(report.Conflicts, a.ID)
+				continue
+			}
+			if !dryRun {
+				if _, err := users.UpdateByID(ctx, a.ID, bson.M{"$set": bson.M{"email": email}}); err != nil {
+					return report, err
+				}
+			}
+			report.Normalized++
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Account token purposes
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+)
+
+// AccountToken is a single-use, expiring token emailed to a user. Only the
+// hash of the token is stored
+type AccountToken struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID    primitive.ObjectID  `bson:"userId" json:"userId"`
+	Purpose   string              `bson:"purpose" json:"purpose"`
+	TokenHash string              `bson:"tokenHash" json:"-"`
+	CreatedAt primitive.DateTime  `bson:"createdAt" json:"createdAt"`
+	ExpiresAt primitive.DateTime  `bson:"expiresAt" json:"expiresAt"`
+	UsedAt    *primitive.DateTime `bson:"usedAt,omitempty" json:"usedAt,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Favorite is a listing a user saved for later. A user saves a listing at
+// most once
+type Favorite struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID     primitive.ObjectID `bson:"userId" json:"userId"`
+	PropertyID primitive.ObjectID `bson:"propertyId" json:"propertyId"`
+	CreatedAt  primitive.DateTime `bson:"createdAt" json:"createdAt"`
+}
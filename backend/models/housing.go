@@ -1,31 +1,117 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// Agent represents a contact person for a property
+// Agent represents a contact person for a property. Listings with an agent
+// account copy its contact details
 type Agent struct {
-	Name  string `bson:"name" json:"name"`
-	Phone string `bson:"phone" json:"phone"`
+	Name  string `bson:"name" json:"name" validate:"max=100"`
+	Phone string `bson:"phone" json:"phone" validate:"max=30"`
+}
+
+// Key identifies the agent across listings. It ignores case, spacing and
+// phone number formatting, and is opaque so it can appear in URLs. An agent
+// without a name or phone has no key
+func (a Agent) Key() string {
+	name := strings.ToLower(strings.Join(strings.Fields(a.Name), " "))
+	phone := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, a.Phone)
+	if name == "" && phone == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(name + "|" + phone))
+	return hex.EncodeToString(sum[:12])
+}
+
+// GeoPoint is a GeoJSON point. Coordinates are ordered longitude, latitude
+type GeoPoint struct {
+	Type        string    `bson:"type" json:"type"`
+	Coordinates []float64 `bson:"coordinates" json:"coordinates"`
+}
+
+// NewGeoPoint builds a GeoJSON point from a latitude and longitude
+func NewGeoPoint(latitude, longitude float64) *GeoPoint {
+	return &GeoPoint{Type: "Point", Coordinates: []float64{longitude, latitude}}
 }
 
-// Housing represents a housing property
+// LocationOf returns the GeoJSON location of a listing at latitude and
+// longitude, or nil when both are zero, which listings use for unset
+// coordinates, so that the listing stays out of geo searches
+func LocationOf(latitude, longitude float64) *GeoPoint {
+	if latitude == 0 && longitude == 0 {
+		return nil
+	}
+	return NewGeoPoint(latitude, longitude)
+}
+
+// DefaultCurrency is the ISO 4217 code used when a listing does not specify one
+const DefaultCurrency = "USD"
+
+// HousingImage is one photo of a listing's gallery along with its thumbnail.
+// Key and ThumbnailKey locate the files in the blob store
+type HousingImage struct {
+	ID           primitive.ObjectID `bson:"id" json:"id"`
+	URL          string             `bson:"url" json:"url"`
+	ThumbnailURL string             `bson:"thumbnailUrl" json:"thumbnailUrl"`
+	Caption      string             `bson:"caption" json:"caption"`
+	Order        int                `bson:"order" json:"order"`
+	ContentType  string             `bson:"contentType" json:"contentType"`
+	Width        int                `bson:"width" json:"width"`
+	Height       int                `bson:"height" json:"height"`
+	Key          string             `bson:"key" json:"-"`
+	ThumbnailKey string             `bson:"thumbnailKey" json:"-"`
+	UploadedAt   primitive.DateTime `bson:"uploadedAt" json:"uploadedAt"`
+}
+
+// Housing represents a housing property. Prices are stored as integer
+// minor units (cents) of Currency. Image holds the cover photo, which is the
+// first gallery image once Images is populated. AgentID is the agent account
+// managing the listing, if any. PriceDrop is computed when the listing is
+// read and never stored. Version counts the saves of the listing, so that a
+// save based on an outdated copy is detected
 type Housing struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Type      string             `bson:"type" json:"type" validate:"required"`
-	Name      string             `bson:"name" json:"name" validate:"required"`
-	Image     string             `bson:"image" json:"image"`
-	County    string             `bson:"county" json:"county"`
-	Address   string             `bson:"address" json:"address" validate:"required"`
-	Bedrooms  string             `bson:"bedrooms" json:"bedrooms"`
-	Bathrooms string             `bson:"bathrooms" json:"bathrooms"`
-	Surface   string             `bson:"surface" json:"surface"`
-	Year      string             `bson:"year" json:"year"`
-	Price     string             `bson:"price" json:"price" validate:"required"`
-	Latitude  float64            `bson:"latitude" json:"latitude"`
-	Longitude float64            `bson:"longitude" json:"longitude"`
-	Agent     Agent              `bson:"agent" json:"agent"`
-	CreatedAt primitive.DateTime `bson:"createdAt" json:"createdAt"`
-	UpdatedAt primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	Type        string              `bson:"type" json:"type" validate:"required,max=50"`
+	Name        string              `bson:"name" json:"name" validate:"required,max=200"`
+	Image       string              `bson:"image" json:"image"`
+	Images      []HousingImage      `bson:"images,omitempty" json:"images,omitempty"`
+	County      string              `bson:"county" json:"county" validate:"max=100"`
+	Address     string              `bson:"address" json:"address" validate:"required,max=300"`
+	Description string              `bson:"description,omitempty" json:"description,omitempty" validate:"max=5000"`
+	Bedrooms    int                 `bson:"bedrooms" json:"bedrooms" validate:"gte=0,lte=100"`
+	Bathrooms   float64             `bson:"bathrooms" json:"bathrooms" validate:"gte=0,lte=100"`
+	Surface     float64             `bson:"surface" json:"surface" validate:"gte=0"`
+	Year        int                 `bson:"year" json:"year" validate:"omitempty,gte=1800,lte=2100"`
+	PriceCents  int64               `bson:"priceCents" json:"priceCents" validate:"gt=0"`
+	Currency    string              `bson:"currency" json:"currency" validate:"omitempty,iso4217"`
+	Latitude    float64             `bson:"latitude" json:"latitude" validate:"latitude"`
+	Longitude   float64             `bson:"longitude" json:"longitude" validate:"longitude"`
+	Location    *GeoPoint           `bson:"location,omitempty" json:"location,omitempty"`
+	Agent       Agent               `bson:"agent" json:"agent"`
+	AgentID     *primitive.ObjectID `bson:"agentId,omitempty" json:"agentId,omitempty"`
+	PriceDrop   *PriceDrop          `bson:"-" json:"priceDrop,omitempty"`
+	CreatedAt   primitive.DateTime  `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   primitive.DateTime  `bson:"updatedAt" json:"updatedAt"`
+	Version     int64               `bson:"version" json:"-"`
+}
+
+// AgentKey identifies the listing's agent across listings: the ID of their
+// account, or the key of the contact details of listings without one
+func (h Housing) AgentKey() string {
+	if h.AgentID != nil {
+		return h.AgentID.Hex()
+	}
+	return h.Agent.Key()
 }
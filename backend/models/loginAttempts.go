@@ -0,0 +1,16 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoginAttempts counts the consecutive failed logins of an email address.
+// Logins are refused until LockedUntil
+type LoginAttempts struct {
+	Email       string              `bson:"_id" json:"email"`
+	Failures    int                 `bson:"failures" json:"failures"`
+	LastFailure primitive.DateTime  `bson:"lastFailure" json:"lastFailure"`
+	LockedUntil *primitive.DateTime `bson:"lockedUntil,omitempty" json:"lockedUntil,omitempty"`
+	// ExpiresAt is when the failures are forgotten
+	ExpiresAt primitive.DateTime `bson:"expiresAt" json:"-"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PriceChange records a change of a listing's price or currency
+type PriceChange struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	PropertyID    primitive.ObjectID `bson:"propertyId" json:"propertyId"`
+	OldPriceCents int64              `bson:"oldPriceCents" json:"oldPriceCents"`
+	OldCurrency   string             `bson:"oldCurrency" json:"oldCurrency"`
+	NewPriceCents int64              `bson:"newPriceCents" json:"newPriceCents"`
+	NewCurrency   string             `bson:"newCurrency" json:"newCurrency"`
+	ChangedBy     primitive.ObjectID `bson:"changedBy" json:"changedBy"`
+	ChangedAt     primitive.DateTime `bson:"changedAt" json:"changedAt"`
+}
+
+// PriceDrop tells how much a listing's price fell within the last Days days
+type PriceDrop struct {
+	// Percent is the reduction rounded to one decimal, such as 12.5
+	Percent        float64 `json:"percent"`
+	FromPriceCents int64   `json:"fromPriceCents"`
+	Days           int     `json:"days"`
+}
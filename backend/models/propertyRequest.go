@@ -6,18 +6,59 @@ import (
 
 // Request status constants
 const (
-	StatusPending  = "pending"
-	StatusApproved = "approved"
-	StatusRejected = "rejected"
+	StatusPending     = "pending"
+	StatusApproved    = "approved"
+	StatusRejected    = "rejected"
+	StatusWithdrawn   = "withdrawn"
+	StatusLeaseSigned = "lease_signed"
+	StatusCancelled   = "cancelled"
 )
 
+// requestTransitions lists the statuses each status may move to.
+// Statuses without an entry are terminal
+var requestTransitions = map[string][]string{
+	StatusPending:  {StatusApproved, StatusRejected, StatusWithdrawn},
+	StatusApproved: {StatusLeaseSigned, StatusCancelled},
+}
+
+// IsValidRequestStatus reports whether status is a known request status
+func IsValidRequestStatus(status string) bool {
+	switch status {
+	case StatusPending, StatusApproved, StatusRejected, StatusWithdrawn, StatusLeaseSigned, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// CanTransitionRequest reports whether a request may move from one status to another
+func CanTransitionRequest(from, to string) bool {
+	for _, next := range requestTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestStatusChange is an entry in a request's status history
+type RequestStatusChange struct {
+	From      string             `bson:"from,omitempty" json:"from,omitempty"`
+	To        string             `bson:"to" json:"to"`
+	ChangedBy primitive.ObjectID `bson:"changedBy" json:"changedBy"`
+	Reason    string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	ChangedAt primitive.DateTime `bson:"changedAt" json:"changedAt"`
+}
+
 // PropertyRequest represents a user's request for a property
 type PropertyRequest struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	UserID     primitive.ObjectID `bson:"userId" json:"userId"`
-	PropertyID primitive.ObjectID `bson:"propertyId" json:"propertyId"`
-	Status     string             `bson:"status" json:"status" default:"pending"`
-	Message    string             `bson:"message" json:"message,omitempty"`
-	CreatedAt  primitive.DateTime `bson:"createdAt" json:"createdAt"`
-	UpdatedAt  primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
+	ID              primitive.ObjectID    `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID          primitive.ObjectID    `bson:"userId" json:"userId"`
+	PropertyID      primitive.ObjectID    `bson:"propertyId" json:"propertyId"`
+	Status          string                `bson:"status" json:"status" default:"pending"`
+	Message         string                `bson:"message" json:"message,omitempty"`
+	RejectionReason string                `bson:"rejectionReason,omitempty" json:"rejectionReason,omitempty"`
+	ProcessedBy     *primitive.ObjectID   `bson:"processedBy,omitempty" json:"processedBy,omitempty"`
+	History         []RequestStatusChange `bson:"history" json:"history"`
+	CreatedAt       primitive.DateTime    `bson:"createdAt" json:"createdAt"`
+	UpdatedAt       primitive.DateTime    `bson:"updatedAt" json:"updatedAt"`
 }
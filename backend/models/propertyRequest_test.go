@@ -0,0 +1,58 @@
+package models
+
+import "testing"
+
+func TestCanTransitionRequest(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{StatusPending, StatusApproved, true},
+		{StatusPending, StatusRejected, true},
+		{StatusPending, StatusWithdrawn, true},
+		{StatusPending, StatusLeaseSigned, false},
+		{StatusPending, StatusCancelled, false},
+		{StatusPending, StatusPending, false},
+		{StatusApproved, StatusLeaseSigned, true},
+		{StatusApproved, StatusCancelled, true},
+		{StatusApproved, StatusRejected, false},
+		{StatusApproved, StatusWithdrawn, false},
+		{StatusRejected, StatusApproved, false},
+		{StatusWithdrawn, StatusPending, false},
+		{StatusLeaseSigned, StatusCancelled, false},
+		{StatusCancelled, StatusApproved, false},
+		{"unknown", StatusApproved, false},
+		{StatusPending, "unknown", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			if got := CanTransitionRequest(tt.from, tt.to); got != tt.want {
+				t.Errorf("CanTransitionRequest(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValidRequestStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   bool
+	}{
+		{StatusPending, true},
+		{StatusApproved, true},
+		{StatusRejected, true},
+		{StatusWithdrawn, true},
+		{StatusLeaseSigned, true},
+		{StatusCancelled, true},
+		{"", false},
+		{"Pending", false},
+		{"archived", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsValidRequestStatus(tt.status); got != tt.want {
+			t.Errorf("IsValidRequestStatus(%q) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
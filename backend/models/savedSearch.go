@@ -0,0 +1,58 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Saved search delivery modes
+const (
+	// DeliveryInstant sends an alert as soon as a listing matches
+	DeliveryInstant = "instant"
+	// DeliveryDaily collects matches into one digest a day
+	DeliveryDaily = "daily"
+)
+
+// SearchRange is an inclusive range of a saved search; a nil bound is open
+type SearchRange struct {
+	Min *float64 `bson:"min,omitempty" json:"min,omitempty"`
+	Max *float64 `bson:"max,omitempty" json:"max,omitempty"`
+}
+
+// SearchCriteria holds the housing search filters a saved search repeats.
+// Prices are in major currency units, like the minPrice and maxPrice
+// search parameters
+type SearchCriteria struct {
+	County    string      `bson:"county,omitempty" json:"county,omitempty" validate:"max=100"`
+	Type      string      `bson:"type,omitempty" json:"type,omitempty" validate:"max=50"`
+	Price     SearchRange `bson:"price" json:"price"`
+	Bedrooms  SearchRange `bson:"bedrooms" json:"bedrooms"`
+	Bathrooms SearchRange `bson:"bathrooms" json:"bathrooms"`
+	Year      SearchRange `bson:"year" json:"year"`
+	Surface   SearchRange `bson:"surface" json:"surface"`
+}
+
+// SavedSearch is a named housing search a user is alerted about when new
+// or updated listings match it
+type SavedSearch struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID   primitive.ObjectID `bson:"userId" json:"userId"`
+	Name     string             `bson:"name" json:"name"`
+	Criteria SearchCriteria     `bson:"criteria" json:"criteria"`
+	Delivery string             `bson:"delivery" json:"delivery"`
+	// LastNotifiedAt is when the user was last sent alerts for this search
+	LastNotifiedAt *primitive.DateTime `bson:"lastNotifiedAt,omitempty" json:"lastNotifiedAt,omitempty"`
+	CreatedAt      primitive.DateTime  `bson:"createdAt" json:"createdAt"`
+	UpdatedAt      primitive.DateTime  `bson:"updatedAt" json:"updatedAt"`
+}
+
+// ListingAlert records that a listing matched a saved search. A listing
+// alerts a search at most once
+type ListingAlert struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID        primitive.ObjectID `bson:"userId" json:"userId"`
+	SavedSearchID primitive.ObjectID `bson:"savedSearchId" json:"savedSearchId"`
+	PropertyID    primitive.ObjectID `bson:"propertyId" json:"propertyId"`
+	CreatedAt     primitive.DateTime `bson:"createdAt" json:"createdAt"`
+	// DeliveredAt is unset until the alert is sent to the user
+	DeliveredAt *primitive.DateTime `bson:"deliveredAt,omitempty" json:"deliveredAt,omitempty"`
+}
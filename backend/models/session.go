@@ -0,0 +1,20 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is a login session. Its refresh token is rotated on every use;
+// the hashes of rotated-out tokens are kept so that replaying one can be
+// detected and the whole session revoked
+type Session struct {
+	ID                  primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID              primitive.ObjectID  `bson:"userId" json:"userId"`
+	RefreshTokenHash    string              `bson:"refreshTokenHash" json:"-"`
+	PreviousTokenHashes []string            `bson:"previousTokenHashes" json:"-"`
+	CreatedAt           primitive.DateTime  `bson:"createdAt" json:"createdAt"`
+	LastUsedAt          primitive.DateTime  `bson:"lastUsedAt" json:"lastUsedAt"`
+	ExpiresAt           primitive.DateTime  `bson:"expiresAt" json:"expiresAt"`
+	RevokedAt           *primitive.DateTime `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	RevokedReason       string              `bson:"revokedReason,omitempty" json:"revokedReason,omitempty"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tour status constants
+const (
+	TourBooked    = "booked"
+	TourCancelled = "cancelled"
+)
+
+// AvailabilityWindow is a period in which the agent of a listing gives tours.
+// It is split into back-to-back slots of SlotMinutes, starting at Start
+type AvailabilityWindow struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	PropertyID primitive.ObjectID `bson:"propertyId" json:"propertyId"`
+	// AgentKey identifies the agent giving the tours, whose windows may not
+	// overlap across their listings
+	AgentKey    string             `bson:"agentKey" json:"-"`
+	Start       primitive.DateTime `bson:"start" json:"start"`
+	End         primitive.DateTime `bson:"end" json:"end"`
+	SlotMinutes int                `bson:"slotMinutes" json:"slotMinutes"`
+	CreatedBy   primitive.ObjectID `bson:"createdBy" json:"createdBy"`
+	CreatedAt   primitive.DateTime `bson:"createdAt" json:"createdAt"`
+}
+
+// TimeSlot is a bookable period of an availability window
+type TimeSlot struct {
+	Start primitive.DateTime `json:"start"`
+	End   primitive.DateTime `json:"end"`
+}
+
+// Tour is a viewing of a listing booked by a user
+type Tour struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	PropertyID primitive.ObjectID `bson:"propertyId" json:"propertyId"`
+	UserID     primitive.ObjectID `bson:"userId" json:"userId"`
+	// Agent is the listing's agent when the tour was booked and AgentKey
+	// identifies them in the agent's calendar feed
+	Agent    Agent              `bson:"agent" json:"agent"`
+	AgentKey string             `bson:"agentKey" json:"-"`
+	Start    primitive.DateTime `bson:"start" json:"start"`
+	End      primitive.DateTime `bson:"end" json:"end"`
+	Status   string             `bson:"status" json:"status"`
+	Note     string             `bson:"note,omitempty" json:"note,omitempty"`
+	// Sequence counts the changes to the tour so that calendar clients
+	// replace the event they already have
+	Sequence    int                 `bson:"sequence" json:"sequence"`
+	CreatedAt   primitive.DateTime  `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   primitive.DateTime  `bson:"updatedAt" json:"updatedAt"`
+	CancelledAt *primitive.DateTime `bson:"cancelledAt,omitempty" json:"cancelledAt,omitempty"`
+}
@@ -1,15 +1,51 @@
 package models
 
 import (
+	"strings"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// User role constants
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+	// RoleAgent users manage their own listings and the requests for them
+	RoleAgent = "agent"
+)
+
+// PlaceholderAgentDomain is the reserved domain of the email addresses of
+// the placeholder agent accounts created by migrations, which can never
+// receive mail
+const PlaceholderAgentDomain = "agents.invalid"
+
 type Users struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	FirstName string             `bson:"firstName" json:"firstName" validate:"required"`
-	LastName  string             `bson:"lastName" json:"lastName" validate:"required"`
-	Email     string             `bson:"email" json:"email" validate:"required,email"`
-	Phone     string             `bson:"phone" json:"phone,omitempty"`
-	Password  string             `bson:"password" json:"password,omitempty" validate:"required,min=6"`
-	Role      string             `bson:"role" json:"role,omitempty" default:"user"` // Role can be "admin" or "user"
+	ID                    primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	FirstName             string             `bson:"firstName" json:"firstName" validate:"required"`
+	LastName              string             `bson:"lastName" json:"lastName" validate:"required"`
+	Email                 string             `bson:"email" json:"email" validate:"required,email"`
+	Phone                 string             `bson:"phone" json:"phone,omitempty"`
+	Password              string             `bson:"password" json:"password,omitempty" validate:"required,min=6"`
+	Role                  string             `bson:"role" json:"role,omitempty" default:"user"` // Role can be "admin", "agent" or "user"
+	EmailVerified         bool               `bson:"emailVerified" json:"emailVerified"`
+	Disabled              bool               `bson:"disabled" json:"disabled"`
+	PasswordResetRequired bool               `bson:"passwordResetRequired" json:"passwordResetRequired"`
+}
+
+// NormalizeEmail returns email trimmed and lowercased, the form emails are
+// stored and looked up in so that an address has one account whatever its
+// case
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// IsPlaceholderAgent reports whether u is a placeholder agent account that
+// no one has claimed yet
+func (u Users) IsPlaceholderAgent() bool {
+	return u.Role == RoleAgent && strings.HasSuffix(u.Email, "@"+PlaceholderAgentDomain)
+}
+
+// AgentContact returns the contact details shown on an agent's listings
+func (u Users) AgentContact() Agent {
+	return Agent{Name: strings.TrimSpace(u.FirstName + " " + u.LastName), Phone: u.Phone}
 }
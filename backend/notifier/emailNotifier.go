@@ -0,0 +1,43 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gatorswamp/mailer"
+)
+
+// EmailNotifier emails notifications with a link to every listing
+type EmailNotifier struct {
+	mailer  mailer.Mailer
+	baseURL string
+}
+
+// NewEmailNotifier creates a notifier sending through m and linking to
+// listings on the frontend at baseURL
+func NewEmailNotifier(m mailer.Mailer, baseURL string) *EmailNotifier {
+	return &EmailNotifier{mailer: m, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Notify emails the user the listings of n
+func (e *EmailNotifier) Notify(ctx context.Context, n Notification) error {
+	subject := fmt.Sprintf("A new listing matches %q", n.Search.Name)
+	if len(n.Listings) > 1 {
+		subject = fmt.Sprintf("%d new listings match %q", len(n.Listings), n.Search.Name)
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\nThese listings match your saved search %q:\n\n", n.User.FirstName, n.Search.Name)
+	for _, property := range n.Listings {
+		fmt.Fprintf(&body, "%s, %s\n%s\n%s/property/%s\n\n",
+			property.Name, property.Address, formatPrice(property), e.baseURL, property.ID.Hex())
+	}
+	body.WriteString("You can change or delete your saved searches at any time.\n")
+
+	return e.mailer.Send(ctx, mailer.Message{
+		To:      n.User.Email,
+		Subject: subject,
+		Body:    body.String(),
+	})
+}
@@ -0,0 +1,29 @@
+package notifier
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogNotifier is a development notifier that logs every notified listing
+type LogNotifier struct{}
+
+// NewLogNotifier creates a log notifier
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Notify logs one line per listing of n
+func (LogNotifier) Notify(ctx context.Context, n Notification) error {
+	for _, property := range n.Listings {
+		slog.InfoContext(ctx, "listing alert",
+			"userId", n.User.ID.Hex(),
+			"savedSearchId", n.Search.ID.Hex(),
+			"savedSearch", n.Search.Name,
+			"propertyId", property.ID.Hex(),
+			"property", property.Name,
+			"price", formatPrice(property),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Package notifier tells users about new listings matching their saved
+// searches
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"gatorswamp/config"
+	"gatorswamp/mailer"
+	"gatorswamp/models"
+)
+
+// Notification lists the listings that matched one of a user's saved searches
+type Notification struct {
+	User     models.Users
+	Search   models.SavedSearch
+	Listings []models.Housing
+}
+
+// Notifier delivers saved search notifications
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+var (
+	defaultNotifier Notifier
+	defaultOnce     sync.Once
+)
+
+// Default returns the process-wide notifier: email when MAIL_DRIVER is smtp
+// and the log otherwise
+func Default() Notifier {
+	defaultOnce.Do(func() {
+		switch config.MailDriver() {
+		case "smtp":
+			defaultNotifier = NewEmailNotifier(mailer.Default(), config.AppBaseURL())
+			log.Printf("Sending listing alerts by email")
+		default:
+			defaultNotifier = NewLogNotifier()
+			log.Printf("Logging listing alerts")
+		}
+	})
+	return defaultNotifier
+}
+
+// formatPrice renders a price stored in minor units, such as "USD 1250.00"
+func formatPrice(property models.Housing) string {
+	return fmt.Sprintf("%s %.2f", property.Currency, float64(property.PriceCents)/100)
+}
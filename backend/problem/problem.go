@@ -0,0 +1,88 @@
+// Package problem writes error responses as RFC 7807 problem details with a
+// machine-readable code clients can branch on
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatorswamp/services"
+)
+
+// ContentType is the media type of problem responses
+const ContentType = "application/problem+json"
+
+// Details is the body of a problem response
+type Details struct {
+	Type     string                `json:"type"`
+	Title    string                `json:"title"`
+	Status   int                   `json:"status"`
+	Code     string                `json:"code"`
+	Detail   string                `json:"detail,omitempty"`
+	Instance string                `json:"instance,omitempty"`
+	Errors   []services.FieldError `json:"errors,omitempty"`
+}
+
+// kinds maps service error kinds to a status code and the code used when
+// the error carries none of its own
+var kinds = []struct {
+	kind   error
+	status int
+	code   string
+}{
+	{services.ErrNotFound, http.StatusNotFound, "not_found"},
+	{services.ErrInvalidID, http.StatusBadRequest, "invalid_id"},
+	{services.ErrValidation, http.StatusBadRequest, "validation_failed"},
+	{services.ErrConflict, http.StatusConflict, "conflict"},
+	{services.ErrForbidden, http.StatusForbidden, "forbidden"},
+	{services.ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+	{services.ErrTooLarge, http.StatusRequestEntityTooLarge, "too_large"},
+	{services.ErrUnsupported, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+	{services.ErrTooManyRequests, http.StatusTooManyRequests, "too_many_requests"},
+}
+
+// Write writes a problem response with the given status, code and detail
+func Write(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	write(w, r, Details{Status: status, Code: code, Detail: detail})
+}
+
+// Error writes the problem response for err. Errors of an unknown kind are
+// logged and reported as internal errors without their message
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	for _, k := range kinds {
+		if !errors.Is(err, k.kind) {
+			continue
+		}
+		details := Details{Status: k.status, Code: k.code, Detail: err.Error()}
+		var serviceErr *services.Error
+		if errors.As(err, &serviceErr) {
+			details.Code = serviceErr.Code
+			details.Errors = serviceErr.Fields
+			if serviceErr.RetryAfter > 0 {
+				// Whole seconds, rounded up so that clients do not retry early
+				seconds := (serviceErr.RetryAfter + time.Second - 1) / time.Second
+				w.Header().Set("Retry-After", strconv.FormatInt(int64(seconds), 10))
+			}
+		}
+		write(w, r, details)
+		return
+	}
+
+	log.Printf("Internal error on %s %s: %v", r.Method, r.URL.Path, err)
+	write(w, r, Details{Status: http.StatusInternalServerError, Code: "internal_error", Detail: "internal server error"})
+}
+
+// write fills in the standard members and writes details
+func write(w http.ResponseWriter, r *http.Request, details Details) {
+	details.Type = "about:blank"
+	details.Title = http.StatusText(details.Status)
+	details.Instance = r.URL.Path
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(details.Status)
+	json.NewEncoder(w).Encode(details)
+}
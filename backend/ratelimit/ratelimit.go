@@ -0,0 +1,90 @@
+// Package ratelimit implements token bucket rate limits. Buckets live in a
+// Store so that several server nodes can share them
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket holding up to Burst tokens, refilled with one
+// token every Interval. Each request takes a token
+type Limit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+// Store keeps token buckets by key
+type Store interface {
+	// Take takes a token from the bucket at key, which starts full. It
+	// returns zero when a token was taken, or how long until the next one
+	// when the bucket is empty
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (time.Duration, error)
+}
+
+// sweepInterval is how often MemoryStore forgets the buckets that are full
+const sweepInterval = time.Minute
+
+// MemoryStore keeps buckets in process memory, which suits a single node
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// bucket is the state of one token bucket
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket is full again, after which it can be dropped
+	full time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Take takes a token from the bucket at key
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	burst := float64(limit.Burst)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, updated: now}
+		s.buckets[key] = b
+	} else if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+float64(elapsed)/float64(limit.Interval))
+		b.updated = now
+	}
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) * float64(limit.Interval)), nil
+	}
+
+	b.tokens--
+	b.full = now.Add(time.Duration((burst - b.tokens) * float64(limit.Interval)))
+	return 0, nil
+}
+
+// sweep drops the buckets that have refilled, which behave like missing ones
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
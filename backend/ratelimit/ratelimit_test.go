@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	limit := Limit{Burst: 3, Interval: 10 * time.Second}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Each step takes a token from the "a" bucket at start plus after
+	type step struct {
+		after time.Duration
+		wait  time.Duration
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name:  "burst then refused",
+			steps: []step{{0, 0}, {0, 0}, {0, 0}, {0, 10 * time.Second}},
+		},
+		{
+			name:  "wait shrinks as the bucket refills",
+			steps: []step{{0, 0}, {0, 0}, {0, 0}, {4 * time.Second, 6 * time.Second}},
+		},
+		{
+			name:  "one token per interval",
+			steps: []step{{0, 0}, {0, 0}, {0, 0}, {10 * time.Second, 0}, {10 * time.Second, 10 * time.Second}},
+		},
+		{
+			name:  "refill stops at the burst",
+			steps: []step{{0, 0}, {time.Hour, 0}, {time.Hour, 0}, {time.Hour, 0}, {time.Hour, 10 * time.Second}},
+		},
+		{
+			name:  "refused takes do not use tokens",
+			steps: []step{{0, 0}, {0, 0}, {0, 0}, {0, 10 * time.Second}, {0, 10 * time.Second}, {10 * time.Second, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			for i, s := range tt.steps {
+				wait, err := store.Take(context.Background(), "a", limit, start.Add(s.after))
+				if err != nil {
+					t.Fatalf("step %d: Take() error = %v", i, err)
+				}
+				if wait != s.wait {
+					t.Fatalf("step %d: Take() = %v, want %v", i, wait, s.wait)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreKeysAreSeparate(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Burst: 1, Interval: time.Minute}
+	now := time.Now()
+
+	if wait, _ := store.Take(context.Background(), "a", limit, now); wait != 0 {
+		t.Fatalf("first take of a waited %v", wait)
+	}
+	if wait, _ := store.Take(context.Background(), "a", limit, now); wait == 0 {
+		t.Fatal("second take of a was not refused")
+	}
+	if wait, _ := store.Take(context.Background(), "b", limit, now); wait != 0 {
+		t.Errorf("first take of b waited %v", wait)
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Burst: 2, Interval: time.Second}
+	now := time.Now()
+
+	store.Take(context.Background(), "idle", limit, now)
+
+	// The idle bucket refilled long before the next sweep
+	store.Take(context.Background(), "other", limit, now.Add(2*sweepInterval))
+	if _, ok := store.buckets["idle"]; ok {
+		t.Error("full bucket was not swept")
+	}
+	if _, ok := store.buckets["other"]; !ok {
+		t.Error("bucket in use was swept")
+	}
+}
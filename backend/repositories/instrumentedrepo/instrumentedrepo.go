@@ -0,0 +1,665 @@
+// Package instrumentedrepo wraps service repositories to record the latency
+// and failures of every operation in the metrics registry
+package instrumentedrepo
+
+import (
+	"context"
+	"time"
+
+	"gatorswamp/metrics"
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Wrap instruments every repository in repos, labelling the metrics with store
+func Wrap(store string, repos services.Repositories) services.Repositories {
+	return services.Repositories{
+		Housing:       &housingRepository{next: repos.Housing, observer: observer{store, "housing"}},
+		Users:         &userRepository{next: repos.Users, observer: observer{store, "users"}},
+		Requests:      &requestRepository{next: repos.Requests, observer: observer{store, "requests"}},
+		Sessions:      &sessionRepository{next: repos.Sessions, observer: observer{store, "sessions"}},
+		AccountTokens: &accountTokenRepository{next: repos.AccountTokens, observer: observer{store, "accountTokens"}},
+		LoginAttempts: &loginAttemptRepository{next: repos.LoginAttempts, observer: observer{store, "loginAttempts"}},
+		Favorites:     &favoriteRepository{next: repos.Favorites, observer: observer{store, "favorites"}},
+		SavedSearches: &savedSearchRepository{next: repos.SavedSearches, observer: observer{store, "savedSearches"}},
+		Alerts:        &listingAlertRepository{next: repos.Alerts, observer: observer{store, "listingAlerts"}},
+		PriceHistory:  &priceHistoryRepository{next: repos.PriceHistory, observer: observer{store, "priceHistory"}},
+		Availability:  &availabilityRepository{next: repos.Availability, observer: observer{store, "tourAvailability"}},
+		Tours:         &tourRepository{next: repos.Tours, observer: observer{store, "tours"}},
+	}
+}
+
+// observer records operations of one repository
+type observer struct {
+	store      string
+	repository string
+}
+
+// observe records an operation that started at start. Lookups that find
+// nothing and writes refused as conflicts are expected and not counted as
+// errors
+func (o observer) observe(operation string, start time.Time, err error) {
+	failed := err != nil && err != services.ErrNotFound && err != services.ErrConflict
+	metrics.ObserveDB(o.store, o.repository, operation, start, failed)
+}
+
+type housingRepository struct {
+	next services.HousingRepository
+	observer
+}
+
+func (r *housingRepository) EnsureIndexes(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.EnsureIndexes(ctx)
+	r.observe("EnsureIndexes", start, err)
+	return err
+}
+
+func (r *housingRepository) Insert(ctx context.Context, property models.Housing) error {
+	start := time.Now()
+	err := r.next.Insert(ctx, property)
+	r.observe("Insert", start, err)
+	return err
+}
+
+func (r *housingRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Housing, error) {
+	start := time.Now()
+	property, err := r.next.FindByID(ctx, id)
+	r.observe("FindByID", start, err)
+	return property, err
+}
+
+func (r *housingRepository) Replace(ctx context.Context, property models.Housing) error {
+	start := time.Now()
+	err := r.next.Replace(ctx, property)
+	r.observe("Replace", start, err)
+	return err
+}
+
+func (r *housingRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	start := time.Now()
+	err := r.next.Delete(ctx, id)
+	r.observe("Delete", start, err)
+	return err
+}
+
+func (r *housingRepository) Search(ctx context.Context, filter services.HousingFilter) ([]models.Housing, error) {
+	start := time.Now()
+	properties, err := r.next.Search(ctx, filter)
+	r.observe("Search", start, err)
+	return properties, err
+}
+
+func (r *housingRepository) List(ctx context.Context, filter services.HousingFilter, page services.PageRequest, after *services.PageCursor, limit int) ([]models.Housing, error) {
+	start := time.Now()
+	properties, err := r.next.List(ctx, filter, page, after, limit)
+	r.observe("List", start, err)
+	return properties, err
+}
+
+func (r *housingRepository) Count(ctx context.Context, filter services.HousingFilter) (int64, error) {
+	start := time.Now()
+	count, err := r.next.Count(ctx, filter)
+	r.observe("Count", start, err)
+	return count, err
+}
+
+func (r *housingRepository) Facets(ctx context.Context) (*services.HousingFacets, error) {
+	start := time.Now()
+	facets, err := r.next.Facets(ctx)
+	r.observe("Facets", start, err)
+	return facets, err
+}
+
+func (r *housingRepository) Near(ctx context.Context, origin services.LatLng, maxDistance float64, box *services.BoundingBox, filter services.HousingFilter, limit int) ([]services.HousingWithDistance, error) {
+	start := time.Now()
+	properties, err := r.next.Near(ctx, origin, maxDistance, box, filter, limit)
+	r.observe("Near", start, err)
+	return properties, err
+}
+
+func (r *housingRepository) TextSearch(ctx context.Context, text services.TextQuery, filter services.HousingFilter, limit int) ([]services.HousingMatch, error) {
+	start := time.Now()
+	matches, err := r.next.TextSearch(ctx, text, filter, limit)
+	r.observe("TextSearch", start, err)
+	return matches, err
+}
+
+type userRepository struct {
+	next services.UserRepository
+	observer
+}
+
+func (r *userRepository) EnsureIndexes(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.EnsureIndexes(ctx)
+	r.observe("EnsureIndexes", start, err)
+	return err
+}
+
+func (r *userRepository) Insert(ctx context.Context, user models.Users) error {
+	start := time.Now()
+	err := r.next.Insert(ctx, user)
+	r.observe("Insert", start, err)
+	return err
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Users, error) {
+	start := time.Now()
+	user, err := r.next.FindByID(ctx, id)
+	r.observe("FindByID", start, err)
+	return user, err
+}
+
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.Users, error) {
+	start := time.Now()
+	user, err := r.next.FindByEmail(ctx, email)
+	r.observe("FindByEmail", start, err)
+	return user, err
+}
+
+func (r *userRepository) List(ctx context.Context, query services.UserQuery) ([]models.Users, int64, error) {
+	start := time.Now()
+	users, total, err := r.next.List(ctx, query)
+	r.observe("List", start, err)
+	return users, total, err
+}
+
+func (r *userRepository) Update(ctx context.Context, id primitive.ObjectID, patch services.UserPatch) (*models.Users, error) {
+	start := time.Now()
+	user, err := r.next.Update(ctx, id, patch)
+	r.observe("Update", start, err)
+	return user, err
+}
+
+type requestRepository struct {
+	next services.RequestRepository
+	observer
+}
+
+func (r *requestRepository) EnsureIndexes(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.EnsureIndexes(ctx)
+	r.observe("EnsureIndexes", start, err)
+	return err
+}
+
+func (r *requestRepository) Insert(ctx context.Context, request models.PropertyRequest) error {
+	start := time.Now()
+	err := r.next.Insert(ctx, request)
+	r.observe("Insert", start, err)
+	return err
+}
+
+func (r *requestRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.PropertyRequest, error) {
+	start := time.Now()
+	request, err := r.next.FindByID(ctx, id)
+	r.observe("FindByID", start, err)
+	return request, err
+}
+
+func (r *requestRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.PropertyRequest, error) {
+	start := time.Now()
+	requests, err := r.next.FindByUser(ctx, userID)
+	r.observe("FindByUser", start, err)
+	return requests, err
+}
+
+func (r *requestRepository) FindAll(ctx context.Context) ([]models.PropertyRequest, error) {
+	start := time.Now()
+	requests, err := r.next.FindAll(ctx)
+	r.observe("FindAll", start, err)
+	return requests, err
+}
+
+func (r *requestRepository) FindByProperties(ctx context.Context, propertyIDs []primitive.ObjectID) ([]models.PropertyRequest, error) {
+	start := time.Now()
+	requests, err := r.next.FindByProperties(ctx, propertyIDs)
+	r.observe("FindByProperties", start, err)
+	return requests, err
+}
+
+func (r *requestRepository) Transition(ctx context.Context, id primitive.ObjectID, change models.RequestStatusChange, rejectionReason string) error {
+	start := time.Now()
+	err := r.next.Transition(ctx, id, change, rejectionReason)
+	r.observe("Transition", start, err)
+	return err
+}
+
+func (r *requestRepository) UpdateMessage(ctx context.Context, id primitive.ObjectID, status string, message string, at time.Time) error {
+	start := time.Now()
+	err := r.next.UpdateMessage(ctx, id, status, message, at)
+	r.observe("UpdateMessage", start, err)
+	return err
+}
+
+func (r *requestRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	start := time.Now()
+	err := r.next.Delete(ctx, id)
+	r.observe("Delete", start, err)
+	return err
+}
+
+type sessionRepository struct {
+	next services.SessionRepository
+	observer
+}
+
+func (r *sessionRepository) EnsureIndexes(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.EnsureIndexes(ctx)
+	r.observe("EnsureIndexes", start, err)
+	return err
+}
+
+func (r *sessionRepository) Insert(ctx context.Context, session models.Session) error {
+	start := time.Now()
+	err := r.next.Insert(ctx, session)
+	r.observe("Insert", start, err)
+	return err
+}
+
+func (r *sessionRepository) Rotate(ctx context.Context, oldHash string, newHash string, now time.Time, expiresAt time.Time) (*models.Session, error) {
+	start := time.Now()
+	session, err := r.next.Rotate(ctx, oldHash, newHash, now, expiresAt)
+	r.observe("Rotate", start, err)
+	return session, err
+}
+
+func (r *sessionRepository) IsActive(ctx context.Context, id primitive.ObjectID, now time.Time) (bool, error) {
+	start := time.Now()
+	active, err := r.next.IsActive(ctx, id, now)
+	r.observe("IsActive", start, err)
+	return active, err
+}
+
+func (r *sessionRepository) Revoke(ctx context.Context, match services.SessionMatch, reason string, now time.Time) (int64, error) {
+	start := time.Now()
+	revoked, err := r.next.Revoke(ctx, match, reason, now)
+	r.observe("Revoke", start, err)
+	return revoked, err
+}
+
+type accountTokenRepository struct {
+	next services.AccountTokenRepository
+	observer
+}
+
+func (r *accountTokenRepository) EnsureIndexes(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.EnsureIndexes(ctx)
+	r.observe("EnsureIndexes", start, err)
+	return err
+}
+
+func (r *accountTokenRepository) Insert(ctx context.Context, token models.AccountToken) error {
+	start := time.Now()
+	err := r.next.Insert(ctx, token)
+	r.observe("Insert", start, err)
+	return err
+}
+
+func (r *accountTokenRepository) InvalidateUnused(ctx context.Context, userID primitive.ObjectID, purpose string, now time.Time) error {
+	start := time.Now()
+	err := r.next.InvalidateUnused(ctx, userID, purpose, now)
+	r.observe("InvalidateUnused", start, err)
+	return err
+}
+
+func (r *accountTokenRepository) Consume(ctx context.Context, hash string, purpose string, now time.Time) (*models.AccountToken, error) {
+	start := time.Now()
+	token, err := r.next.Consume(ctx, hash, purpose, now)
+	r.observe("Consume", start, err)
+	return token, err
+}
+
+type loginAttemptRepository struct {
+	next services.LoginAttemptRepository
+	observer
+}
+
+func (r *loginAttemptRepository) EnsureIndexes(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.EnsureIndexes(ctx)
+	r.observe("EnsureIndexes", start, err)
+	return err
+}
+
+func (r *loginAttemptRepository) Find(ctx context.Context, email string, now time.Time) (*models.LoginAttempts, error) {
+	start := time.Now()
+	attempts, err := r.next.Find(ctx, email, now)
+	r.observe("Find", start, err)
+	return attempts, err
+}
+
+func (r *loginAttemptRepository) RecordFailure(ctx context.Context, email string, at time.Time, window time.Duration) (*models.LoginAttempts, error) {
+	start := time.Now()
+	attempts, err := r.next.RecordFailure(ctx, email, at, window)
+	r.observe("RecordFailure", start, err)
+	return attempts, err
+}
+
+func (r *loginAttemptRepository) Lock(ctx context.Context, email string, until time.Time) error {
+	start := time.Now()
+	err := r.next.Lock(ctx, email, until)
+	r.observe("Lock", start, err)
+	return err
+}
+
+func (r *loginAttemptRepository) Clear(ctx context.Context, email string) error {
+	start := time.Now()
+	err := r.next.Clear(ctx, email)
+	r.observe("Clear", start, err)
+	return err
+}
+
+type favoriteRepository struct {
+	next services.FavoriteRepository
+	observer
+}
+
+func (r *favoriteRepository) EnsureIndexes(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.EnsureIndexes(ctx)
+	r.observe("EnsureIndexes", start, err)
+	return err
+}
+
+func (r *favoriteRepository) Add(ctx context.Context, favorite models.Favorite) (*models.Favorite, error) {
+	start := time.Now()
+	stored, err := r.next.Add(ctx, favorite)
+	r.observe("Add", start, err)
+	return stored, err
+}
+
+func (r *favoriteRepository) Remove(ctx context.Context, userID primitive.ObjectID, propertyID primitive.ObjectID) error {
+	start := time.Now()
+	err := r.next.Remove(ctx, userID, propertyID)
+	r.observe("Remove", start, err)
+	return err
+}
+
+func (r *favoriteRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Favorite, error) {
+	start := time.Now()
+	favorites, err := r.next.FindByUser(ctx, userID)
+	r.observe("FindByUser", start, err)
+	return favorites, err
+}
+
+func (r *favoriteRepository) CountByProperty(ctx context.Context, propertyID primitive.ObjectID) (int64, error) {
+	start := time.Now()
+	count, err := r.next.CountByProperty(ctx, propertyID)
+	r.observe("CountByProperty", start, err)
+	return count, err
+}
+
+func (r *favoriteRepository) DeleteByProperty(ctx context.Context, propertyID primitive.ObjectID) (int64, error) {
+	start := time.Now()
+	deleted, err := r.next.DeleteByProperty(ctx, propertyID)
+	r.observe("DeleteByProperty", start, err)
+	return deleted, err
+}
+
+type savedSearchRepository struct {
+	next services.SavedSearchRepository
+	observer
+}
+
+func (r *savedSearchRepository) EnsureIndexes(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.EnsureIndexes(ctx)
+	r.observe("EnsureIndexes", start, err)
+	return err
+}
+
+func (r *savedSearchRepository) Insert(ctx context.Context, search models.SavedSearch) error {
+	start := time.Now()
+	err := r.next.Insert(ctx, search)
+	r.observe("Insert", start, err)
+	return err
+}
+
+func (r *savedSearchRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.SavedSearch, error) {
+	start := time.Now()
+	search, err := r.next.FindByID(ctx, id)
+	r.observe("FindByID", start, err)
+	return search, err
+}
+
+func (r *savedSearchRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.SavedSearch, error) {
+	start := time.Now()
+	searches, err := r.next.FindByUser(ctx, userID)
+	r.observe("FindByUser", start, err)
+	return searches, err
+}
+
+func (r *savedSearchRepository) FindAll(ctx context.Context) ([]models.SavedSearch, error) {
+	start := time.Now()
+	searches, err := r.next.FindAll(ctx)
+	r.observe("FindAll", start, err)
+	return searches, err
+}
+
+func (r *savedSearchRepository) Replace(ctx context.Context, search models.SavedSearch) error {
+	start := time.Now()
+	err := r.next.Replace(ctx, search)
+	r.observe("Replace", start, err)
+	return err
+}
+
+func (r *savedSearchRepository) SetLastNotified(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	start := time.Now()
+	err := r.next.SetLastNotified(ctx, id, at)
+	r.observe("SetLastNotified", start, err)
+	return err
+}
+
+func (r *savedSearchRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	start := time.Now()
+	err := r.next.Delete(ctx, id)
+	r.observe("Delete", start, err)
+	return err
+}
+
+type listingAlertRepository struct {
+	next services.ListingAlertRepository
+	observer
+}
+
+func (r *listingAlertRepository) EnsureIndexes(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.EnsureIndexes(ctx)
+	r.observe("EnsureIndexes", start, err)
+	return err
+}
+
+func (r *listingAlertRepository) Insert(ctx context.Context, alert models.ListingAlert) (bool, error) {
+	start := time.Now()
+	inserted, err := r.next.Insert(ctx, alert)
+	r.observe("Insert", start, err)
+	return inserted, err
+}
+
+func (r *listingAlertRepository) FindByUser(ctx context.Context, userID primitive.ObjectID, limit int) ([]models.ListingAlert, error) {
+	start := time.Now()
+	alerts, err := r.next.FindByUser(ctx, userID, limit)
+	r.observe("FindByUser", start, err)
+	return alerts, err
+}
+
+func (r *listingAlertRepository) FindUndelivered(ctx context.Context, savedSearchID primitive.ObjectID) ([]models.ListingAlert, error) {
+	start := time.Now()
+	alerts, err := r.next.FindUndelivered(ctx, savedSearchID)
+	r.observe("FindUndelivered", start, err)
+	return alerts, err
+}
+
+func (r *listingAlertRepository) MarkDelivered(ctx context.Context, ids []primitive.ObjectID, at time.Time) error {
+	start := time.Now()
+	err := r.next.MarkDelivered(ctx, ids, at)
+	r.observe("MarkDelivered", start, err)
+	return err
+}
+
+func (r *listingAlertRepository) DeleteBySearch(ctx context.Context, savedSearchID primitive.ObjectID) error {
+	start := time.Now()
+	err := r.next.DeleteBySearch(ctx, savedSearchID)
+	r.observe("DeleteBySearch", start, err)
+	return err
+}
+
+type priceHistoryRepository struct {
+	next services.PriceHistoryRepository
+	observer
+}
+
+func (r *priceHistoryRepository) EnsureIndexes(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.EnsureIndexes(ctx)
+	r.observe("EnsureIndexes", start, err)
+	return err
+}
+
+func (r *priceHistoryRepository) Insert(ctx context.Context, change models.PriceChange) error {
+	start := time.Now()
+	err := r.next.Insert(ctx, change)
+	r.observe("Insert", start, err)
+	return err
+}
+
+func (r *priceHistoryRepository) FindByProperty(ctx context.Context, propertyID primitive.ObjectID) ([]models.PriceChange, error) {
+	start := time.Now()
+	changes, err := r.next.FindByProperty(ctx, propertyID)
+	r.observe("FindByProperty", start, err)
+	return changes, err
+}
+
+func (r *priceHistoryRepository) FindSince(ctx context.Context, propertyIDs []primitive.ObjectID, since time.Time) ([]models.PriceChange, error) {
+	start := time.Now()
+	changes, err := r.next.FindSince(ctx, propertyIDs, since)
+	r.observe("FindSince", start, err)
+	return changes, err
+}
+
+func (r *priceHistoryRepository) DeleteByProperty(ctx context.Context, propertyID primitive.ObjectID) error {
+	start := time.Now()
+	err := r.next.DeleteByProperty(ctx, propertyID)
+	r.observe("DeleteByProperty", start, err)
+	return err
+}
+
+type availabilityRepository struct {
+	next services.AvailabilityRepository
+	observer
+}
+
+func (r *availabilityRepository) EnsureIndexes(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.EnsureIndexes(ctx)
+	r.observe("EnsureIndexes", start, err)
+	return err
+}
+
+func (r *availabilityRepository) Insert(ctx context.Context, window models.AvailabilityWindow) error {
+	start := time.Now()
+	err := r.next.Insert(ctx, window)
+	r.observe("Insert", start, err)
+	return err
+}
+
+func (r *availabilityRepository) FindByProperty(ctx context.Context, propertyID primitive.ObjectID, from time.Time, to time.Time) ([]models.AvailabilityWindow, error) {
+	start := time.Now()
+	windows, err := r.next.FindByProperty(ctx, propertyID, from, to)
+	r.observe("FindByProperty", start, err)
+	return windows, err
+}
+
+func (r *availabilityRepository) Delete(ctx context.Context, propertyID primitive.ObjectID, id primitive.ObjectID) error {
+	start := time.Now()
+	err := r.next.Delete(ctx, propertyID, id)
+	r.observe("Delete", start, err)
+	return err
+}
+
+func (r *availabilityRepository) DeleteByProperty(ctx context.Context, propertyID primitive.ObjectID) error {
+	start := time.Now()
+	err := r.next.DeleteByProperty(ctx, propertyID)
+	r.observe("DeleteByProperty", start, err)
+	return err
+}
+
+type tourRepository struct {
+	next services.TourRepository
+	observer
+}
+
+func (r *tourRepository) EnsureIndexes(ctx context.Context) error {
+	start := time.Now()
+	err := r.next.EnsureIndexes(ctx)
+	r.observe("EnsureIndexes", start, err)
+	return err
+}
+
+func (r *tourRepository) Insert(ctx context.Context, tour models.Tour) error {
+	start := time.Now()
+	err := r.next.Insert(ctx, tour)
+	r.observe("Insert", start, err)
+	return err
+}
+
+func (r *tourRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Tour, error) {
+	start := time.Now()
+	tour, err := r.next.FindByID(ctx, id)
+	r.observe("FindByID", start, err)
+	return tour, err
+}
+
+func (r *tourRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Tour, error) {
+	start := time.Now()
+	tours, err := r.next.FindByUser(ctx, userID)
+	r.observe("FindByUser", start, err)
+	return tours, err
+}
+
+func (r *tourRepository) FindAll(ctx context.Context) ([]models.Tour, error) {
+	start := time.Now()
+	tours, err := r.next.FindAll(ctx)
+	r.observe("FindAll", start, err)
+	return tours, err
+}
+
+func (r *tourRepository) FindByAgent(ctx context.Context, agentKey string, since time.Time) ([]models.Tour, error) {
+	start := time.Now()
+	tours, err := r.next.FindByAgent(ctx, agentKey, since)
+	r.observe("FindByAgent", start, err)
+	return tours, err
+}
+
+func (r *tourRepository) FindBooked(ctx context.Context, agentKey string, from time.Time, to time.Time) ([]models.Tour, error) {
+	start := time.Now()
+	tours, err := r.next.FindBooked(ctx, agentKey, from, to)
+	r.observe("FindBooked", start, err)
+	return tours, err
+}
+
+func (r *tourRepository) Reschedule(ctx context.Context, id primitive.ObjectID, startAt time.Time, end time.Time, at time.Time) (*models.Tour, error) {
+	start := time.Now()
+	tour, err := r.next.Reschedule(ctx, id, startAt, end, at)
+	r.observe("Reschedule", start, err)
+	return tour, err
+}
+
+func (r *tourRepository) Cancel(ctx context.Context, id primitive.ObjectID, at time.Time) (*models.Tour, error) {
+	start := time.Now()
+	tour, err := r.next.Cancel(ctx, id, at)
+	r.observe("Cancel", start, err)
+	return tour, err
+}
+
+func (r *tourRepository) CancelByProperty(ctx context.Context, propertyID primitive.ObjectID, at time.Time) error {
+	start := time.Now()
+	err := r.next.CancelByProperty(ctx, propertyID, at)
+	r.observe("CancelByProperty", start, err)
+	return err
+}
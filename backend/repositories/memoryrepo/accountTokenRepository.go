@@ -0,0 +1,86 @@
+package memoryrepo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AccountTokenRepository keeps account tokens in memory
+type AccountTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[primitive.ObjectID]models.AccountToken
+}
+
+// NewAccountTokenRepository creates an empty account token repository
+func NewAccountTokenRepository() *AccountTokenRepository {
+	return &AccountTokenRepository{
+		tokens: map[primitive.ObjectID]models.AccountToken{},
+	}
+}
+
+// EnsureIndexes is a no-op; tokens are scanned in full
+func (r *AccountTokenRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Insert adds a new token
+func (r *AccountTokenRepository) Insert(ctx context.Context, token models.AccountToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tokens[token.ID]; ok {
+		return errDuplicateID
+	}
+	r.tokens[token.ID] = copyToken(token)
+	return nil
+}
+
+// InvalidateUnused marks every unused token of userID for purpose as used
+func (r *AccountTokenRepository) InvalidateUnused(ctx context.Context, userID primitive.ObjectID, purpose string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	usedAt := primitive.NewDateTimeFromTime(now)
+	for id, token := range r.tokens {
+		if token.UserID == userID && token.Purpose == purpose && token.UsedAt == nil {
+			token.UsedAt = &usedAt
+			r.tokens[id] = token
+		}
+	}
+	return nil
+}
+
+// Consume marks a valid token as used and returns it
+func (r *AccountTokenRepository) Consume(ctx context.Context, hash string, purpose string, now time.Time) (*models.AccountToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.tokens {
+		if token.TokenHash != hash || token.Purpose != purpose || token.UsedAt != nil || !token.ExpiresAt.Time().After(now) {
+			continue
+		}
+
+		usedAt := primitive.NewDateTimeFromTime(now)
+		token.UsedAt = &usedAt
+		r.tokens[id] = token
+
+		token = copyToken(token)
+		return &token, nil
+	}
+
+	return nil, services.ErrNotFound
+}
+
+// copyToken returns a copy of token that shares no memory with it
+func copyToken(token models.AccountToken) models.AccountToken {
+	if token.UsedAt != nil {
+		usedAt := *token.UsedAt
+		token.UsedAt = &usedAt
+	}
+	return token
+}
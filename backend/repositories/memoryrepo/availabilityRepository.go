@@ -0,0 +1,96 @@
+package memoryrepo
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AvailabilityRepository keeps availability windows in memory. The lock
+// makes checking for overlapping windows and inserting a single step, like
+// the unique index does in MongoDB
+type AvailabilityRepository struct {
+	mu      sync.RWMutex
+	windows map[primitive.ObjectID]models.AvailabilityWindow
+}
+
+// NewAvailabilityRepository creates an empty availability repository
+func NewAvailabilityRepository() *AvailabilityRepository {
+	return &AvailabilityRepository{
+		windows: map[primitive.ObjectID]models.AvailabilityWindow{},
+	}
+}
+
+// EnsureIndexes is a no-op; windows are scanned in full
+func (r *AvailabilityRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Insert adds a new availability window, or returns ErrConflict when it
+// overlaps another window of the same agent
+func (r *AvailabilityRepository) Insert(ctx context.Context, window models.AvailabilityWindow) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.windows[window.ID]; ok {
+		return errDuplicateID
+	}
+	for _, other := range r.windows {
+		if other.AgentKey == window.AgentKey && other.Start < window.End && other.End > window.Start {
+			return services.ErrConflict
+		}
+	}
+	r.windows[window.ID] = window
+	return nil
+}
+
+// FindByProperty returns the windows of a listing that overlap the period
+// from from to to, in start order
+func (r *AvailabilityRepository) FindByProperty(ctx context.Context, propertyID primitive.ObjectID, from time.Time, to time.Time) ([]models.AvailabilityWindow, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	start, end := primitive.NewDateTimeFromTime(from), primitive.NewDateTimeFromTime(to)
+	windows := []models.AvailabilityWindow{}
+	for _, window := range r.windows {
+		if window.PropertyID == propertyID && window.Start < end && window.End > start {
+			windows = append(windows, window)
+		}
+	}
+
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].Start < windows[j].Start
+	})
+	return windows, nil
+}
+
+// Delete removes a window of a listing
+func (r *AvailabilityRepository) Delete(ctx context.Context, propertyID primitive.ObjectID, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	window, ok := r.windows[id]
+	if !ok || window.PropertyID != propertyID {
+		return services.ErrNotFound
+	}
+	delete(r.windows, id)
+	return nil
+}
+
+// DeleteByProperty removes every window of a listing
+func (r *AvailabilityRepository) DeleteByProperty(ctx context.Context, propertyID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, window := range r.windows {
+		if window.PropertyID == propertyID {
+			delete(r.windows, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package memoryrepo
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// favoriteKey identifies a user's favorite of a listing
+type favoriteKey struct {
+	userID     primitive.ObjectID
+	propertyID primitive.ObjectID
+}
+
+// FavoriteRepository keeps saved listings in memory
+type FavoriteRepository struct {
+	mu        sync.RWMutex
+	favorites map[favoriteKey]models.Favorite
+}
+
+// NewFavoriteRepository creates an empty favorite repository
+func NewFavoriteRepository() *FavoriteRepository {
+	return &FavoriteRepository{
+		favorites: map[favoriteKey]models.Favorite{},
+	}
+}
+
+// EnsureIndexes is a no-op; favorites are keyed by user and listing
+func (r *FavoriteRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Add saves favorite unless its user already saved the listing, and returns
+// the stored favorite either way
+func (r *FavoriteRepository) Add(ctx context.Context, favorite models.Favorite) (*models.Favorite, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := favoriteKey{favorite.UserID, favorite.PropertyID}
+	if stored, ok := r.favorites[key]; ok {
+		return &stored, nil
+	}
+	r.favorites[key] = favorite
+	return &favorite, nil
+}
+
+// Remove deletes a user's favorite of a listing
+func (r *FavoriteRepository) Remove(ctx context.Context, userID primitive.ObjectID, propertyID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := favoriteKey{userID, propertyID}
+	if _, ok := r.favorites[key]; !ok {
+		return services.ErrNotFound
+	}
+	delete(r.favorites, key)
+	return nil
+}
+
+// FindByUser returns a user's favorites, newest first
+func (r *FavoriteRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Favorite, error) {
+	r.mu.RLock()
+	var favorites []models.Favorite
+	for key, favorite := range r.favorites {
+		if key.userID == userID {
+			favorites = append(favorites, favorite)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(favorites, func(i, j int) bool {
+		if favorites[i].CreatedAt != favorites[j].CreatedAt {
+			return favorites[i].CreatedAt > favorites[j].CreatedAt
+		}
+		return compareIDs(favorites[i].ID, favorites[j].ID) > 0
+	})
+	return favorites, nil
+}
+
+// CountByProperty returns how many users saved a listing
+func (r *FavoriteRepository) CountByProperty(ctx context.Context, propertyID primitive.ObjectID) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for key := range r.favorites {
+		if key.propertyID == propertyID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteByProperty removes every favorite of a listing
+func (r *FavoriteRepository) DeleteByProperty(ctx context.Context, propertyID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for key := range r.favorites {
+		if key.propertyID == propertyID {
+			delete(r.favorites, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
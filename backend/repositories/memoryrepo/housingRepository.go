@@ -0,0 +1,248 @@
+package memoryrepo
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// earthRadiusMeters matches the radius MongoDB uses for spherical queries
+const earthRadiusMeters = 6378100
+
+// HousingRepository keeps housing listings in memory
+type HousingRepository struct {
+	mu         sync.RWMutex
+	properties map[primitive.ObjectID]models.Housing
+}
+
+// NewHousingRepository creates an empty housing repository
+func NewHousingRepository() *HousingRepository {
+	return &HousingRepository{
+		properties: map[primitive.ObjectID]models.Housing{},
+	}
+}
+
+// EnsureIndexes is a no-op; listings are scanned in full
+func (r *HousingRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Insert adds a new listing
+func (r *HousingRepository) Insert(ctx context.Context, property models.Housing) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.properties[property.ID]; ok {
+		return errDuplicateID
+	}
+	r.properties[property.ID] = copyHousing(property)
+	return nil
+}
+
+// FindByID returns the listing with the given ID
+func (r *HousingRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Housing, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	property, ok := r.properties[id]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+
+	property = copyHousing(property)
+	return &property, nil
+}
+
+// Replace overwrites a stored listing
+func (r *HousingRepository) Replace(ctx context.Context, property models.Housing) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.properties[property.ID]
+	if !ok {
+		return services.ErrNotFound
+	}
+	if stored.Version != property.Version {
+		return services.ErrConflict
+	}
+
+	property.Version++
+	r.properties[property.ID] = copyHousing(property)
+	return nil
+}
+
+// Delete removes a listing
+func (r *HousingRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.properties[id]; !ok {
+		return services.ErrNotFound
+	}
+	delete(r.properties, id)
+	return nil
+}
+
+// Search returns every listing matching filter, newest first
+func (r *HousingRepository) Search(ctx context.Context, filter services.HousingFilter) ([]models.Housing, error) {
+	properties := r.matching(filter)
+	sort.Slice(properties, func(i, j int) bool {
+		return properties[i].CreatedAt > properties[j].CreatedAt
+	})
+
+	return properties, nil
+}
+
+// List returns up to limit listings matching filter in page order
+func (r *HousingRepository) List(ctx context.Context, filter services.HousingFilter, page services.PageRequest, after *services.PageCursor, limit int) ([]models.Housing, error) {
+	desc := page.Order != services.SortAsc
+	before := func(value float64, id primitive.ObjectID, than float64, thanID primitive.ObjectID) bool {
+		if value != than {
+			return (value < than) != desc
+		}
+		if cmp := compareIDs(id, thanID); cmp != 0 {
+			return (cmp < 0) != desc
+		}
+		// A listing is not before itself, so a page never repeats the
+		// listing its cursor points at
+		return false
+	}
+
+	var properties []models.Housing
+	for _, property := range r.matching(filter) {
+		value := services.HousingSortValue(property, page.Sort)
+		if after != nil && !before(after.Value, after.ID, value, property.ID) {
+			continue
+		}
+		properties = append(properties, property)
+	}
+
+	sort.Slice(properties, func(i, j int) bool {
+		return before(
+			services.HousingSortValue(properties[i], page.Sort), properties[i].ID,
+			services.HousingSortValue(properties[j], page.Sort), properties[j].ID,
+		)
+	})
+
+	if len(properties) > limit {
+		properties = properties[:limit]
+	}
+	return properties, nil
+}
+
+// Count returns the number of listings matching filter
+func (r *HousingRepository) Count(ctx context.Context, filter services.HousingFilter) (int64, error) {
+	return int64(len(r.matching(filter))), nil
+}
+
+// Facets returns the distinct counties and types of the listings
+func (r *HousingRepository) Facets(ctx context.Context) (*services.HousingFacets, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counties := map[string]bool{}
+	types := map[string]bool{}
+	facets := &services.HousingFacets{}
+	for _, property := range r.properties {
+		if !counties[property.County] {
+			counties[property.County] = true
+			facets.Counties = append(facets.Counties, property.County)
+		}
+		if !types[property.Type] {
+			types[property.Type] = true
+			facets.Types = append(facets.Types, property.Type)
+		}
+	}
+	return facets, nil
+}
+
+// Near returns located listings matching filter ordered by great-circle
+// distance from origin
+func (r *HousingRepository) Near(ctx context.Context, origin services.LatLng, maxDistance float64, box *services.BoundingBox, filter services.HousingFilter, limit int) ([]services.HousingWithDistance, error) {
+	properties := []services.HousingWithDistance{}
+	for _, property := range r.matching(filter) {
+		if property.Location == nil || len(property.Location.Coordinates) != 2 {
+			continue
+		}
+
+		point := services.LatLng{Lat: property.Location.Coordinates[1], Lng: property.Location.Coordinates[0]}
+		if box != nil && !box.Contains(point) {
+			continue
+		}
+
+		distance := haversine(origin, point)
+		if maxDistance > 0 && distance > maxDistance {
+			continue
+		}
+
+		properties = append(properties, services.HousingWithDistance{Housing: property, DistanceMeters: distance})
+	}
+
+	sort.Slice(properties, func(i, j int) bool {
+		return properties[i].DistanceMeters < properties[j].DistanceMeters
+	})
+
+	if len(properties) > limit {
+		properties = properties[:limit]
+	}
+	return properties, nil
+}
+
+// TextSearch returns listings matching filter that contain the query,
+// scored by the weighted number of matches
+func (r *HousingRepository) TextSearch(ctx context.Context, text services.TextQuery, filter services.HousingFilter, limit int) ([]services.HousingMatch, error) {
+	matches := []services.HousingMatch{}
+	for _, property := range r.matching(filter) {
+		if text.Matches(property) {
+			matches = append(matches, services.HousingMatch{Housing: property, Score: text.Score(property)})
+		}
+	}
+
+	services.SortMatches(matches)
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// matching returns copies of the listings that satisfy filter
+func (r *HousingRepository) matching(filter services.HousingFilter) []models.Housing {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var properties []models.Housing
+	for _, property := range r.properties {
+		if filter.Matches(property) {
+			properties = append(properties, copyHousing(property))
+		}
+	}
+	return properties
+}
+
+// copyHousing returns a copy of property that shares no memory with it
+func copyHousing(property models.Housing) models.Housing {
+	if property.Location != nil {
+		location := *property.Location
+		location.Coordinates = append([]float64(nil), property.Location.Coordinates...)
+		property.Location = &location
+	}
+	property.Images = append([]models.HousingImage(nil), property.Images...)
+	return property
+}
+
+// haversine returns the great-circle distance between two points in meters
+func haversine(a, b services.LatLng) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(b.Lat - a.Lat)
+	dLng := toRad(b.Lng - a.Lng)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(a.Lat))*math.Cos(toRad(b.Lat))*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(h)))
+}
@@ -0,0 +1,138 @@
+package memoryrepo
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// alertKey identifies the alert of a listing for a saved search
+type alertKey struct {
+	savedSearchID primitive.ObjectID
+	propertyID    primitive.ObjectID
+}
+
+// ListingAlertRepository keeps listing alerts in memory
+type ListingAlertRepository struct {
+	mu     sync.RWMutex
+	alerts map[alertKey]models.ListingAlert
+}
+
+// NewListingAlertRepository creates an empty listing alert repository
+func NewListingAlertRepository() *ListingAlertRepository {
+	return &ListingAlertRepository{
+		alerts: map[alertKey]models.ListingAlert{},
+	}
+}
+
+// EnsureIndexes is a no-op; alerts are keyed by search and listing
+func (r *ListingAlertRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Insert records alert unless the listing already alerted the same search
+func (r *ListingAlertRepository) Insert(ctx context.Context, alert models.ListingAlert) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := alertKey{alert.SavedSearchID, alert.PropertyID}
+	if _, ok := r.alerts[key]; ok {
+		return false, nil
+	}
+	r.alerts[key] = copyAlert(alert)
+	return true, nil
+}
+
+// FindByUser returns up to limit of a user's alerts, newest first
+func (r *ListingAlertRepository) FindByUser(ctx context.Context, userID primitive.ObjectID, limit int) ([]models.ListingAlert, error) {
+	alerts := r.find(func(alert models.ListingAlert) bool {
+		return alert.UserID == userID
+	})
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].CreatedAt != alerts[j].CreatedAt {
+			return alerts[i].CreatedAt > alerts[j].CreatedAt
+		}
+		return compareIDs(alerts[i].ID, alerts[j].ID) > 0
+	})
+	if len(alerts) > limit {
+		alerts = alerts[:limit]
+	}
+	return alerts, nil
+}
+
+// FindUndelivered returns the alerts of a search not yet sent, oldest first
+func (r *ListingAlertRepository) FindUndelivered(ctx context.Context, savedSearchID primitive.ObjectID) ([]models.ListingAlert, error) {
+	alerts := r.find(func(alert models.ListingAlert) bool {
+		return alert.SavedSearchID == savedSearchID && alert.DeliveredAt == nil
+	})
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].CreatedAt != alerts[j].CreatedAt {
+			return alerts[i].CreatedAt < alerts[j].CreatedAt
+		}
+		return compareIDs(alerts[i].ID, alerts[j].ID) < 0
+	})
+	return alerts, nil
+}
+
+// MarkDelivered records that the alerts with ids were sent
+func (r *ListingAlertRepository) MarkDelivered(ctx context.Context, ids []primitive.ObjectID, at time.Time) error {
+	delivered := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		delivered[id] = true
+	}
+	deliveredAt := primitive.NewDateTimeFromTime(at)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, alert := range r.alerts {
+		if delivered[alert.ID] {
+			at := deliveredAt
+			alert.DeliveredAt = &at
+			r.alerts[key] = alert
+		}
+	}
+	return nil
+}
+
+// DeleteBySearch removes every alert of a saved search
+func (r *ListingAlertRepository) DeleteBySearch(ctx context.Context, savedSearchID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key := range r.alerts {
+		if key.savedSearchID == savedSearchID {
+			delete(r.alerts, key)
+		}
+	}
+	return nil
+}
+
+// find returns copies of the alerts accepted by match in no particular order
+func (r *ListingAlertRepository) find(match func(models.ListingAlert) bool) []models.ListingAlert {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var alerts []models.ListingAlert
+	for _, alert := range r.alerts {
+		if match(alert) {
+			alerts = append(alerts, copyAlert(alert))
+		}
+	}
+	return alerts
+}
+
+// copyAlert returns a copy of alert that shares no memory with it
+func copyAlert(alert models.ListingAlert) models.ListingAlert {
+	if alert.DeliveredAt != nil {
+		deliveredAt := *alert.DeliveredAt
+		alert.DeliveredAt = &deliveredAt
+	}
+	return alert
+}
@@ -0,0 +1,92 @@
+package memoryrepo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoginAttemptRepository keeps failed login counts in memory
+type LoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempts
+}
+
+// NewLoginAttemptRepository creates an empty login attempt repository
+func NewLoginAttemptRepository() *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		attempts: map[string]models.LoginAttempts{},
+	}
+}
+
+// EnsureIndexes is a no-op; records are looked up by email
+func (r *LoginAttemptRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Find returns the failed logins of email that are not yet forgotten
+func (r *LoginAttemptRepository) Find(ctx context.Context, email string, now time.Time) (*models.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts, ok := r.attempts[email]
+	if !ok || !attempts.ExpiresAt.Time().After(now) {
+		return nil, services.ErrNotFound
+	}
+	return copyLoginAttempts(attempts), nil
+}
+
+// RecordFailure counts a failed login, restarting the count when the
+// previous failures are forgotten
+func (r *LoginAttemptRepository) RecordFailure(ctx context.Context, email string, at time.Time, window time.Duration) (*models.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts, ok := r.attempts[email]
+	if !ok || !attempts.ExpiresAt.Time().After(at) {
+		attempts = models.LoginAttempts{Email: email}
+	}
+	attempts.Failures++
+	attempts.LastFailure = primitive.NewDateTimeFromTime(at)
+	attempts.ExpiresAt = primitive.NewDateTimeFromTime(at.Add(window))
+	r.attempts[email] = attempts
+
+	return copyLoginAttempts(attempts), nil
+}
+
+// Lock refuses the logins of email until until
+func (r *LoginAttemptRepository) Lock(ctx context.Context, email string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts, ok := r.attempts[email]
+	if !ok {
+		return services.ErrNotFound
+	}
+	lockedUntil := primitive.NewDateTimeFromTime(until)
+	attempts.LockedUntil = &lockedUntil
+	r.attempts[email] = attempts
+	return nil
+}
+
+// Clear forgets the failed logins of email
+func (r *LoginAttemptRepository) Clear(ctx context.Context, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, email)
+	return nil
+}
+
+// copyLoginAttempts returns a copy of attempts that shares no pointers
+func copyLoginAttempts(attempts models.LoginAttempts) *models.LoginAttempts {
+	if attempts.LockedUntil != nil {
+		lockedUntil := *attempts.LockedUntil
+		attempts.LockedUntil = &lockedUntil
+	}
+	return &attempts
+}
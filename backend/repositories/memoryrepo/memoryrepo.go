@@ -0,0 +1,53 @@
+// Package memoryrepo implements the service repositories in process memory.
+// Nothing is persisted; it is meant for local development and tests
+package memoryrepo
+
+import (
+	"bytes"
+	"errors"
+
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errDuplicateID is returned when inserting a record whose ID is taken
+var errDuplicateID = errors.New("duplicate ID")
+
+// Compile-time checks that every repository satisfies its interface
+var (
+	_ services.HousingRepository      = (*HousingRepository)(nil)
+	_ services.UserRepository         = (*UserRepository)(nil)
+	_ services.RequestRepository      = (*RequestRepository)(nil)
+	_ services.SessionRepository      = (*SessionRepository)(nil)
+	_ services.AccountTokenRepository = (*AccountTokenRepository)(nil)
+	_ services.LoginAttemptRepository = (*LoginAttemptRepository)(nil)
+	_ services.FavoriteRepository     = (*FavoriteRepository)(nil)
+	_ services.SavedSearchRepository  = (*SavedSearchRepository)(nil)
+	_ services.ListingAlertRepository = (*ListingAlertRepository)(nil)
+	_ services.PriceHistoryRepository = (*PriceHistoryRepository)(nil)
+	_ services.AvailabilityRepository = (*AvailabilityRepository)(nil)
+	_ services.TourRepository         = (*TourRepository)(nil)
+)
+
+// NewRepositories returns a fresh, empty set of in-memory repositories
+func NewRepositories() services.Repositories {
+	return services.Repositories{
+		Housing:       NewHousingRepository(),
+		Users:         NewUserRepository(),
+		Requests:      NewRequestRepository(),
+		Sessions:      NewSessionRepository(),
+		AccountTokens: NewAccountTokenRepository(),
+		LoginAttempts: NewLoginAttemptRepository(),
+		Favorites:     NewFavoriteRepository(),
+		SavedSearches: NewSavedSearchRepository(),
+		Alerts:        NewListingAlertRepository(),
+		PriceHistory:  NewPriceHistoryRepository(),
+		Availability:  NewAvailabilityRepository(),
+		Tours:         NewTourRepository(),
+	}
+}
+
+// compareIDs orders ObjectIDs the way MongoDB does
+func compareIDs(a, b primitive.ObjectID) int {
+	return bytes.Compare(a[:], b[:])
+}
@@ -0,0 +1,107 @@
+package memoryrepo
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PriceHistoryRepository keeps listing price changes in memory
+type PriceHistoryRepository struct {
+	mu      sync.RWMutex
+	changes []models.PriceChange
+}
+
+// NewPriceHistoryRepository creates an empty price history repository
+func NewPriceHistoryRepository() *PriceHistoryRepository {
+	return &PriceHistoryRepository{}
+}
+
+// EnsureIndexes is a no-op; price changes are scanned in full
+func (r *PriceHistoryRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Insert adds a price change
+func (r *PriceHistoryRepository) Insert(ctx context.Context, change models.PriceChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.changes {
+		if existing.ID == change.ID {
+			return errDuplicateID
+		}
+	}
+	r.changes = append(r.changes, change)
+	return nil
+}
+
+// FindByProperty returns the price changes of a listing, newest first
+func (r *PriceHistoryRepository) FindByProperty(ctx context.Context, propertyID primitive.ObjectID) ([]models.PriceChange, error) {
+	changes := r.find(func(change models.PriceChange) bool {
+		return change.PropertyID == propertyID
+	})
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].ChangedAt != changes[j].ChangedAt {
+			return changes[i].ChangedAt > changes[j].ChangedAt
+		}
+		return compareIDs(changes[i].ID, changes[j].ID) > 0
+	})
+	return changes, nil
+}
+
+// FindSince returns the price changes of the given listings made at or after
+// since, oldest first
+func (r *PriceHistoryRepository) FindSince(ctx context.Context, propertyIDs []primitive.ObjectID, since time.Time) ([]models.PriceChange, error) {
+	wanted := make(map[primitive.ObjectID]bool, len(propertyIDs))
+	for _, id := range propertyIDs {
+		wanted[id] = true
+	}
+	from := primitive.NewDateTimeFromTime(since)
+
+	changes := r.find(func(change models.PriceChange) bool {
+		return wanted[change.PropertyID] && change.ChangedAt >= from
+	})
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].ChangedAt != changes[j].ChangedAt {
+			return changes[i].ChangedAt < changes[j].ChangedAt
+		}
+		return compareIDs(changes[i].ID, changes[j].ID) < 0
+	})
+	return changes, nil
+}
+
+// DeleteByProperty removes the price history of a listing
+func (r *PriceHistoryRepository) DeleteByProperty(ctx context.Context, propertyID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.changes[:0]
+	for _, change := range r.changes {
+		if change.PropertyID != propertyID {
+			kept = append(kept, change)
+		}
+	}
+	r.changes = kept
+	return nil
+}
+
+// find returns the price changes accepted by match
+func (r *PriceHistoryRepository) find(match func(models.PriceChange) bool) []models.PriceChange {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var changes []models.PriceChange
+	for _, change := range r.changes {
+		if match(change) {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,156 @@
+package memoryrepo
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RequestRepository keeps property requests in memory
+type RequestRepository struct {
+	mu       sync.RWMutex
+	requests map[primitive.ObjectID]models.PropertyRequest
+}
+
+// NewRequestRepository creates an empty request repository
+func NewRequestRepository() *RequestRepository {
+	return &RequestRepository{
+		requests: map[primitive.ObjectID]models.PropertyRequest{},
+	}
+}
+
+// EnsureIndexes is a no-op; requests are scanned in full
+func (r *RequestRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Insert adds a new request
+func (r *RequestRepository) Insert(ctx context.Context, request models.PropertyRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.requests[request.ID]; ok {
+		return errDuplicateID
+	}
+	r.requests[request.ID] = copyRequest(request)
+	return nil
+}
+
+// FindByID returns the request with the given ID
+func (r *RequestRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.PropertyRequest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	request, ok := r.requests[id]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+
+	request = copyRequest(request)
+	return &request, nil
+}
+
+// FindByUser returns a user's requests, newest first
+func (r *RequestRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.PropertyRequest, error) {
+	return r.find(func(request models.PropertyRequest) bool {
+		return request.UserID == userID
+	}), nil
+}
+
+// FindAll returns every request, newest first
+func (r *RequestRepository) FindAll(ctx context.Context) ([]models.PropertyRequest, error) {
+	return r.find(func(models.PropertyRequest) bool { return true }), nil
+}
+
+// FindByProperties returns the requests for any of the listings, newest first
+func (r *RequestRepository) FindByProperties(ctx context.Context, propertyIDs []primitive.ObjectID) ([]models.PropertyRequest, error) {
+	wanted := make(map[primitive.ObjectID]bool, len(propertyIDs))
+	for _, id := range propertyIDs {
+		wanted[id] = true
+	}
+	return r.find(func(request models.PropertyRequest) bool {
+		return wanted[request.PropertyID]
+	}), nil
+}
+
+// Transition applies a status change while the request is still in change.From
+func (r *RequestRepository) Transition(ctx context.Context, id primitive.ObjectID, change models.RequestStatusChange, rejectionReason string) error {
+	return r.updateInStatus(id, change.From, func(request *models.PropertyRequest) {
+		processedBy := change.ChangedBy
+		request.Status = change.To
+		request.UpdatedAt = change.ChangedAt
+		request.ProcessedBy = &processedBy
+		if change.To == models.StatusRejected {
+			request.RejectionReason = rejectionReason
+		}
+		request.History = append(request.History, change)
+	})
+}
+
+// UpdateMessage changes the message while the request has status
+func (r *RequestRepository) UpdateMessage(ctx context.Context, id primitive.ObjectID, status string, message string, at time.Time) error {
+	return r.updateInStatus(id, status, func(request *models.PropertyRequest) {
+		request.Message = message
+		request.UpdatedAt = primitive.NewDateTimeFromTime(at)
+	})
+}
+
+// Delete removes a request
+func (r *RequestRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.requests[id]; !ok {
+		return services.ErrNotFound
+	}
+	delete(r.requests, id)
+	return nil
+}
+
+// updateInStatus applies update only if the request still has status
+func (r *RequestRepository) updateInStatus(id primitive.ObjectID, status string, update func(*models.PropertyRequest)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	request, ok := r.requests[id]
+	if !ok || request.Status != status {
+		return services.ErrNotFound
+	}
+
+	request = copyRequest(request)
+	update(&request)
+	r.requests[id] = request
+	return nil
+}
+
+// find returns copies of the requests accepted by match, newest first
+func (r *RequestRepository) find(match func(models.PropertyRequest) bool) []models.PropertyRequest {
+	r.mu.RLock()
+	var requests []models.PropertyRequest
+	for _, request := range r.requests {
+		if match(request) {
+			requests = append(requests, copyRequest(request))
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].CreatedAt > requests[j].CreatedAt
+	})
+	return requests
+}
+
+// copyRequest returns a copy of request that shares no memory with it
+func copyRequest(request models.PropertyRequest) models.PropertyRequest {
+	if request.ProcessedBy != nil {
+		processedBy := *request.ProcessedBy
+		request.ProcessedBy = &processedBy
+	}
+	request.History = append([]models.RequestStatusChange(nil), request.History...)
+	return request
+}
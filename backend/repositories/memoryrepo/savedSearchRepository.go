@@ -0,0 +1,152 @@
+package memoryrepo
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SavedSearchRepository keeps saved searches in memory
+type SavedSearchRepository struct {
+	mu       sync.RWMutex
+	searches map[primitive.ObjectID]models.SavedSearch
+}
+
+// NewSavedSearchRepository creates an empty saved search repository
+func NewSavedSearchRepository() *SavedSearchRepository {
+	return &SavedSearchRepository{
+		searches: map[primitive.ObjectID]models.SavedSearch{},
+	}
+}
+
+// EnsureIndexes is a no-op; saved searches are scanned in full
+func (r *SavedSearchRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Insert adds a new saved search
+func (r *SavedSearchRepository) Insert(ctx context.Context, search models.SavedSearch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.searches[search.ID]; ok {
+		return errDuplicateID
+	}
+	r.searches[search.ID] = copySavedSearch(search)
+	return nil
+}
+
+// FindByID returns the saved search with the given ID
+func (r *SavedSearchRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.SavedSearch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	search, ok := r.searches[id]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+
+	search = copySavedSearch(search)
+	return &search, nil
+}
+
+// FindByUser returns a user's saved searches, newest first
+func (r *SavedSearchRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.SavedSearch, error) {
+	return r.find(func(search models.SavedSearch) bool {
+		return search.UserID == userID
+	}), nil
+}
+
+// FindAll returns every saved search
+func (r *SavedSearchRepository) FindAll(ctx context.Context) ([]models.SavedSearch, error) {
+	return r.find(func(models.SavedSearch) bool { return true }), nil
+}
+
+// Replace overwrites a saved search
+func (r *SavedSearchRepository) Replace(ctx context.Context, search models.SavedSearch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.searches[search.ID]; !ok {
+		return services.ErrNotFound
+	}
+	r.searches[search.ID] = copySavedSearch(search)
+	return nil
+}
+
+// SetLastNotified records when the user was last alerted about a search
+func (r *SavedSearchRepository) SetLastNotified(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	search, ok := r.searches[id]
+	if !ok {
+		return services.ErrNotFound
+	}
+	notifiedAt := primitive.NewDateTimeFromTime(at)
+	search.LastNotifiedAt = &notifiedAt
+	r.searches[id] = search
+	return nil
+}
+
+// Delete removes a saved search
+func (r *SavedSearchRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.searches[id]; !ok {
+		return services.ErrNotFound
+	}
+	delete(r.searches, id)
+	return nil
+}
+
+// find returns copies of the saved searches accepted by match, newest first
+func (r *SavedSearchRepository) find(match func(models.SavedSearch) bool) []models.SavedSearch {
+	r.mu.RLock()
+	var searches []models.SavedSearch
+	for _, search := range r.searches {
+		if match(search) {
+			searches = append(searches, copySavedSearch(search))
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(searches, func(i, j int) bool {
+		return searches[i].CreatedAt > searches[j].CreatedAt
+	})
+	return searches
+}
+
+// copySavedSearch returns a copy of search that shares no memory with it
+func copySavedSearch(search models.SavedSearch) models.SavedSearch {
+	if search.LastNotifiedAt != nil {
+		notifiedAt := *search.LastNotifiedAt
+		search.LastNotifiedAt = &notifiedAt
+	}
+	for _, rg := range []*models.SearchRange{
+		&search.Criteria.Price,
+		&search.Criteria.Bedrooms,
+		&search.Criteria.Bathrooms,
+		&search.Criteria.Year,
+		&search.Criteria.Surface,
+	} {
+		rg.Min = copyFloat(rg.Min)
+		rg.Max = copyFloat(rg.Max)
+	}
+	return search
+}
+
+// copyFloat returns a pointer to a copy of *f, or nil
+func copyFloat(f *float64) *float64 {
+	if f == nil {
+		return nil
+	}
+	v := *f
+	return &v
+}
@@ -0,0 +1,129 @@
+package memoryrepo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SessionRepository keeps login sessions in memory
+type SessionRepository struct {
+	mu       sync.RWMutex
+	sessions map[primitive.ObjectID]models.Session
+}
+
+// NewSessionRepository creates an empty session repository
+func NewSessionRepository() *SessionRepository {
+	return &SessionRepository{
+		sessions: map[primitive.ObjectID]models.Session{},
+	}
+}
+
+// EnsureIndexes is a no-op; sessions are scanned in full
+func (r *SessionRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Insert adds a new session
+func (r *SessionRepository) Insert(ctx context.Context, session models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sessions[session.ID]; ok {
+		return errDuplicateID
+	}
+	r.sessions[session.ID] = copySession(session)
+	return nil
+}
+
+// Rotate swaps the refresh token of the active session holding oldHash
+func (r *SessionRepository) Rotate(ctx context.Context, oldHash string, newHash string, now time.Time, expiresAt time.Time) (*models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, session := range r.sessions {
+		if session.RefreshTokenHash != oldHash || !active(session, now) {
+			continue
+		}
+
+		session = copySession(session)
+		session.RefreshTokenHash = newHash
+		session.LastUsedAt = primitive.NewDateTimeFromTime(now)
+		session.ExpiresAt = primitive.NewDateTimeFromTime(expiresAt)
+		session.PreviousTokenHashes = append(session.PreviousTokenHashes, oldHash)
+		r.sessions[id] = session
+
+		session = copySession(session)
+		return &session, nil
+	}
+
+	return nil, services.ErrNotFound
+}
+
+// IsActive reports whether a session exists and has not been revoked or expired
+func (r *SessionRepository) IsActive(ctx context.Context, id primitive.ObjectID, now time.Time) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.sessions[id]
+	return ok && active(session, now), nil
+}
+
+// Revoke marks the unrevoked sessions matching match as revoked
+func (r *SessionRepository) Revoke(ctx context.Context, match services.SessionMatch, reason string, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revokedAt := primitive.NewDateTimeFromTime(now)
+	var revoked int64
+	for id, session := range r.sessions {
+		if session.RevokedAt != nil || !matches(session, match) {
+			continue
+		}
+
+		session.RevokedAt = &revokedAt
+		session.RevokedReason = reason
+		r.sessions[id] = session
+		revoked++
+	}
+
+	return revoked, nil
+}
+
+// active reports whether session is neither revoked nor expired at now
+func active(session models.Session, now time.Time) bool {
+	return session.RevokedAt == nil && session.ExpiresAt.Time().After(now)
+}
+
+// matches reports whether session is selected by match
+func matches(session models.Session, match services.SessionMatch) bool {
+	switch {
+	case match.ID != nil:
+		return session.ID == *match.ID
+	case match.UserID != nil:
+		return session.UserID == *match.UserID
+	case match.RefreshTokenHash != "":
+		return session.RefreshTokenHash == match.RefreshTokenHash
+	case match.PreviousTokenHash != "":
+		for _, hash := range session.PreviousTokenHashes {
+			if hash == match.PreviousTokenHash {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copySession returns a copy of session that shares no memory with it
+func copySession(session models.Session) models.Session {
+	session.PreviousTokenHashes = append([]string{}, session.PreviousTokenHashes...)
+	if session.RevokedAt != nil {
+		revokedAt := *session.RevokedAt
+		session.RevokedAt = &revokedAt
+	}
+	return session
+}
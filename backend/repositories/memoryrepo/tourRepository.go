@@ -0,0 +1,212 @@
+package memoryrepo
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TourRepository keeps tours in memory. The lock makes checking a slot and
+// taking it a single step, like the unique index does in MongoDB
+type TourRepository struct {
+	mu    sync.RWMutex
+	tours map[primitive.ObjectID]models.Tour
+}
+
+// NewTourRepository creates an empty tour repository
+func NewTourRepository() *TourRepository {
+	return &TourRepository{
+		tours: map[primitive.ObjectID]models.Tour{},
+	}
+}
+
+// EnsureIndexes is a no-op; tours are scanned in full
+func (r *TourRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Insert stores a booked tour, or returns ErrConflict when its slot is taken
+func (r *TourRepository) Insert(ctx context.Context, tour models.Tour) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tours[tour.ID]; ok {
+		return errDuplicateID
+	}
+	if tour.Status == models.TourBooked && r.slotTaken(tour.AgentKey, tour.Start, tour.ID) {
+		return services.ErrConflict
+	}
+	r.tours[tour.ID] = copyTour(tour)
+	return nil
+}
+
+// FindByID returns the tour with the given ID
+func (r *TourRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Tour, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tour, ok := r.tours[id]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+
+	tour = copyTour(tour)
+	return &tour, nil
+}
+
+// FindByUser returns a user's tours, latest start first
+func (r *TourRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Tour, error) {
+	tours := r.find(func(tour models.Tour) bool {
+		return tour.UserID == userID
+	})
+	sortTours(tours, false)
+	return tours, nil
+}
+
+// FindAll returns every tour, latest start first
+func (r *TourRepository) FindAll(ctx context.Context) ([]models.Tour, error) {
+	tours := r.find(func(models.Tour) bool { return true })
+	sortTours(tours, false)
+	return tours, nil
+}
+
+// FindByAgent returns the tours of an agent starting at or after since, in
+// start order
+func (r *TourRepository) FindByAgent(ctx context.Context, agentKey string, since time.Time) ([]models.Tour, error) {
+	from := primitive.NewDateTimeFromTime(since)
+	tours := r.find(func(tour models.Tour) bool {
+		return tour.AgentKey == agentKey && tour.Start >= from
+	})
+	sortTours(tours, true)
+	return tours, nil
+}
+
+// FindBooked returns the booked tours of an agent that overlap the period
+// from from to to, in start order
+func (r *TourRepository) FindBooked(ctx context.Context, agentKey string, from time.Time, to time.Time) ([]models.Tour, error) {
+	start, end := primitive.NewDateTimeFromTime(from), primitive.NewDateTimeFromTime(to)
+	tours := r.find(func(tour models.Tour) bool {
+		return tour.AgentKey == agentKey && tour.Status == models.TourBooked &&
+			tour.Start < end && tour.End > start
+	})
+	sortTours(tours, true)
+	return tours, nil
+}
+
+// Reschedule moves a booked tour to another slot
+func (r *TourRepository) Reschedule(ctx context.Context, id primitive.ObjectID, start time.Time, end time.Time, at time.Time) (*models.Tour, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tour, ok := r.tours[id]
+	if !ok || tour.Status != models.TourBooked {
+		return nil, services.ErrNotFound
+	}
+
+	newStart := primitive.NewDateTimeFromTime(start)
+	if r.slotTaken(tour.AgentKey, newStart, tour.ID) {
+		return nil, services.ErrConflict
+	}
+
+	tour.Start = newStart
+	tour.End = primitive.NewDateTimeFromTime(end)
+	tour.UpdatedAt = primitive.NewDateTimeFromTime(at)
+	tour.Sequence++
+	r.tours[id] = tour
+
+	tour = copyTour(tour)
+	return &tour, nil
+}
+
+// Cancel cancels a booked tour
+func (r *TourRepository) Cancel(ctx context.Context, id primitive.ObjectID, at time.Time) (*models.Tour, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tour, ok := r.tours[id]
+	if !ok || tour.Status != models.TourBooked {
+		return nil, services.ErrNotFound
+	}
+
+	tour = cancelTour(tour, at)
+	r.tours[id] = tour
+
+	tour = copyTour(tour)
+	return &tour, nil
+}
+
+// CancelByProperty cancels every booked tour of a listing
+func (r *TourRepository) CancelByProperty(ctx context.Context, propertyID primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, tour := range r.tours {
+		if tour.PropertyID == propertyID && tour.Status == models.TourBooked {
+			r.tours[id] = cancelTour(tour, at)
+		}
+	}
+	return nil
+}
+
+// slotTaken reports whether the agent has a booked tour other than except
+// starting at start. The caller must hold the lock
+func (r *TourRepository) slotTaken(agentKey string, start primitive.DateTime, except primitive.ObjectID) bool {
+	for _, tour := range r.tours {
+		if tour.ID != except && tour.AgentKey == agentKey && tour.Status == models.TourBooked && tour.Start == start {
+			return true
+		}
+	}
+	return false
+}
+
+// find returns copies of the tours accepted by match
+func (r *TourRepository) find(match func(models.Tour) bool) []models.Tour {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tours []models.Tour
+	for _, tour := range r.tours {
+		if match(tour) {
+			tours = append(tours, copyTour(tour))
+		}
+	}
+	return tours
+}
+
+// sortTours orders tours by start, earliest first when ascending
+func sortTours(tours []models.Tour, ascending bool) {
+	sort.Slice(tours, func(i, j int) bool {
+		a, b := tours[i], tours[j]
+		if !ascending {
+			a, b = b, a
+		}
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		return compareIDs(a.ID, b.ID) < 0
+	})
+}
+
+// cancelTour returns tour cancelled at the given time
+func cancelTour(tour models.Tour, at time.Time) models.Tour {
+	now := primitive.NewDateTimeFromTime(at)
+	tour.Status = models.TourCancelled
+	tour.CancelledAt = &now
+	tour.UpdatedAt = now
+	tour.Sequence++
+	return tour
+}
+
+// copyTour returns a copy of tour that shares no memory with it
+func copyTour(tour models.Tour) models.Tour {
+	if tour.CancelledAt != nil {
+		cancelledAt := *tour.CancelledAt
+		tour.CancelledAt = &cancelledAt
+	}
+	return tour
+}
@@ -0,0 +1,161 @@
+package memoryrepo
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserRepository keeps user accounts in memory
+type UserRepository struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]models.Users
+}
+
+// NewUserRepository creates an empty user repository
+func NewUserRepository() *UserRepository {
+	return &UserRepository{
+		users: map[primitive.ObjectID]models.Users{},
+	}
+}
+
+// EnsureIndexes is a no-op; users are scanned in full
+func (r *UserRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Insert adds a new user, or returns ErrConflict when its email is taken
+func (r *UserRepository) Insert(ctx context.Context, user models.Users) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[user.ID]; ok {
+		return errDuplicateID
+	}
+	user.Email = models.NormalizeEmail(user.Email)
+	if r.emailTaken(user.Email, user.ID) {
+		return services.ErrConflict
+	}
+	r.users[user.ID] = user
+	return nil
+}
+
+// FindByID returns the user with the given ID
+func (r *UserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Users, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+	return &user, nil
+}
+
+// FindByEmail returns the user registered with email
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.Users, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	email = models.NormalizeEmail(email)
+	for _, user := range r.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, services.ErrNotFound
+}
+
+// List returns a page of users matching query, newest first
+func (r *UserRepository) List(ctx context.Context, query services.UserQuery) ([]models.Users, int64, error) {
+	r.mu.RLock()
+	search := strings.ToLower(query.Search)
+	var users []models.Users
+	for _, user := range r.users {
+		if search != "" &&
+			!strings.Contains(strings.ToLower(user.FirstName), search) &&
+			!strings.Contains(strings.ToLower(user.LastName), search) &&
+			!strings.Contains(strings.ToLower(user.Email), search) {
+			continue
+		}
+		if query.Role != "" && user.Role != query.Role {
+			continue
+		}
+		if query.Disabled != nil && user.Disabled != *query.Disabled {
+			continue
+		}
+		users = append(users, user)
+	}
+	r.mu.RUnlock()
+
+	// ObjectIDs embed their creation time, so ID orders by registration
+	sort.Slice(users, func(i, j int) bool {
+		return compareIDs(users[i].ID, users[j].ID) > 0
+	})
+
+	total := int64(len(users))
+	start := (query.Page - 1) * query.Limit
+	if start > len(users) {
+		start = len(users)
+	}
+	end := start + query.Limit
+	if end > len(users) {
+		end = len(users)
+	}
+
+	return users[start:end], total, nil
+}
+
+// Update applies patch to a user and returns the updated user, or returns
+// ErrConflict when the new email is taken
+func (r *UserRepository) Update(ctx context.Context, id primitive.ObjectID, patch services.UserPatch) (*models.Users, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+
+	if patch.Email != nil {
+		email := models.NormalizeEmail(*patch.Email)
+		if r.emailTaken(email, id) {
+			return nil, services.ErrConflict
+		}
+		user.Email = email
+	}
+	if patch.Role != nil {
+		user.Role = *patch.Role
+	}
+	if patch.Password != nil {
+		user.Password = *patch.Password
+	}
+	if patch.Disabled != nil {
+		user.Disabled = *patch.Disabled
+	}
+	if patch.PasswordResetRequired != nil {
+		user.PasswordResetRequired = *patch.PasswordResetRequired
+	}
+	if patch.EmailVerified != nil {
+		user.EmailVerified = *patch.EmailVerified
+	}
+
+	r.users[id] = user
+	return &user, nil
+}
+
+// emailTaken reports whether a user other than id has email. The caller
+// must hold the lock
+func (r *UserRepository) emailTaken(email string, id primitive.ObjectID) bool {
+	for _, user := range r.users {
+		if user.Email == email && user.ID != id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AccountTokenRepository stores account tokens in a MongoDB collection
+type AccountTokenRepository struct {
+	collection *mongo.Collection
+}
+
+// NewAccountTokenRepository creates an account token repository on collection
+func NewAccountTokenRepository(collection *mongo.Collection) *AccountTokenRepository {
+	return &AccountTokenRepository{
+		collection: collection,
+	}
+}
+
+// EnsureIndexes creates the lookup index and a TTL index that removes
+// tokens once they expire
+func (r *AccountTokenRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "purpose", Value: 1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+// Insert adds a new token
+func (r *AccountTokenRepository) Insert(ctx context.Context, token models.AccountToken) error {
+	_, err := r.collection.InsertOne(ctx, token)
+	return err
+}
+
+// InvalidateUnused marks every unused token of userID for purpose as used
+func (r *AccountTokenRepository) InvalidateUnused(ctx context.Context, userID primitive.ObjectID, purpose string, now time.Time) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"userId": userID, "purpose": purpose, "usedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"usedAt": primitive.NewDateTimeFromTime(now)}},
+	)
+	return err
+}
+
+// Consume atomically marks a valid token as used and returns it
+func (r *AccountTokenRepository) Consume(ctx context.Context, hash string, purpose string, now time.Time) (*models.AccountToken, error) {
+	usedAt := primitive.NewDateTimeFromTime(now)
+	var token models.AccountToken
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"tokenHash": hash,
+			"purpose":   purpose,
+			"usedAt":    bson.M{"$exists": false},
+			"expiresAt": bson.M{"$gt": usedAt},
+		},
+		bson.M{"$set": bson.M{"usedAt": usedAt}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, services.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
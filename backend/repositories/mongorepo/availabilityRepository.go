@@ -0,0 +1,108 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AvailabilityRepository stores availability windows in a MongoDB collection
+type AvailabilityRepository struct {
+	collection *mongo.Collection
+}
+
+// NewAvailabilityRepository creates an availability repository on collection
+func NewAvailabilityRepository(collection *mongo.Collection) *AvailabilityRepository {
+	return &AvailabilityRepository{
+		collection: collection,
+	}
+}
+
+// availabilityDocument is an availability window as stored. Minutes lists
+// the start of every minute the window covers, so that a unique index over
+// an agent's minutes refuses overlapping windows atomically
+type availabilityDocument struct {
+	models.AvailabilityWindow `bson:",inline"`
+	Minutes                   []primitive.DateTime `bson:"minutes"`
+}
+
+// EnsureIndexes creates the per-listing lookup index and the unique index
+// over the minutes of each agent's windows
+func (r *AvailabilityRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "propertyId", Value: 1}, {Key: "start", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "agentKey", Value: 1}, {Key: "minutes", Value: 1}},
+			Options: options.Index().
+				SetName("agent_minutes").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"minutes": bson.M{"$exists": true}}),
+		},
+	})
+	return err
+}
+
+// Insert adds a new availability window, or returns ErrConflict when it
+// overlaps another window of the same agent
+func (r *AvailabilityRepository) Insert(ctx context.Context, window models.AvailabilityWindow) error {
+	var minutes []primitive.DateTime
+	for at := window.Start.Time(); at.Before(window.End.Time()); at = at.Add(time.Minute) {
+		minutes = append(minutes, primitive.NewDateTimeFromTime(at))
+	}
+
+	_, err := r.collection.InsertOne(ctx, availabilityDocument{AvailabilityWindow: window, Minutes: minutes})
+	if mongo.IsDuplicateKeyError(err) {
+		return services.ErrConflict
+	}
+	return err
+}
+
+// FindByProperty returns the windows of a listing that overlap the period
+// from from to to, in start order
+func (r *AvailabilityRepository) FindByProperty(ctx context.Context, propertyID primitive.ObjectID, from time.Time, to time.Time) ([]models.AvailabilityWindow, error) {
+	filter := bson.M{
+		"propertyId": propertyID,
+		"start":      bson.M{"$lt": primitive.NewDateTimeFromTime(to)},
+		"end":        bson.M{"$gt": primitive.NewDateTimeFromTime(from)},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "start", Value: 1}}).
+		SetProjection(bson.M{"minutes": 0})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	windows := []models.AvailabilityWindow{}
+	if err = cursor.All(ctx, &windows); err != nil {
+		return nil, err
+	}
+
+	return windows, nil
+}
+
+// Delete removes a window of a listing
+func (r *AvailabilityRepository) Delete(ctx context.Context, propertyID primitive.ObjectID, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "propertyId": propertyID})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return services.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByProperty removes every window of a listing
+func (r *AvailabilityRepository) DeleteByProperty(ctx context.Context, propertyID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"propertyId": propertyID})
+	return err
+}
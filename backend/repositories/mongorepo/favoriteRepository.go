@@ -0,0 +1,114 @@
+package mongorepo
+
+import (
+	"context"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FavoriteRepository stores saved listings in a MongoDB collection
+type FavoriteRepository struct {
+	collection *mongo.Collection
+}
+
+// NewFavoriteRepository creates a favorite repository on collection
+func NewFavoriteRepository(collection *mongo.Collection) *FavoriteRepository {
+	return &FavoriteRepository{
+		collection: collection,
+	}
+}
+
+// EnsureIndexes creates the unique user and listing index, which also serves
+// per-user lookups, and the per-listing index used for counts and cleanup
+func (r *FavoriteRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "propertyId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "propertyId", Value: 1}}},
+	})
+	return err
+}
+
+// Add saves favorite unless its user already saved the listing, and returns
+// the stored favorite either way
+func (r *FavoriteRepository) Add(ctx context.Context, favorite models.Favorite) (*models.Favorite, error) {
+	stored, err := r.upsert(ctx, favorite)
+	// Two concurrent upserts can both try to insert; the loser finds the
+	// winner's document on retry
+	if mongo.IsDuplicateKeyError(err) {
+		stored, err = r.upsert(ctx, favorite)
+	}
+	return stored, err
+}
+
+// Remove deletes a user's favorite of a listing
+func (r *FavoriteRepository) Remove(ctx context.Context, userID primitive.ObjectID, propertyID primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"userId": userID, "propertyId": propertyID})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return services.ErrNotFound
+	}
+	return nil
+}
+
+// FindByUser returns a user's favorites, newest first
+func (r *FavoriteRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Favorite, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var favorites []models.Favorite
+	if err = cursor.All(ctx, &favorites); err != nil {
+		return nil, err
+	}
+
+	return favorites, nil
+}
+
+// CountByProperty returns how many users saved a listing
+func (r *FavoriteRepository) CountByProperty(ctx context.Context, propertyID primitive.ObjectID) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"propertyId": propertyID})
+}
+
+// DeleteByProperty removes every favorite of a listing
+func (r *FavoriteRepository) DeleteByProperty(ctx context.Context, propertyID primitive.ObjectID) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"propertyId": propertyID})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// upsert inserts favorite unless a favorite of the same user and listing
+// exists and returns the stored document
+func (r *FavoriteRepository) upsert(ctx context.Context, favorite models.Favorite) (*models.Favorite, error) {
+	var stored models.Favorite
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"userId": favorite.UserID, "propertyId": favorite.PropertyID},
+		bson.M{"$setOnInsert": bson.M{
+			"_id":       favorite.ID,
+			"createdAt": favorite.CreatedAt,
+		}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&stored)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stored, nil
+}
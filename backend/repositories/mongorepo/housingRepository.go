@@ -0,0 +1,394 @@
+package mongorepo
+
+import (
+	"context"
+	"regexp"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// housingLocationField is the GeoJSON field indexed for geo queries
+const housingLocationField = "location"
+
+// sortFields maps the public sort keys to document fields
+var sortFields = map[string]string{
+	services.SortNewest:   "createdAt",
+	services.SortPrice:    "priceCents",
+	services.SortBedrooms: "bedrooms",
+	services.SortSurface:  "surface",
+}
+
+// HousingRepository stores housing listings in a MongoDB collection
+type HousingRepository struct {
+	collection *mongo.Collection
+}
+
+// NewHousingRepository creates a housing repository on collection
+func NewHousingRepository(collection *mongo.Collection) *HousingRepository {
+	return &HousingRepository{
+		collection: collection,
+	}
+}
+
+// EnsureIndexes creates the indexes the housing queries rely on
+func (r *HousingRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: housingLocationField, Value: "2dsphere"}},
+			Options: options.Index().SetName("location_2dsphere"),
+		},
+		{Keys: bson.D{{Key: "agentId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		textIndex(),
+	})
+	return err
+}
+
+// textIndex returns the text index over the searched fields. A collection
+// has at most one text index, so changing its fields or weights means
+// dropping "housing_text" first
+func textIndex() mongo.IndexModel {
+	keys := bson.D{}
+	weights := bson.D{}
+	for _, field := range services.TextFields {
+		keys = append(keys, bson.E{Key: field.Name, Value: "text"})
+		weights = append(weights, bson.E{Key: field.Name, Value: field.Weight})
+	}
+
+	return mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetName("housing_text").SetWeights(weights).SetDefaultLanguage("english"),
+	}
+}
+
+// Insert adds a new listing
+func (r *HousingRepository) Insert(ctx context.Context, property models.Housing) error {
+	_, err := r.collection.InsertOne(ctx, property)
+	return err
+}
+
+// FindByID returns the listing with the given ID
+func (r *HousingRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Housing, error) {
+	var property models.Housing
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&property)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, services.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &property, nil
+}
+
+// Replace overwrites a stored listing whose version is still
+// property.Version and increments its version
+func (r *HousingRepository) Replace(ctx context.Context, property models.Housing) error {
+	// Listings saved before versions were added have no version field
+	version := any(property.Version)
+	if property.Version == 0 {
+		version = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	filter := bson.M{"_id": property.ID, "version": version}
+	property.Version++
+	result, err := r.collection.ReplaceOne(ctx, filter, property)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		// Tell a missing listing from one saved in the meantime
+		count, err := r.collection.CountDocuments(ctx, bson.M{"_id": property.ID}, options.Count().SetLimit(1))
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return services.ErrNotFound
+		}
+		return services.ErrConflict
+	}
+	return nil
+}
+
+// Delete removes a listing
+func (r *HousingRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return services.ErrNotFound
+	}
+	return nil
+}
+
+// Search returns every listing matching filter, newest first
+func (r *HousingRepository) Search(ctx context.Context, filter services.HousingFilter) ([]models.Housing, error) {
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	return r.find(ctx, housingQuery(filter), findOptions)
+}
+
+// List returns up to limit listings matching filter in page order
+func (r *HousingRepository) List(ctx context.Context, filter services.HousingFilter, page services.PageRequest, after *services.PageCursor, limit int) ([]models.Housing, error) {
+	query := housingQuery(filter)
+	if after != nil {
+		query = bson.M{"$and": bson.A{query, afterCursor(page, after)}}
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(sortSpec(page))
+	findOptions.SetLimit(int64(limit))
+
+	return r.find(ctx, query, findOptions)
+}
+
+// Count returns the number of listings matching filter
+func (r *HousingRepository) Count(ctx context.Context, filter services.HousingFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, housingQuery(filter))
+}
+
+// Facets returns the distinct counties and types of the listings
+func (r *HousingRepository) Facets(ctx context.Context) (*services.HousingFacets, error) {
+	counties, err := r.distinct(ctx, "county")
+	if err != nil {
+		return nil, err
+	}
+	types, err := r.distinct(ctx, "type")
+	if err != nil {
+		return nil, err
+	}
+	return &services.HousingFacets{Counties: counties, Types: types}, nil
+}
+
+// distinct returns the distinct string values of a listing field
+func (r *HousingRepository) distinct(ctx context.Context, field string) ([]string, error) {
+	values, err := r.collection.Distinct(ctx, field, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, value := range values {
+		if str, ok := value.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result, nil
+}
+
+// Near runs a $geoNear aggregation from origin
+func (r *HousingRepository) Near(ctx context.Context, origin services.LatLng, maxDistance float64, box *services.BoundingBox, filter services.HousingFilter, limit int) ([]services.HousingWithDistance, error) {
+	query := housingQuery(filter)
+	if box != nil {
+		query[housingLocationField] = bson.M{"$geoWithin": bson.M{"$geometry": polygon(*box)}}
+	}
+
+	near := bson.M{
+		"near":          models.NewGeoPoint(origin.Lat, origin.Lng),
+		"distanceField": "distanceMeters",
+		"key":           housingLocationField,
+		"spherical":     true,
+		"query":         query,
+	}
+	if maxDistance > 0 {
+		near["maxDistance"] = maxDistance
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$geoNear", Value: near}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	properties := []services.HousingWithDistance{}
+	if err = cursor.All(ctx, &properties); err != nil {
+		return nil, err
+	}
+
+	return properties, nil
+}
+
+// TextSearch ranks listings by the text index score. Prefixes are matched
+// with regular expressions, which the text index cannot serve; when the
+// query only has prefixes the listings are scored in process instead
+func (r *HousingRepository) TextSearch(ctx context.Context, text services.TextQuery, filter services.HousingFilter, limit int) ([]services.HousingMatch, error) {
+	conditions := bson.A{housingQuery(filter)}
+	for _, prefix := range text.Prefixes {
+		conditions = append(conditions, prefixQuery(prefix))
+	}
+
+	search := text.SearchString()
+	if search == "" {
+		// Regular expressions cannot use the text index, so only the newest
+		// candidates are scored
+		findOptions := options.Find().
+			SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+			SetLimit(services.MaxTextCandidates)
+		properties, err := r.find(ctx, bson.M{"$and": conditions}, findOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		matches := make([]services.HousingMatch, len(properties))
+		for i, property := range properties {
+			matches[i] = services.HousingMatch{Housing: property, Score: text.Score(property)}
+		}
+		services.SortMatches(matches)
+		if len(matches) > limit {
+			matches = matches[:limit]
+		}
+		return matches, nil
+	}
+
+	// $text must sit at the top level of the query
+	query := bson.M{"$text": bson.M{"$search": search}, "$and": conditions}
+	score := bson.M{"$meta": "textScore"}
+	findOptions := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "createdAt", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, query, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	matches := []services.HousingMatch{}
+	if err = cursor.All(ctx, &matches); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// prefixQuery matches listings with a word starting with prefix in any
+// searched field
+func prefixQuery(prefix string) bson.M {
+	pattern := primitive.Regex{Pattern: `(^|[^\p{L}\p{N}])` + regexp.QuoteMeta(prefix), Options: "i"}
+
+	fields := bson.A{}
+	for _, field := range services.TextFields {
+		fields = append(fields, bson.M{field.Name: pattern})
+	}
+	return bson.M{"$or": fields}
+}
+
+// find decodes every listing returned by a query
+func (r *HousingRepository) find(ctx context.Context, query bson.M, opts *options.FindOptions) ([]models.Housing, error) {
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var properties []models.Housing
+	if err = cursor.All(ctx, &properties); err != nil {
+		return nil, err
+	}
+
+	return properties, nil
+}
+
+// housingQuery translates a housing filter into a MongoDB query document
+func housingQuery(f services.HousingFilter) bson.M {
+	query := bson.M{}
+
+	if f.County != "" {
+		query["county"] = f.County
+	}
+	if f.Type != "" {
+		query["type"] = f.Type
+	}
+	if f.AgentID != nil {
+		query["agentId"] = *f.AgentID
+	}
+
+	addRange(query, "priceCents", f.PriceCents())
+	addRange(query, "bedrooms", f.Bedrooms)
+	addRange(query, "bathrooms", f.Bathrooms)
+	addRange(query, "year", f.Year)
+	addRange(query, "surface", f.Surface)
+
+	return query
+}
+
+// addRange adds $gte/$lte conditions on field for the set bounds of r
+func addRange(query bson.M, field string, r services.NumericRange) {
+	if !r.IsSet() {
+		return
+	}
+
+	cond := bson.M{}
+	if r.Min != nil {
+		cond["$gte"] = *r.Min
+	}
+	if r.Max != nil {
+		cond["$lte"] = *r.Max
+	}
+	query[field] = cond
+}
+
+// direction returns the MongoDB sort direction for the page order
+func direction(page services.PageRequest) int {
+	if page.Order == services.SortAsc {
+		return 1
+	}
+	return -1
+}
+
+// sortSpec sorts on the requested field, tie-breaking on _id so that
+// documents sharing a value always come back in the same order
+func sortSpec(page services.PageRequest) bson.D {
+	return bson.D{
+		{Key: sortFields[page.Sort], Value: direction(page)},
+		{Key: "_id", Value: direction(page)},
+	}
+}
+
+// afterCursor returns the filter selecting documents strictly after the cursor
+func afterCursor(page services.PageRequest, c *services.PageCursor) bson.M {
+	op := "$lt"
+	if page.Order == services.SortAsc {
+		op = "$gt"
+	}
+
+	// Cursors carry plain numbers; dates must be compared as dates
+	var value interface{} = c.Value
+	if page.Sort == services.SortNewest {
+		value = primitive.DateTime(int64(c.Value))
+	}
+
+	field := sortFields[page.Sort]
+	return bson.M{"$or": bson.A{
+		bson.M{field: bson.M{op: value}},
+		bson.M{field: value, "_id": bson.M{op: c.ID}},
+	}}
+}
+
+// polygon returns the box as a closed GeoJSON polygon
+func polygon(b services.BoundingBox) bson.M {
+	sw, ne := b.SouthWest, b.NorthEast
+	return bson.M{
+		"type": "Polygon",
+		"coordinates": bson.A{bson.A{
+			bson.A{sw.Lng, sw.Lat},
+			bson.A{ne.Lng, sw.Lat},
+			bson.A{ne.Lng, ne.Lat},
+			bson.A{sw.Lng, ne.Lat},
+			bson.A{sw.Lng, sw.Lat},
+		}},
+	}
+}
@@ -0,0 +1,102 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ListingAlertRepository stores listing alerts in a MongoDB collection
+type ListingAlertRepository struct {
+	collection *mongo.Collection
+}
+
+// NewListingAlertRepository creates a listing alert repository on collection
+func NewListingAlertRepository(collection *mongo.Collection) *ListingAlertRepository {
+	return &ListingAlertRepository{
+		collection: collection,
+	}
+}
+
+// EnsureIndexes creates the unique search and listing index, which also
+// serves per-search lookups, and the per-user lookup index
+func (r *ListingAlertRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "savedSearchId", Value: 1}, {Key: "propertyId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	return err
+}
+
+// Insert records alert unless the listing already alerted the same search
+func (r *ListingAlertRepository) Insert(ctx context.Context, alert models.ListingAlert) (bool, error) {
+	_, err := r.collection.InsertOne(ctx, alert)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// FindByUser returns up to limit of a user's alerts, newest first
+func (r *ListingAlertRepository) FindByUser(ctx context.Context, userID primitive.ObjectID, limit int) ([]models.ListingAlert, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+	return r.find(ctx, bson.M{"userId": userID}, opts)
+}
+
+// FindUndelivered returns the alerts of a search not yet sent, oldest first
+func (r *ListingAlertRepository) FindUndelivered(ctx context.Context, savedSearchID primitive.ObjectID) ([]models.ListingAlert, error) {
+	filter := bson.M{
+		"savedSearchId": savedSearchID,
+		"deliveredAt":   bson.M{"$exists": false},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	return r.find(ctx, filter, opts)
+}
+
+// MarkDelivered records that the alerts with ids were sent
+func (r *ListingAlertRepository) MarkDelivered(ctx context.Context, ids []primitive.ObjectID, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := r.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{
+		"$set": bson.M{"deliveredAt": primitive.NewDateTimeFromTime(at)},
+	})
+	return err
+}
+
+// DeleteBySearch removes every alert of a saved search
+func (r *ListingAlertRepository) DeleteBySearch(ctx context.Context, savedSearchID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"savedSearchId": savedSearchID})
+	return err
+}
+
+// find decodes the alerts matching filter
+func (r *ListingAlertRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.ListingAlert, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var alerts []models.ListingAlert
+	if err = cursor.All(ctx, &alerts); err != nil {
+		return nil, err
+	}
+
+	return alerts, nil
+}
@@ -0,0 +1,97 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LoginAttemptRepository stores failed login counts in a MongoDB
+// collection, keyed by email address
+type LoginAttemptRepository struct {
+	collection *mongo.Collection
+}
+
+// NewLoginAttemptRepository creates a login attempt repository on collection
+func NewLoginAttemptRepository(collection *mongo.Collection) *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		collection: collection,
+	}
+}
+
+// EnsureIndexes creates a TTL index that removes records once their
+// failures are forgotten
+func (r *LoginAttemptRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// Find returns the failed logins of email that are not yet forgotten. The
+// TTL monitor runs about once a minute, so expiry is checked here as well
+func (r *LoginAttemptRepository) Find(ctx context.Context, email string, now time.Time) (*models.LoginAttempts, error) {
+	filter := bson.M{"_id": email, "expiresAt": bson.M{"$gt": primitive.NewDateTimeFromTime(now)}}
+
+	var attempts models.LoginAttempts
+	if err := r.collection.FindOne(ctx, filter).Decode(&attempts); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, services.ErrNotFound
+		}
+		return nil, err
+	}
+	return &attempts, nil
+}
+
+// RecordFailure atomically counts a failed login, restarting the count and
+// dropping the lock when the previous failures are forgotten
+func (r *LoginAttemptRepository) RecordFailure(ctx context.Context, email string, at time.Time, window time.Duration) (*models.LoginAttempts, error) {
+	now := primitive.NewDateTimeFromTime(at)
+	remembered := bson.M{"$gt": bson.A{"$expiresAt", now}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"failures":    bson.M{"$cond": bson.A{remembered, bson.M{"$add": bson.A{"$failures", 1}}, 1}},
+		"lockedUntil": bson.M{"$cond": bson.A{remembered, "$lockedUntil", "$$REMOVE"}},
+		"lastFailure": now,
+		"expiresAt":   primitive.NewDateTimeFromTime(at.Add(window)),
+	}}}}
+
+	var attempts models.LoginAttempts
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": email},
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempts)
+	if err != nil {
+		return nil, err
+	}
+	return &attempts, nil
+}
+
+// Lock refuses the logins of email until until
+func (r *LoginAttemptRepository) Lock(ctx context.Context, email string, until time.Time) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": email}, bson.M{
+		"$set": bson.M{"lockedUntil": primitive.NewDateTimeFromTime(until)},
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return services.ErrNotFound
+	}
+	return nil
+}
+
+// Clear forgets the failed logins of email
+func (r *LoginAttemptRepository) Clear(ctx context.Context, email string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": email})
+	return err
+}
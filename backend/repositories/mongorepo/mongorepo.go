@@ -0,0 +1,41 @@
+// Package mongorepo implements the service repositories on MongoDB
+package mongorepo
+
+import (
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Compile-time checks that every repository satisfies its interface
+var (
+	_ services.HousingRepository      = (*HousingRepository)(nil)
+	_ services.UserRepository         = (*UserRepository)(nil)
+	_ services.RequestRepository      = (*RequestRepository)(nil)
+	_ services.SessionRepository      = (*SessionRepository)(nil)
+	_ services.AccountTokenRepository = (*AccountTokenRepository)(nil)
+	_ services.FavoriteRepository     = (*FavoriteRepository)(nil)
+	_ services.SavedSearchRepository  = (*SavedSearchRepository)(nil)
+	_ services.ListingAlertRepository = (*ListingAlertRepository)(nil)
+	_ services.PriceHistoryRepository = (*PriceHistoryRepository)(nil)
+	_ services.AvailabilityRepository = (*AvailabilityRepository)(nil)
+	_ services.TourRepository         = (*TourRepository)(nil)
+	_ services.LoginAttemptRepository = (*LoginAttemptRepository)(nil)
+)
+
+// NewRepositories returns MongoDB-backed repositories stored in db
+func NewRepositories(db *mongo.Database) services.Repositories {
+	return services.Repositories{
+		Housing:       NewHousingRepository(db.Collection("housing")),
+		Users:         NewUserRepository(db.Collection("users")),
+		Requests:      NewRequestRepository(db.Collection("propertyRequests")),
+		Sessions:      NewSessionRepository(db.Collection("sessions")),
+		AccountTokens: NewAccountTokenRepository(db.Collection("accountTokens")),
+		LoginAttempts: NewLoginAttemptRepository(db.Collection("loginAttempts")),
+		Favorites:     NewFavoriteRepository(db.Collection("favorites")),
+		SavedSearches: NewSavedSearchRepository(db.Collection("savedSearches")),
+		Alerts:        NewListingAlertRepository(db.Collection("listingAlerts")),
+		PriceHistory:  NewPriceHistoryRepository(db.Collection("priceHistory")),
+		Availability:  NewAvailabilityRepository(db.Collection("tourAvailability")),
+		Tours:         NewTourRepository(db.Collection("tours")),
+	}
+}
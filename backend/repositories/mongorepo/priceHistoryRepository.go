@@ -0,0 +1,77 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PriceHistoryRepository stores listing price changes in a MongoDB collection
+type PriceHistoryRepository struct {
+	collection *mongo.Collection
+}
+
+// NewPriceHistoryRepository creates a price history repository on collection
+func NewPriceHistoryRepository(collection *mongo.Collection) *PriceHistoryRepository {
+	return &PriceHistoryRepository{
+		collection: collection,
+	}
+}
+
+// EnsureIndexes creates the per-listing lookup index
+func (r *PriceHistoryRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "propertyId", Value: 1}, {Key: "changedAt", Value: -1}},
+	})
+	return err
+}
+
+// Insert adds a price change
+func (r *PriceHistoryRepository) Insert(ctx context.Context, change models.PriceChange) error {
+	_, err := r.collection.InsertOne(ctx, change)
+	return err
+}
+
+// FindByProperty returns the price changes of a listing, newest first
+func (r *PriceHistoryRepository) FindByProperty(ctx context.Context, propertyID primitive.ObjectID) ([]models.PriceChange, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "changedAt", Value: -1}, {Key: "_id", Value: -1}})
+	return r.find(ctx, bson.M{"propertyId": propertyID}, opts)
+}
+
+// FindSince returns the price changes of the given listings made at or after
+// since, oldest first
+func (r *PriceHistoryRepository) FindSince(ctx context.Context, propertyIDs []primitive.ObjectID, since time.Time) ([]models.PriceChange, error) {
+	filter := bson.M{
+		"propertyId": bson.M{"$in": propertyIDs},
+		"changedAt":  bson.M{"$gte": primitive.NewDateTimeFromTime(since)},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "changedAt", Value: 1}, {Key: "_id", Value: 1}})
+	return r.find(ctx, filter, opts)
+}
+
+// DeleteByProperty removes the price history of a listing
+func (r *PriceHistoryRepository) DeleteByProperty(ctx context.Context, propertyID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"propertyId": propertyID})
+	return err
+}
+
+// find decodes the price changes matching filter
+func (r *PriceHistoryRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.PriceChange, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var changes []models.PriceChange
+	if err = cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,145 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RequestRepository stores property requests in a MongoDB collection
+type RequestRepository struct {
+	collection *mongo.Collection
+}
+
+// NewRequestRepository creates a request repository on collection
+func NewRequestRepository(collection *mongo.Collection) *RequestRepository {
+	return &RequestRepository{
+		collection: collection,
+	}
+}
+
+// EnsureIndexes creates the per-user and per-listing lookup indexes
+func (r *RequestRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "propertyId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	return err
+}
+
+// Insert adds a new request
+func (r *RequestRepository) Insert(ctx context.Context, request models.PropertyRequest) error {
+	_, err := r.collection.InsertOne(ctx, request)
+	return err
+}
+
+// FindByID returns the request with the given ID
+func (r *RequestRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.PropertyRequest, error) {
+	var request models.PropertyRequest
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&request)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, services.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// FindByUser returns a user's requests, newest first
+func (r *RequestRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.PropertyRequest, error) {
+	return r.find(ctx, bson.M{"userId": userID})
+}
+
+// FindAll returns every request, newest first
+func (r *RequestRepository) FindAll(ctx context.Context) ([]models.PropertyRequest, error) {
+	return r.find(ctx, bson.M{})
+}
+
+// FindByProperties returns the requests for any of the listings, newest first
+func (r *RequestRepository) FindByProperties(ctx context.Context, propertyIDs []primitive.ObjectID) ([]models.PropertyRequest, error) {
+	if len(propertyIDs) == 0 {
+		return nil, nil
+	}
+	return r.find(ctx, bson.M{"propertyId": bson.M{"$in": propertyIDs}})
+}
+
+// Transition applies a status change while the request is still in change.From
+func (r *RequestRepository) Transition(ctx context.Context, id primitive.ObjectID, change models.RequestStatusChange, rejectionReason string) error {
+	set := bson.M{
+		"status":      change.To,
+		"updatedAt":   change.ChangedAt,
+		"processedBy": change.ChangedBy,
+	}
+	if change.To == models.StatusRejected {
+		set["rejectionReason"] = rejectionReason
+	}
+	update := bson.M{
+		"$set":  set,
+		"$push": bson.M{"history": change},
+	}
+
+	return r.updateInStatus(ctx, id, change.From, update)
+}
+
+// UpdateMessage changes the message while the request has status
+func (r *RequestRepository) UpdateMessage(ctx context.Context, id primitive.ObjectID, status string, message string, at time.Time) error {
+	update := bson.M{
+		"$set": bson.M{
+			"message":   message,
+			"updatedAt": primitive.NewDateTimeFromTime(at),
+		},
+	}
+
+	return r.updateInStatus(ctx, id, status, update)
+}
+
+// Delete removes a request
+func (r *RequestRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return services.ErrNotFound
+	}
+	return nil
+}
+
+// updateInStatus applies update only if the request still has status
+func (r *RequestRepository) updateInStatus(ctx context.Context, id primitive.ObjectID, status string, update bson.M) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "status": status}, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return services.ErrNotFound
+	}
+	return nil
+}
+
+// find decodes the requests matching filter, newest first
+func (r *RequestRepository) find(ctx context.Context, filter bson.M) ([]models.PropertyRequest, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var requests []models.PropertyRequest
+	if err = cursor.All(ctx, &requests); err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
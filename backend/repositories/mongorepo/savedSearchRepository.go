@@ -0,0 +1,121 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SavedSearchRepository stores saved searches in a MongoDB collection
+type SavedSearchRepository struct {
+	collection *mongo.Collection
+}
+
+// NewSavedSearchRepository creates a saved search repository on collection
+func NewSavedSearchRepository(collection *mongo.Collection) *SavedSearchRepository {
+	return &SavedSearchRepository{
+		collection: collection,
+	}
+}
+
+// EnsureIndexes creates the per-user lookup index
+func (r *SavedSearchRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}},
+	})
+	return err
+}
+
+// Insert adds a new saved search
+func (r *SavedSearchRepository) Insert(ctx context.Context, search models.SavedSearch) error {
+	_, err := r.collection.InsertOne(ctx, search)
+	return err
+}
+
+// FindByID returns the saved search with the given ID
+func (r *SavedSearchRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.SavedSearch, error) {
+	var search models.SavedSearch
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&search)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, services.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &search, nil
+}
+
+// FindByUser returns a user's saved searches, newest first
+func (r *SavedSearchRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.SavedSearch, error) {
+	return r.find(ctx, bson.M{"userId": userID})
+}
+
+// FindAll returns every saved search
+func (r *SavedSearchRepository) FindAll(ctx context.Context) ([]models.SavedSearch, error) {
+	return r.find(ctx, bson.M{})
+}
+
+// Replace overwrites a saved search
+func (r *SavedSearchRepository) Replace(ctx context.Context, search models.SavedSearch) error {
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": search.ID}, search)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return services.ErrNotFound
+	}
+	return nil
+}
+
+// SetLastNotified records when the user was last alerted about a search
+func (r *SavedSearchRepository) SetLastNotified(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"lastNotifiedAt": primitive.NewDateTimeFromTime(at)},
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return services.ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a saved search
+func (r *SavedSearchRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return services.ErrNotFound
+	}
+	return nil
+}
+
+// find decodes the saved searches matching filter, newest first
+func (r *SavedSearchRepository) find(ctx context.Context, filter bson.M) ([]models.SavedSearch, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var searches []models.SavedSearch
+	if err = cursor.All(ctx, &searches); err != nil {
+		return nil, err
+	}
+
+	return searches, nil
+}
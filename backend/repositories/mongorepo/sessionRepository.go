@@ -0,0 +1,116 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SessionRepository stores login sessions in a MongoDB collection
+type SessionRepository struct {
+	collection *mongo.Collection
+}
+
+// NewSessionRepository creates a session repository on collection
+func NewSessionRepository(collection *mongo.Collection) *SessionRepository {
+	return &SessionRepository{
+		collection: collection,
+	}
+}
+
+// EnsureIndexes creates the token lookup indexes and a TTL index that
+// removes sessions once they expire
+func (r *SessionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "refreshTokenHash", Value: 1}}},
+		{Keys: bson.D{{Key: "previousTokenHashes", Value: 1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+// Insert adds a new session
+func (r *SessionRepository) Insert(ctx context.Context, session models.Session) error {
+	_, err := r.collection.InsertOne(ctx, session)
+	return err
+}
+
+// Rotate swaps the refresh token of the active session holding oldHash
+func (r *SessionRepository) Rotate(ctx context.Context, oldHash string, newHash string, now time.Time, expiresAt time.Time) (*models.Session, error) {
+	var session models.Session
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"refreshTokenHash": oldHash,
+			"revokedAt":        bson.M{"$exists": false},
+			"expiresAt":        bson.M{"$gt": primitive.NewDateTimeFromTime(now)},
+		},
+		bson.M{
+			"$set": bson.M{
+				"refreshTokenHash": newHash,
+				"lastUsedAt":       primitive.NewDateTimeFromTime(now),
+				"expiresAt":        primitive.NewDateTimeFromTime(expiresAt),
+			},
+			"$push": bson.M{"previousTokenHashes": oldHash},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, services.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// IsActive reports whether a session exists and has not been revoked or expired
+func (r *SessionRepository) IsActive(ctx context.Context, id primitive.ObjectID, now time.Time) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{
+		"_id":       id,
+		"revokedAt": bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": primitive.NewDateTimeFromTime(now)},
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Revoke marks the active sessions matching match as revoked
+func (r *SessionRepository) Revoke(ctx context.Context, match services.SessionMatch, reason string, now time.Time) (int64, error) {
+	filter := bson.M{"revokedAt": bson.M{"$exists": false}}
+	switch {
+	case match.ID != nil:
+		filter["_id"] = *match.ID
+	case match.UserID != nil:
+		filter["userId"] = *match.UserID
+	case match.RefreshTokenHash != "":
+		filter["refreshTokenHash"] = match.RefreshTokenHash
+	case match.PreviousTokenHash != "":
+		filter["previousTokenHashes"] = match.PreviousTokenHash
+	default:
+		return 0, nil
+	}
+
+	result, err := r.collection.UpdateMany(ctx, filter, bson.M{
+		"$set": bson.M{
+			"revokedAt":     primitive.NewDateTimeFromTime(now),
+			"revokedReason": reason,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,186 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TourRepository stores tours in a MongoDB collection
+type TourRepository struct {
+	collection *mongo.Collection
+}
+
+// NewTourRepository creates a tour repository on collection
+func NewTourRepository(collection *mongo.Collection) *TourRepository {
+	return &TourRepository{
+		collection: collection,
+	}
+}
+
+// EnsureIndexes creates the unique slot index over the booked tours of each
+// agent, which also serves availability lookups, and the per-listing,
+// per-user and per-agent lookup indexes. It drops the per-listing slot
+// index that let an agent be booked twice at once across listings
+func (r *TourRepository) EnsureIndexes(ctx context.Context) error {
+	if _, err := r.collection.Indexes().DropOne(ctx, "booked_slot"); err != nil && !isMissingIndex(err) {
+		return err
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "agentKey", Value: 1}, {Key: "start", Value: 1}},
+			Options: options.Index().
+				SetName("agent_booked_slot").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"status": models.TourBooked}),
+		},
+		{Keys: bson.D{{Key: "propertyId", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "start", Value: -1}}},
+		{Keys: bson.D{{Key: "agentKey", Value: 1}, {Key: "start", Value: 1}}},
+	})
+	return err
+}
+
+// Insert stores a booked tour, or returns ErrConflict when its slot is taken
+func (r *TourRepository) Insert(ctx context.Context, tour models.Tour) error {
+	_, err := r.collection.InsertOne(ctx, tour)
+	if mongo.IsDuplicateKeyError(err) {
+		return services.ErrConflict
+	}
+	return err
+}
+
+// FindByID returns the tour with the given ID
+func (r *TourRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Tour, error) {
+	var tour models.Tour
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&tour)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, services.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &tour, nil
+}
+
+// FindByUser returns a user's tours, latest start first
+func (r *TourRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Tour, error) {
+	return r.find(ctx, bson.M{"userId": userID}, -1)
+}
+
+// FindAll returns every tour, latest start first
+func (r *TourRepository) FindAll(ctx context.Context) ([]models.Tour, error) {
+	return r.find(ctx, bson.M{}, -1)
+}
+
+// FindByAgent returns the tours of an agent starting at or after since, in
+// start order
+func (r *TourRepository) FindByAgent(ctx context.Context, agentKey string, since time.Time) ([]models.Tour, error) {
+	return r.find(ctx, bson.M{
+		"agentKey": agentKey,
+		"start":    bson.M{"$gte": primitive.NewDateTimeFromTime(since)},
+	}, 1)
+}
+
+// FindBooked returns the booked tours of an agent that overlap the period
+// from from to to, in start order
+func (r *TourRepository) FindBooked(ctx context.Context, agentKey string, from time.Time, to time.Time) ([]models.Tour, error) {
+	return r.find(ctx, bson.M{
+		"agentKey": agentKey,
+		"status":   models.TourBooked,
+		"start":    bson.M{"$lt": primitive.NewDateTimeFromTime(to)},
+		"end":      bson.M{"$gt": primitive.NewDateTimeFromTime(from)},
+	}, 1)
+}
+
+// Reschedule moves a booked tour to another slot. The unique slot index
+// rejects the update when the slot is taken
+func (r *TourRepository) Reschedule(ctx context.Context, id primitive.ObjectID, start time.Time, end time.Time, at time.Time) (*models.Tour, error) {
+	return r.update(ctx, id, bson.M{
+		"$set": bson.M{
+			"start":     primitive.NewDateTimeFromTime(start),
+			"end":       primitive.NewDateTimeFromTime(end),
+			"updatedAt": primitive.NewDateTimeFromTime(at),
+		},
+		"$inc": bson.M{"sequence": 1},
+	})
+}
+
+// Cancel cancels a booked tour
+func (r *TourRepository) Cancel(ctx context.Context, id primitive.ObjectID, at time.Time) (*models.Tour, error) {
+	return r.update(ctx, id, cancelUpdate(at))
+}
+
+// CancelByProperty cancels every booked tour of a listing
+func (r *TourRepository) CancelByProperty(ctx context.Context, propertyID primitive.ObjectID, at time.Time) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{"propertyId": propertyID, "status": models.TourBooked}, cancelUpdate(at))
+	return err
+}
+
+// isMissingIndex reports whether err says an index or its collection does
+// not exist
+func isMissingIndex(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 26 || cmdErr.Code == 27)
+}
+
+// cancelUpdate is the update that cancels a tour at the given time
+func cancelUpdate(at time.Time) bson.M {
+	now := primitive.NewDateTimeFromTime(at)
+	return bson.M{
+		"$set": bson.M{
+			"status":      models.TourCancelled,
+			"cancelledAt": now,
+			"updatedAt":   now,
+		},
+		"$inc": bson.M{"sequence": 1},
+	}
+}
+
+// update applies update to a booked tour and returns the result
+func (r *TourRepository) update(ctx context.Context, id primitive.ObjectID, update bson.M) (*models.Tour, error) {
+	var tour models.Tour
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id, "status": models.TourBooked},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&tour)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, services.ErrNotFound
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, services.ErrConflict
+		}
+		return nil, err
+	}
+
+	return &tour, nil
+}
+
+// find decodes the tours matching filter sorted by start in direction
+func (r *TourRepository) find(ctx context.Context, filter bson.M, direction int) ([]models.Tour, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "start", Value: direction}, {Key: "_id", Value: direction}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tours []models.Tour
+	if err = cursor.All(ctx, &tours); err != nil {
+		return nil, err
+	}
+
+	return tours, nil
+}
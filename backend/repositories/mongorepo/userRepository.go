@@ -0,0 +1,166 @@
+package mongorepo
+
+import (
+	"context"
+	"regexp"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserRepository stores user accounts in a MongoDB collection
+type UserRepository struct {
+	collection *mongo.Collection
+}
+
+// NewUserRepository creates a user repository on collection
+func NewUserRepository(collection *mongo.Collection) *UserRepository {
+	return &UserRepository{
+		collection: collection,
+	}
+}
+
+// EnsureIndexes creates the unique email index, which also serves lookups.
+// It drops the email index that let two registrations of one address race
+func (r *UserRepository) EnsureIndexes(ctx context.Context) error {
+	if _, err := r.collection.Indexes().DropOne(ctx, "email_1"); err != nil && !isMissingIndex(err) {
+		return err
+	}
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetName("email_unique").SetUnique(true),
+	})
+	return err
+}
+
+// Insert adds a new user, or returns ErrConflict when its email is taken
+func (r *UserRepository) Insert(ctx context.Context, user models.Users) error {
+	user.Email = models.NormalizeEmail(user.Email)
+	_, err := r.collection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return services.ErrConflict
+	}
+	return err
+}
+
+// FindByID returns the user with the given ID
+func (r *UserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Users, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+// FindByEmail returns the user registered with email
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.Users, error) {
+	return r.findOne(ctx, bson.M{"email": models.NormalizeEmail(email)})
+}
+
+// List returns a page of users matching query, newest first
+func (r *UserRepository) List(ctx context.Context, query services.UserQuery) ([]models.Users, int64, error) {
+	filter := bson.M{}
+	if query.Search != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query.Search), Options: "i"}
+		filter["$or"] = bson.A{
+			bson.M{"firstName": pattern},
+			bson.M{"lastName": pattern},
+			bson.M{"email": pattern},
+		}
+	}
+	if query.Role != "" {
+		filter["role"] = query.Role
+	}
+	if query.Disabled != nil {
+		if *query.Disabled {
+			filter["disabled"] = true
+		} else {
+			filter["disabled"] = bson.M{"$ne": true}
+		}
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// ObjectIDs embed their creation time, so _id orders by registration
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetSkip(int64((query.Page - 1) * query.Limit)).
+		SetLimit(int64(query.Limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.Users
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// Update applies patch to a user and returns the updated document, or
+// returns ErrConflict when the new email is taken
+func (r *UserRepository) Update(ctx context.Context, id primitive.ObjectID, patch services.UserPatch) (*models.Users, error) {
+	set := bson.M{}
+	if patch.Email != nil {
+		set["email"] = models.NormalizeEmail(*patch.Email)
+	}
+	if patch.Role != nil {
+		set["role"] = *patch.Role
+	}
+	if patch.Password != nil {
+		set["password"] = *patch.Password
+	}
+	if patch.Disabled != nil {
+		set["disabled"] = *patch.Disabled
+	}
+	if patch.PasswordResetRequired != nil {
+		set["passwordResetRequired"] = *patch.PasswordResetRequired
+	}
+	if patch.EmailVerified != nil {
+		set["emailVerified"] = *patch.EmailVerified
+	}
+	if len(set) == 0 {
+		return r.FindByID(ctx, id)
+	}
+
+	var user models.Users
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, services.ErrNotFound
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, services.ErrConflict
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// findOne decodes the single user matching filter
+func (r *UserRepository) findOne(ctx context.Context, filter bson.M) (*models.Users, error) {
+	var user models.Users
+	err := r.collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, services.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
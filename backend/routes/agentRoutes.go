@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"net/http"
+
+	"gatorswamp/authz"
+	"gatorswamp/controllers"
+	"gatorswamp/middlewares"
+	"gatorswamp/services"
+	"github.com/gorilla/mux"
+)
+
+// SetupAgentRoutes initializes the routes of the agent dashboard
+func SetupAgentRoutes(router *mux.Router, svc *services.Services) {
+	// Initialize controllers
+	agentController := controllers.NewAgentController(svc.Housing, svc.Requests)
+
+	// Create auth middleware with the user service
+	authMiddleware := middlewares.AuthMiddleware(svc.Users)
+
+	// The dashboard shows the listings the user writes as their agent
+	canWrite := middlewares.RequirePermission(authz.HousingWrite)
+
+	router.Handle("/listings", authMiddleware(canWrite(http.HandlerFunc(agentController.GetMyListings)))).Methods("GET")
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"net/http"
+
+	"gatorswamp/controllers"
+	"gatorswamp/middlewares"
+	"gatorswamp/services"
+	"github.com/gorilla/mux"
+)
+
+// SetupFavoriteRoutes initializes the routes of the user's saved listings
+func SetupFavoriteRoutes(router *mux.Router, svc *services.Services) {
+	// Initialize controllers
+	favoriteController := controllers.NewFavoriteController(svc.Favorites, svc.Housing)
+
+	// Create auth middleware with the user service
+	authMiddleware := middlewares.AuthMiddleware(svc.Users)
+
+	// All favorite routes require authentication
+	router.Handle("", authMiddleware(http.HandlerFunc(favoriteController.GetMyFavorites))).Methods("GET")
+	router.Handle("/{propertyId}", authMiddleware(http.HandlerFunc(favoriteController.AddFavorite))).Methods("PUT")
+	router.Handle("/{propertyId}", authMiddleware(http.HandlerFunc(favoriteController.RemoveFavorite))).Methods("DELETE")
+}
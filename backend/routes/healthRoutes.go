@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"gatorswamp/controllers"
+	"github.com/gorilla/mux"
+)
+
+// SetupHealthRoutes registers the liveness and readiness probes
+func SetupHealthRoutes(router *mux.Router, health *controllers.HealthController) {
+	router.HandleFunc("/healthz", health.Healthz).Methods("GET", "HEAD")
+	router.HandleFunc("/readyz", health.Readyz).Methods("GET", "HEAD")
+}
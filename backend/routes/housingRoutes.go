@@ -3,30 +3,55 @@ package routes
 import (
 	"net/http"
 
+	"gatorswamp/authz"
 	"gatorswamp/controllers"
 	"gatorswamp/middlewares"
+	"gatorswamp/services"
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // SetupHousingRoutes initializes all housing-related routes
-func SetupHousingRoutes(router *mux.Router, db *mongo.Database) {
-	// Initialize collections
-	housingCollection := db.Collection("housing")
-
+func SetupHousingRoutes(router *mux.Router, svc *services.Services) {
 	// Initialize controllers
-	housingController := controllers.NewHousingController(housingCollection)
+	housingController := controllers.NewHousingController(svc.Housing)
+	favoriteController := controllers.NewFavoriteController(svc.Favorites, svc.Housing)
+	tourController := controllers.NewTourController(svc.Tours, svc.Housing)
+
+	// Create auth middleware with the user service
+	authMiddleware := middlewares.AuthMiddleware(svc.Users)
 
-	// Create auth middleware with the user collection
-	userCollection := db.Collection("users")
-	authMiddleware := middlewares.AuthMiddleware(userCollection)
+	// Permission checks, which run after authentication
+	canWrite := middlewares.RequirePermission(authz.HousingWrite)
+	canDelete := middlewares.RequirePermission(authz.HousingDelete)
+	canAssign := middlewares.RequirePermission(authz.HousingAssign)
+	canReadStats := middlewares.RequirePermission(authz.HousingStats)
 
 	// Public routes - no authentication required
 	router.HandleFunc("/all", housingController.GetAllHousing).Methods("GET")
+	router.HandleFunc("/search", housingController.SearchHousing).Methods("GET")
+	router.HandleFunc("/facets", housingController.GetHousingFacets).Methods("GET")
+	router.HandleFunc("/near", housingController.NearHousing).Methods("GET")
+	router.HandleFunc("/within", housingController.HousingInBounds).Methods("GET")
 	router.HandleFunc("/{id}", housingController.GetHousingByID).Methods("GET")
+	router.HandleFunc("/{id}/price-history", housingController.GetPriceHistory).Methods("GET")
+	router.HandleFunc("/{id}/availability", tourController.GetAvailability).Methods("GET")
+
+	// Listing management; the handlers check that agents manage the listing
+	router.Handle("/create", authMiddleware(canWrite(http.HandlerFunc(housingController.CreateHousing)))).Methods("POST")
+	router.Handle("/{id}", authMiddleware(canWrite(http.HandlerFunc(housingController.UpdateHousing)))).Methods("PUT")
+	router.Handle("/{id}", authMiddleware(canDelete(http.HandlerFunc(housingController.DeleteHousing)))).Methods("DELETE")
+	router.Handle("/{id}/agent", authMiddleware(canAssign(http.HandlerFunc(housingController.UnassignAgent)))).Methods("DELETE")
+
+	// Gallery management
+	router.Handle("/{id}/images", authMiddleware(canWrite(http.HandlerFunc(housingController.UploadHousingImages)))).Methods("POST")
+	router.Handle("/{id}/images", authMiddleware(canWrite(http.HandlerFunc(housingController.ArrangeHousingImages)))).Methods("PUT")
+	router.Handle("/{id}/images/{imageId}", authMiddleware(canWrite(http.HandlerFunc(housingController.DeleteHousingImage)))).Methods("DELETE")
+
+	// Listing statistics
+	router.Handle("/{id}/favorites/count", authMiddleware(canReadStats(http.HandlerFunc(favoriteController.GetFavoriteCount)))).Methods("GET")
 
-	// Protected routes for authenticated users
-	router.Handle("/create", authMiddleware(http.HandlerFunc(housingController.CreateHousing))).Methods("POST")
-	router.Handle("/{id}", authMiddleware(http.HandlerFunc(housingController.UpdateHousing))).Methods("PUT")
-	router.Handle("/{id}", authMiddleware(http.HandlerFunc(housingController.DeleteHousing))).Methods("DELETE")
+	// Tour availability is published by the listing's agent or by admins on their behalf
+	router.Handle("/{id}/availability", authMiddleware(canWrite(http.HandlerFunc(tourController.AddAvailability)))).Methods("POST")
+	router.Handle("/{id}/availability/{windowId}", authMiddleware(canWrite(http.HandlerFunc(tourController.DeleteAvailability)))).Methods("DELETE")
+	router.Handle("/{id}/tour-feed", authMiddleware(canWrite(http.HandlerFunc(tourController.GetAgentFeedURL)))).Methods("GET")
 }
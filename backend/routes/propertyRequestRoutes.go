@@ -3,27 +3,29 @@ package routes
 import (
 	"net/http"
 
+	"gatorswamp/authz"
 	"gatorswamp/controllers"
 	"gatorswamp/middlewares"
+	"gatorswamp/services"
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // SetupRequestRoutes initializes all property request-related routes
-func SetupRequestRoutes(router *mux.Router, db *mongo.Database) {
-	// Initialize collections
-	requestCollection := db.Collection("propertyRequests")
-	housingCollection := db.Collection("housing")
-
+func SetupRequestRoutes(router *mux.Router, svc *services.Services) {
 	// Initialize controllers
-	requestController := controllers.NewPropertyRequestController(requestCollection, housingCollection)
+	requestController := controllers.NewPropertyRequestController(svc.Requests, svc.Users, svc.Housing)
+
+	// Create auth middleware with the user service
+	authMiddleware := middlewares.AuthMiddleware(svc.Users)
 
-	// Create auth middleware with the user collection
-	userCollection := db.Collection("users")
-	authMiddleware := middlewares.AuthMiddleware(userCollection)
+	// Permission checks, which run after authentication
+	canCreate := middlewares.RequirePermission(authz.RequestsCreate)
+	canReview := middlewares.RequirePermission(authz.RequestsReview)
 
 	// All request routes require authentication
-	router.Handle("/create", authMiddleware(http.HandlerFunc(requestController.CreateRequest))).Methods("POST")
+	router.Handle("/create", authMiddleware(canCreate(http.HandlerFunc(requestController.CreateRequest)))).Methods("POST")
 	router.Handle("/my-requests", authMiddleware(http.HandlerFunc(requestController.GetMyRequests))).Methods("GET")
-	router.Handle("/{id}/status", authMiddleware(http.HandlerFunc(requestController.UpdateRequestStatus))).Methods("PUT")
+	router.Handle("/{id}", authMiddleware(http.HandlerFunc(requestController.UpdateRequest))).Methods("PUT")
+	router.Handle("/{id}", authMiddleware(http.HandlerFunc(requestController.DeleteRequest))).Methods("DELETE")
+	router.Handle("/{id}/status", authMiddleware(canReview(http.HandlerFunc(requestController.UpdateRequestStatus)))).Methods("PUT")
 }
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"net/http"
+
+	"gatorswamp/controllers"
+	"gatorswamp/middlewares"
+	"gatorswamp/services"
+	"github.com/gorilla/mux"
+)
+
+// SetupSavedSearchRoutes initializes the routes of the user's saved searches
+// and their alerts
+func SetupSavedSearchRoutes(router *mux.Router, svc *services.Services) {
+	// Initialize controllers
+	savedSearchController := controllers.NewSavedSearchController(svc.SavedSearches, svc.Housing)
+
+	// Create auth middleware with the user service
+	authMiddleware := middlewares.AuthMiddleware(svc.Users)
+
+	// All saved search routes require authentication
+	router.Handle("", authMiddleware(http.HandlerFunc(savedSearchController.GetMySavedSearches))).Methods("GET")
+	router.Handle("", authMiddleware(http.HandlerFunc(savedSearchController.CreateSavedSearch))).Methods("POST")
+	router.Handle("/alerts", authMiddleware(http.HandlerFunc(savedSearchController.GetMyAlerts))).Methods("GET")
+	router.Handle("/{id}", authMiddleware(http.HandlerFunc(savedSearchController.UpdateSavedSearch))).Methods("PUT")
+	router.Handle("/{id}", authMiddleware(http.HandlerFunc(savedSearchController.DeleteSavedSearch))).Methods("DELETE")
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"net/http"
+
+	"gatorswamp/authz"
+	"gatorswamp/controllers"
+	"gatorswamp/middlewares"
+	"gatorswamp/services"
+	"github.com/gorilla/mux"
+)
+
+// SetupTourRoutes initializes the routes of booked tours and the agents'
+// tour calendars
+func SetupTourRoutes(router *mux.Router, svc *services.Services) {
+	// Initialize controllers
+	tourController := controllers.NewTourController(svc.Tours, svc.Housing)
+
+	// Create auth middleware with the user service
+	authMiddleware := middlewares.AuthMiddleware(svc.Users)
+	canBook := middlewares.RequirePermission(authz.ToursBook)
+
+	// Calendar feeds are authorized by the signed token in their URL
+	router.HandleFunc("/feed/{agentKey}.ics", tourController.GetAgentFeed).Methods("GET")
+
+	// Protected routes for authenticated users
+	router.Handle("", authMiddleware(http.HandlerFunc(tourController.GetMyTours))).Methods("GET")
+	router.Handle("", authMiddleware(canBook(http.HandlerFunc(tourController.BookTour)))).Methods("POST")
+	router.Handle("/{id}", authMiddleware(http.HandlerFunc(tourController.RescheduleTour))).Methods("PUT")
+	router.Handle("/{id}", authMiddleware(http.HandlerFunc(tourController.CancelTour))).Methods("DELETE")
+	router.Handle("/{id}/calendar.ics", authMiddleware(http.HandlerFunc(tourController.DownloadTour))).Methods("GET")
+}
@@ -2,64 +2,90 @@ package routes
 
 import (
 	"net/http"
+	"time"
 
+	"gatorswamp/authz"
 	"gatorswamp/controllers"
 	"gatorswamp/middlewares"
+	"gatorswamp/ratelimit"
+	"gatorswamp/services"
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// AdminMiddleware checks if the user has admin role
-func AdminMiddleware(userCollection *mongo.Collection) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// First apply auth middleware to get the user
-			authMiddleware := middlewares.AuthMiddleware(userCollection)
-
-			// Create a handler that checks the user role
-			roleCheckHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				user, ok := middlewares.GetUserFromContext(r.Context())
-				if !ok || user.Role != "admin" {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusForbidden)
-					w.Write([]byte(`{"error":"Admin access required"}`))
-					return
-				}
-
-				// If user is admin, proceed to next handler
-				next.ServeHTTP(w, r)
-			})
-
-			// Apply auth middleware followed by role check
-			authMiddleware(roleCheckHandler).ServeHTTP(w, r)
-		})
-	}
-}
+// Rate limits of the sign-in, sign-up and account email routes
+var (
+	// loginIPLimit allows bursts of 20 logins per address, then one every 3s
+	loginIPLimit = ratelimit.Limit{Burst: 20, Interval: 3 * time.Second}
+	// loginEmailLimit allows bursts of 10 logins per account, then one every 30s
+	loginEmailLimit = ratelimit.Limit{Burst: 10, Interval: 30 * time.Second}
+	// registerIPLimit allows bursts of 5 sign-ups per address, then one every 5m
+	registerIPLimit = ratelimit.Limit{Burst: 5, Interval: 5 * time.Minute}
+	// mailIPLimit allows bursts of 5 reset or verification emails per
+	// address, then one every minute
+	mailIPLimit = ratelimit.Limit{Burst: 5, Interval: time.Minute}
+	// mailEmailLimit allows bursts of 3 reset or verification emails to an
+	// email address, then one every 10m
+	mailEmailLimit = ratelimit.Limit{Burst: 3, Interval: 10 * time.Minute}
+	// tokenIPLimit allows bursts of 10 password reset or email verification
+	// tokens per address, then one every 30s
+	tokenIPLimit = ratelimit.Limit{Burst: 10, Interval: 30 * time.Second}
+	// refreshIPLimit allows bursts of 30 token refreshes per address, then one every 2s
+	refreshIPLimit = ratelimit.Limit{Burst: 30, Interval: 2 * time.Second}
+)
 
 // SetupUserRoutes initializes all user-related routes
-func SetupUserRoutes(router *mux.Router, db *mongo.Database) {
+func SetupUserRoutes(router *mux.Router, svc *services.Services) {
 	// Initialize controllers
-	userCollection := db.Collection("users")
-	userController := controllers.NewUserController(userCollection)
+	userController := controllers.NewUserController(svc.Users)
+
+	// Create auth middleware with the user service
+	authMiddleware := middlewares.AuthMiddleware(svc.Users)
+
+	// Admin routes need the user management permission
+	canManageUsers := middlewares.RequirePermission(authz.UsersManage)
 
-	// Create auth middleware with the user collection
-	authMiddleware := middlewares.AuthMiddleware(userCollection)
+	// Sign-in and sign-up are rate limited by client address, and sign-in
+	// also by account so that spreading attempts over addresses does not help
+	loginByIP := middlewares.RateLimit(svc.RateLimits, "login-ip", loginIPLimit, middlewares.ClientIP)
+	loginByEmail := middlewares.RateLimit(svc.RateLimits, "login-email", loginEmailLimit, middlewares.BodyEmail)
+	registerByIP := middlewares.RateLimit(svc.RateLimits, "register-ip", registerIPLimit, middlewares.ClientIP)
 
-	// Create admin middleware (combines auth check and role check)
-	adminMiddleware := AdminMiddleware(userCollection)
+	// Routes that send email are limited by client address and by the
+	// address mailed, which shares one bucket across the routes so that
+	// nobody can flood an inbox
+	mailByIP := middlewares.RateLimit(svc.RateLimits, "mail-ip", mailIPLimit, middlewares.ClientIP)
+	mailByBodyEmail := middlewares.RateLimit(svc.RateLimits, "mail-email", mailEmailLimit, middlewares.BodyEmail)
+	mailByUserEmail := middlewares.RateLimit(svc.RateLimits, "mail-email", mailEmailLimit, middlewares.UserEmail)
+	tokenByIP := middlewares.RateLimit(svc.RateLimits, "token-ip", tokenIPLimit, middlewares.ClientIP)
+	refreshByIP := middlewares.RateLimit(svc.RateLimits, "refresh-ip", refreshIPLimit, middlewares.ClientIP)
 
 	// Public routes - no authentication required
-	router.HandleFunc("/login", userController.LoginUser).Methods("POST")
-	router.HandleFunc("/register", userController.RegisterUser).Methods("POST")
+	router.Handle("/login", loginByIP(loginByEmail(http.HandlerFunc(userController.LoginUser)))).Methods("POST")
+	router.Handle("/register", registerByIP(http.HandlerFunc(userController.RegisterUser))).Methods("POST")
 	router.HandleFunc("/logout", userController.LogoutUser).Methods("POST")
+	router.Handle("/refresh", refreshByIP(http.HandlerFunc(userController.RefreshToken))).Methods("POST")
+	router.Handle("/password/forgot", mailByIP(mailByBodyEmail(http.HandlerFunc(userController.ForgotPassword)))).Methods("POST")
+	router.Handle("/password/reset", tokenByIP(http.HandlerFunc(userController.ResetPassword))).Methods("POST")
+	router.Handle("/verify-email", tokenByIP(http.HandlerFunc(userController.VerifyEmail))).Methods("POST")
 
 	// Protected routes - require authentication
 	router.Handle("/auth/status", authMiddleware(http.HandlerFunc(userController.GetAuthStatus))).Methods("GET")
 	router.Handle("/profile", authMiddleware(http.HandlerFunc(userController.GetMyProfile))).Methods("GET")
+	router.Handle("/verify-email/resend", mailByIP(authMiddleware(mailByUserEmail(http.HandlerFunc(userController.ResendVerificationEmail))))).Methods("POST")
+	router.Handle("/logout-all", authMiddleware(http.HandlerFunc(userController.LogoutAllDevices))).Methods("POST")
 
-	// Admin routes - require admin role
+	// Admin routes - authenticate once, then check the permission
 	adminRouter := router.PathPrefix("/admin").Subrouter()
-	adminRouter.Use(adminMiddleware)
+	adminRouter.Use(authMiddleware, canManageUsers)
 
-	// Admin-specific routes will go here when needed
+	// Admin user management
+	adminUserController := controllers.NewAdminUserController(svc.Users, svc.Requests, svc.Housing)
+	adminRouter.HandleFunc("/users", adminUserController.ListUsers).Methods("GET")
+	adminRouter.HandleFunc("/users/{id}", adminUserController.GetUser).Methods("GET")
+	adminRouter.HandleFunc("/users/{id}/role", adminUserController.UpdateUserRole).Methods("PUT")
+	adminRouter.HandleFunc("/users/{id}/disable", adminUserController.DisableUser).Methods("POST")
+	adminRouter.HandleFunc("/users/{id}/enable", adminUserController.EnableUser).Methods("POST")
+	adminRouter.HandleFunc("/users/{id}/force-password-reset", adminUserController.ForcePasswordReset).Methods("POST")
+	adminRouter.HandleFunc("/users/{id}/unlock", adminUserController.UnlockUser).Methods("POST")
+	adminRouter.HandleFunc("/users/{id}/claim", adminUserController.ClaimAgent).Methods("POST")
 }
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Account token lifetimes
+const (
+	PasswordResetTTL     = time.Hour
+	EmailVerificationTTL = 48 * time.Hour
+)
+
+// ErrInvalidAccountToken is returned for unknown, expired or already used tokens
+var ErrInvalidAccountToken = NewError(ErrValidation, "invalid_token", "invalid or expired token")
+
+// AccountTokenService issues and consumes single-use account tokens
+type AccountTokenService struct {
+	repo AccountTokenRepository
+}
+
+// NewAccountTokenService creates a new account token service
+func NewAccountTokenService(repo AccountTokenRepository) *AccountTokenService {
+	return &AccountTokenService{
+		repo: repo,
+	}
+}
+
+// Issue creates a new token for userID, invalidating any earlier unused
+// token with the same purpose. The plain token is returned once and never stored
+func (s *AccountTokenService) Issue(userID primitive.ObjectID, purpose string, ttl time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if err := s.repo.InvalidateUnused(ctx, userID, purpose, now); err != nil {
+		return "", err
+	}
+
+	err = s.repo.Insert(ctx, models.AccountToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		CreatedAt: primitive.NewDateTimeFromTime(now),
+		ExpiresAt: primitive.NewDateTimeFromTime(now.Add(ttl)),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Consume atomically marks a valid token as used and returns it
+func (s *AccountTokenService) Consume(token string, purpose string) (*models.AccountToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if token == "" {
+		return nil, ErrInvalidAccountToken
+	}
+
+	accountToken, err := s.repo.Consume(ctx, utils.HashToken(token), purpose, time.Now())
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrInvalidAccountToken
+		}
+		return nil, err
+	}
+
+	return accountToken, nil
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gatorswamp/config"
+	"gatorswamp/metrics"
+	"gatorswamp/models"
+	"gatorswamp/notifier"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// alertQueueSize bounds how many saved listings can wait to be matched
+const alertQueueSize = 256
+
+// maxAlertCheckInterval bounds how long undelivered alerts wait between
+// delivery attempts
+const maxAlertCheckInterval = time.Hour
+
+// AlertService matches created and updated listings against saved searches
+// in the background, records an alert for every match and delivers the
+// alerts instantly or as a periodic digest
+type AlertService struct {
+	searches       SavedSearchRepository
+	alerts         ListingAlertRepository
+	userService    *UserService
+	housingService *HousingService
+	notifier       notifier.Notifier
+	digestInterval time.Duration
+	queue          chan models.Housing
+}
+
+// NewAlertService creates a new alert service. Nothing is matched or
+// delivered until Run is called
+func NewAlertService(searches SavedSearchRepository, alerts ListingAlertRepository, userService *UserService, housingService *HousingService) *AlertService {
+	return &AlertService{
+		searches:       searches,
+		alerts:         alerts,
+		userService:    userService,
+		housingService: housingService,
+		notifier:       notifier.Default(),
+		digestInterval: config.Current().Alerts.DigestInterval,
+		queue:          make(chan models.Housing, alertQueueSize),
+	}
+}
+
+// ListingSaved queues a created or updated listing for matching without
+// blocking. The listing is dropped, logged and counted in
+// metrics.AlertsDropped when the queue is full
+func (s *AlertService) ListingSaved(property models.Housing) {
+	select {
+	case s.queue <- property:
+	default:
+		metrics.AlertsDropped.Inc()
+		log.Printf("Alert queue full, not matching property %s", property.ID.Hex())
+	}
+}
+
+// Run matches queued listings and delivers due alerts until ctx is done.
+// The listings still queued then are matched before it returns, so ctx
+// should be cancelled once no more listings are being saved
+func (s *AlertService) Run(ctx context.Context) {
+	ticker := time.NewTicker(min(s.digestInterval, maxAlertCheckInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.flushQueue()
+			return
+		case property := <-s.queue:
+			s.matchListings(s.drainQueue(property))
+		case now := <-ticker.C:
+			s.deliverDue(now)
+		}
+	}
+}
+
+// flushQueue matches every listing waiting in the queue
+func (s *AlertService) flushQueue() {
+	for {
+		select {
+		case property := <-s.queue:
+			s.matchListings(s.drainQueue(property))
+		default:
+			return
+		}
+	}
+}
+
+// drainQueue returns first followed by the listings already waiting in the
+// queue, keeping only the latest save of each listing
+func (s *AlertService) drainQueue(first models.Housing) []models.Housing {
+	batch := []models.Housing{first}
+	index := map[primitive.ObjectID]int{first.ID: 0}
+	for len(batch) < alertQueueSize {
+		select {
+		case property := <-s.queue:
+			if i, ok := index[property.ID]; ok {
+				batch[i] = property
+				continue
+			}
+			index[property.ID] = len(batch)
+			batch = append(batch, property)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// matchListings records an alert for every saved search each listing
+// matches that it has not alerted before, and delivers instant ones right
+// away. The saved searches are loaded once for the whole batch
+func (s *AlertService) matchListings(properties []models.Housing) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	searches, err := s.searches.FindAll(ctx)
+	if err != nil {
+		log.Printf("Failed to load saved searches: %v", err)
+		return
+	}
+
+	for _, property := range properties {
+		s.matchListing(ctx, property, searches)
+	}
+}
+
+// matchListing records an alert for every search of searches property
+// matches that it has not alerted before, and delivers instant ones
+func (s *AlertService) matchListing(ctx context.Context, property models.Housing, searches []models.SavedSearch) {
+	now := time.Now()
+	for _, search := range searches {
+		if !SearchFilter(search.Criteria).Matches(property) {
+			continue
+		}
+
+		alert := models.ListingAlert{
+			ID:            primitive.NewObjectID(),
+			UserID:        search.UserID,
+			SavedSearchID: search.ID,
+			PropertyID:    property.ID,
+			CreatedAt:     primitive.NewDateTimeFromTime(now),
+		}
+		inserted, err := s.alerts.Insert(ctx, alert)
+		if err != nil {
+			log.Printf("Failed to record alert for saved search %s: %v", search.ID.Hex(), err)
+			continue
+		}
+		if inserted && search.Delivery == models.DeliveryInstant {
+			s.deliver(ctx, search, []models.ListingAlert{alert}, now)
+		}
+	}
+}
+
+// deliverDue sends digests of daily searches whose interval has passed and
+// retries instant alerts that failed to send
+func (s *AlertService) deliverDue(now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	searches, err := s.searches.FindAll(ctx)
+	if err != nil {
+		log.Printf("Failed to load saved searches: %v", err)
+		return
+	}
+
+	for _, search := range searches {
+		if search.Delivery == models.DeliveryDaily {
+			last := search.CreatedAt
+			if search.LastNotifiedAt != nil {
+				last = *search.LastNotifiedAt
+			}
+			if now.Sub(last.Time()) < s.digestInterval {
+				continue
+			}
+		}
+
+		pending, err := s.alerts.FindUndelivered(ctx, search.ID)
+		if err != nil {
+			log.Printf("Failed to load alerts of saved search %s: %v", search.ID.Hex(), err)
+			continue
+		}
+		if len(pending) > 0 {
+			s.deliver(ctx, search, pending, now)
+		}
+	}
+}
+
+// deliver notifies the owner of search about the listings of alerts and
+// marks them delivered. Alerts of deleted listings are marked delivered
+// without being sent
+func (s *AlertService) deliver(ctx context.Context, search models.SavedSearch, alerts []models.ListingAlert, now time.Time) {
+	user, err := s.userService.GetUserByID(search.UserID.Hex())
+	if err != nil {
+		log.Printf("Failed to load owner of saved search %s: %v", search.ID.Hex(), err)
+		return
+	}
+	if user.Disabled {
+		return
+	}
+
+	var listings []models.Housing
+	ids := make([]primitive.ObjectID, 0, len(alerts))
+	for _, alert := range alerts {
+		ids = append(ids, alert.ID)
+		if property, err := s.housingService.GetPropertyByID(alert.PropertyID.Hex()); err == nil {
+			listings = append(listings, *property)
+		}
+	}
+
+	if len(listings) > 0 {
+		err := s.notifier.Notify(ctx, notifier.Notification{User: *user, Search: search, Listings: listings})
+		if err != nil {
+			log.Printf("Failed to send alerts of saved search %s: %v", search.ID.Hex(), err)
+			return
+		}
+	}
+
+	if err := s.alerts.MarkDelivered(ctx, ids, now); err != nil {
+		log.Printf("Failed to mark alerts of saved search %s delivered: %v", search.ID.Hex(), err)
+	}
+	if err := s.searches.SetLastNotified(ctx, search.ID, now); err != nil {
+		log.Printf("Failed to update saved search %s: %v", search.ID.Hex(), err)
+	}
+}
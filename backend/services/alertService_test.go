@@ -0,0 +1,122 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+)
+
+// runAlerts matches the listings saved so far; Run flushes its queue when
+// its context is already done
+func runAlerts(svc *services.Services) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	svc.Alerts.Run(ctx)
+}
+
+// alertsBySearch returns the alerts of userID keyed by saved search
+func alertsBySearch(t *testing.T, svc *services.Services, userID string) map[string][]models.ListingAlert {
+	t.Helper()
+	alerts, err := svc.SavedSearches.GetAlertsByUser(userID)
+	if err != nil {
+		t.Fatalf("GetAlertsByUser() error = %v", err)
+	}
+	result := map[string][]models.ListingAlert{}
+	for _, alert := range alerts {
+		result[alert.SavedSearchID.Hex()] = append(result[alert.SavedSearchID.Hex()], alert)
+	}
+	return result
+}
+
+func TestAlertMatching(t *testing.T) {
+	svc := newServices(t)
+	user := createUser(t, svc, "gator@ufl.edu", "Str0ngPass")
+
+	maxPrice := 1500.0
+	searches := map[string]models.SavedSearch{
+		"county":      {Name: "Alachua", Criteria: models.SearchCriteria{County: "Alachua"}},
+		"affordable":  {Name: "Affordable", Criteria: models.SearchCriteria{Price: models.SearchRange{Max: &maxPrice}}},
+		"other place": {Name: "Duval", Criteria: models.SearchCriteria{County: "Duval"}},
+	}
+	ids := map[string]string{}
+	for key, search := range searches {
+		saved, err := svc.SavedSearches.CreateSavedSearch(user.ID, search)
+		if err != nil {
+			t.Fatalf("CreateSavedSearch(%q) error = %v", search.Name, err)
+		}
+		ids[key] = saved.ID.Hex()
+	}
+
+	// createListing lists at $1000 in Alachua
+	property := createListing(t, svc, "Pool House")
+	runAlerts(svc)
+
+	alerts := alertsBySearch(t, svc, user.ID)
+	for key, want := range map[string]int{"county": 1, "affordable": 1, "other place": 0} {
+		if got := len(alerts[ids[key]]); got != want {
+			t.Errorf("%s search has %d alerts, want %d", key, got, want)
+		}
+	}
+	for _, alert := range alerts[ids["county"]] {
+		if alert.PropertyID != property.ID {
+			t.Errorf("alert is for %s, want %s", alert.PropertyID.Hex(), property.ID.Hex())
+		}
+		if alert.DeliveredAt == nil {
+			t.Error("instant alert was not delivered")
+		}
+	}
+
+	// Saving the listing again does not alert twice
+	property.Description = "Now with a pool"
+	if _, err := svc.Housing.UpdateProperty(property.ID.Hex(), *property, user.ID); err != nil {
+		t.Fatalf("UpdateProperty() error = %v", err)
+	}
+	runAlerts(svc)
+	if got := len(alertsBySearch(t, svc, user.ID)[ids["county"]]); got != 1 {
+		t.Errorf("county search has %d alerts after an update, want 1", got)
+	}
+}
+
+func TestAlertDigest(t *testing.T) {
+	svc := newServices(t)
+	user := createUser(t, svc, "gator@ufl.edu", "Str0ngPass")
+	search, err := svc.SavedSearches.CreateSavedSearch(user.ID, models.SavedSearch{
+		Name:     "Alachua",
+		Criteria: models.SearchCriteria{County: "Alachua"},
+		Delivery: models.DeliveryDaily,
+	})
+	if err != nil {
+		t.Fatalf("CreateSavedSearch() error = %v", err)
+	}
+
+	createListing(t, svc, "Pool House")
+	runAlerts(svc)
+
+	// delivered reports whether the digest alert has been sent
+	delivered := func() bool {
+		t.Helper()
+		alerts := alertsBySearch(t, svc, user.ID)[search.ID.Hex()]
+		if len(alerts) != 1 {
+			t.Fatalf("search has %d alerts, want 1", len(alerts))
+		}
+		return alerts[0].DeliveredAt != nil
+	}
+
+	if delivered() {
+		t.Fatal("daily alert was delivered instantly")
+	}
+
+	// The digest is due a day after the search was created
+	created := search.CreatedAt.Time()
+	svc.Alerts.DeliverDue(created.Add(23 * time.Hour))
+	if delivered() {
+		t.Error("digest was delivered before its interval passed")
+	}
+	svc.Alerts.DeliverDue(created.Add(24 * time.Hour))
+	if !delivered() {
+		t.Error("digest was not delivered once its interval passed")
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Error kinds. Errors meant for clients wrap one of them so that the HTTP
+// layer can pick a status code without reading the message
+var (
+	// ErrNotFound is also returned by repositories when no record matches
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidID    = errors.New("invalid ID")
+	ErrValidation   = errors.New("validation failed")
+	ErrConflict     = errors.New("conflict")
+	ErrForbidden    = errors.New("forbidden")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrTooLarge     = errors.New("too large")
+	ErrUnsupported  = errors.New("unsupported media type")
+	// ErrTooManyRequests errors tell in RetryAfter when to try again
+	ErrTooManyRequests = errors.New("too many requests")
+)
+
+// Error is an error meant for clients. Code is a stable machine-readable
+// identifier such as "property_not_found" and Message explains it in English
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	// Fields lists the invalid fields of a validation error
+	Fields []FieldError
+	// RetryAfter is how long to wait before retrying, when known
+	RetryAfter time.Duration
+}
+
+// FieldError describes why one request field is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewError creates a client error of the given kind
+func NewError(kind error, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// TooManyRequests creates an error refusing a request until retryAfter has
+// passed
+func TooManyRequests(code, message string, retryAfter time.Duration) *Error {
+	return &Error{Kind: ErrTooManyRequests, Code: code, Message: message, RetryAfter: retryAfter}
+}
+
+// ValidationError creates a validation error listing the invalid fields
+func ValidationError(fields ...FieldError) *Error {
+	message := "request validation failed"
+	if len(fields) == 1 {
+		message = fields[0].Message
+	}
+	return &Error{Kind: ErrValidation, Code: "validation_failed", Message: message, Fields: fields}
+}
+
+// InvalidField creates a validation error for a single field
+func InvalidField(field, code, format string, args ...any) *Error {
+	return ValidationError(FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// invalidID creates the error for a malformed ID of the named resource
+func invalidID(resource string) *Error {
+	return NewError(ErrInvalidID, "invalid_id", fmt.Sprintf("invalid %s ID format", resource))
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the kind so that errors.Is matches it
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// Errors shared by several services
+var (
+	ErrPropertyNotFound = NewError(ErrNotFound, "property_not_found", "property not found")
+	ErrRequestNotFound  = NewError(ErrNotFound, "request_not_found", "request not found")
+	// ErrListingChanged is returned when other writes kept saving a listing
+	// while an edit of it was being saved
+	ErrListingChanged = NewError(ErrConflict, "listing_changed", "listing was changed by someone else, try again")
+)
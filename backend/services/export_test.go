@@ -0,0 +1,9 @@
+package services
+
+import "time"
+
+// DeliverDue lets the tests deliver the alerts due at now without waiting
+// for the ticker of Run
+func (s *AlertService) DeliverDue(now time.Time) {
+	s.deliverDue(now)
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrFavoriteNotFound is returned when a user has not saved a listing
+var ErrFavoriteNotFound = NewError(ErrNotFound, "favorite_not_found", "listing is not in your favorites")
+
+// FavoriteService handles business logic for saved listings
+type FavoriteService struct {
+	repo           FavoriteRepository
+	housingService *HousingService
+}
+
+// NewFavoriteService creates a new favorite service
+func NewFavoriteService(repo FavoriteRepository, housingService *HousingService) *FavoriteService {
+	return &FavoriteService{
+		repo:           repo,
+		housingService: housingService,
+	}
+}
+
+// AddFavorite saves a listing for a user. Saving a listing twice is not an
+// error; created reports whether the favorite is new
+func (s *FavoriteService) AddFavorite(userID string, propertyID string) (favorite *models.Favorite, created bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, false, invalidID("user")
+	}
+
+	// Validate property exists
+	property, err := s.housingService.GetPropertyByID(propertyID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	candidate := models.Favorite{
+		ID:         primitive.NewObjectID(),
+		UserID:     userObjID,
+		PropertyID: property.ID,
+		CreatedAt:  primitive.NewDateTimeFromTime(time.Now()),
+	}
+	favorite, err = s.repo.Add(ctx, candidate)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return favorite, favorite.ID == candidate.ID, nil
+}
+
+// RemoveFavorite removes a listing from a user's favorites
+func (s *FavoriteService) RemoveFavorite(userID string, propertyID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return invalidID("user")
+	}
+
+	propertyObjID, err := primitive.ObjectIDFromHex(propertyID)
+	if err != nil {
+		return invalidID("property")
+	}
+
+	err = s.repo.Remove(ctx, userObjID, propertyObjID)
+	if err != nil {
+		if err == ErrNotFound {
+			return ErrFavoriteNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetFavoritesByUser retrieves a user's favorites, newest first
+func (s *FavoriteService) GetFavoritesByUser(userID string) ([]models.Favorite, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, invalidID("user")
+	}
+
+	return s.repo.FindByUser(ctx, objID)
+}
+
+// CountFavorites returns how many users saved a listing
+func (s *FavoriteService) CountFavorites(propertyID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	property, err := s.housingService.GetPropertyByID(propertyID)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.repo.CountByProperty(ctx, property.ID)
+}
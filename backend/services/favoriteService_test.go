@@ -0,0 +1,123 @@
+package services_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"gatorswamp/services"
+)
+
+func TestFavorites(t *testing.T) {
+	svc := newServices(t)
+	user := createUser(t, svc, "gator@ufl.edu", "Str0ngPass")
+	pool := createListing(t, svc, "Pool House")
+	loft := createListing(t, svc, "Loft")
+
+	first, created, err := svc.Favorites.AddFavorite(user.ID, pool.ID.Hex())
+	if err != nil || !created {
+		t.Fatalf("AddFavorite() = created %t, error %v, want a new favorite", created, err)
+	}
+	again, created, err := svc.Favorites.AddFavorite(user.ID, pool.ID.Hex())
+	if err != nil || created || again.ID != first.ID {
+		t.Fatalf("AddFavorite() again = %s, created %t, error %v, want the existing favorite %s", again.ID.Hex(), created, err, first.ID.Hex())
+	}
+	if _, _, err := svc.Favorites.AddFavorite(user.ID, loft.ID.Hex()); err != nil {
+		t.Fatalf("AddFavorite() error = %v", err)
+	}
+
+	favorites, err := svc.Favorites.GetFavoritesByUser(user.ID)
+	if err != nil {
+		t.Fatalf("GetFavoritesByUser() error = %v", err)
+	}
+	if len(favorites) != 2 || favorites[0].PropertyID != loft.ID || favorites[1].PropertyID != pool.ID {
+		t.Errorf("favorites = %+v, want the loft then the pool house", favorites)
+	}
+	if count, err := svc.Favorites.CountFavorites(pool.ID.Hex()); err != nil || count != 1 {
+		t.Errorf("CountFavorites() = %d, %v, want 1", count, err)
+	}
+
+	if err := svc.Favorites.RemoveFavorite(user.ID, pool.ID.Hex()); err != nil {
+		t.Fatalf("RemoveFavorite() error = %v", err)
+	}
+	if err := svc.Favorites.RemoveFavorite(user.ID, pool.ID.Hex()); !errors.Is(err, services.ErrFavoriteNotFound) {
+		t.Errorf("RemoveFavorite() again error = %v, want %v", err, services.ErrFavoriteNotFound)
+	}
+	if count, err := svc.Favorites.CountFavorites(pool.ID.Hex()); err != nil || count != 0 {
+		t.Errorf("CountFavorites() after removal = %d, %v, want 0", count, err)
+	}
+}
+
+func TestFavoritesRejects(t *testing.T) {
+	svc := newServices(t)
+	user := createUser(t, svc, "gator@ufl.edu", "Str0ngPass")
+	pool := createListing(t, svc, "Pool House")
+
+	if _, _, err := svc.Favorites.AddFavorite(user.ID, "000000000000000000000000"); !errors.Is(err, services.ErrPropertyNotFound) {
+		t.Errorf("AddFavorite() of a missing listing error = %v, want %v", err, services.ErrPropertyNotFound)
+	}
+	if _, _, err := svc.Favorites.AddFavorite(user.ID, "pool"); !errors.Is(err, services.ErrInvalidID) {
+		t.Errorf("AddFavorite() with a bad listing ID error = %v, want %v", err, services.ErrInvalidID)
+	}
+	if _, _, err := svc.Favorites.AddFavorite("gator", pool.ID.Hex()); !errors.Is(err, services.ErrInvalidID) {
+		t.Errorf("AddFavorite() with a bad user ID error = %v, want %v", err, services.ErrInvalidID)
+	}
+	if err := svc.Favorites.RemoveFavorite(user.ID, "pool"); !errors.Is(err, services.ErrInvalidID) {
+		t.Errorf("RemoveFavorite() with a bad listing ID error = %v, want %v", err, services.ErrInvalidID)
+	}
+	if _, err := svc.Favorites.CountFavorites("000000000000000000000000"); !errors.Is(err, services.ErrPropertyNotFound) {
+		t.Errorf("CountFavorites() of a missing listing error = %v, want %v", err, services.ErrPropertyNotFound)
+	}
+}
+
+func TestFavoritesConcurrentAdd(t *testing.T) {
+	const n = 8
+	svc := newServices(t)
+	user := createUser(t, svc, "gator@ufl.edu", "Str0ngPass")
+	pool := createListing(t, svc, "Pool House")
+
+	var wg sync.WaitGroup
+	created := make(chan bool, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, isNew, err := svc.Favorites.AddFavorite(user.ID, pool.ID.Hex())
+			if err != nil {
+				t.Errorf("AddFavorite() error = %v", err)
+			}
+			created <- isNew
+		}()
+	}
+	wg.Wait()
+	close(created)
+
+	newFavorites := 0
+	for isNew := range created {
+		if isNew {
+			newFavorites++
+		}
+	}
+	if newFavorites != 1 {
+		t.Errorf("%d adds created a favorite, want 1", newFavorites)
+	}
+	if count, _ := svc.Favorites.CountFavorites(pool.ID.Hex()); count != 1 {
+		t.Errorf("CountFavorites() = %d, want 1", count)
+	}
+}
+
+func TestDeletePropertyRemovesFavorites(t *testing.T) {
+	svc := newServices(t)
+	user := createUser(t, svc, "gator@ufl.edu", "Str0ngPass")
+	pool := createListing(t, svc, "Pool House")
+	if _, _, err := svc.Favorites.AddFavorite(user.ID, pool.ID.Hex()); err != nil {
+		t.Fatalf("AddFavorite() error = %v", err)
+	}
+
+	if err := svc.Housing.DeleteProperty(pool.ID.Hex()); err != nil {
+		t.Fatalf("DeleteProperty() error = %v", err)
+	}
+	if favorites, err := svc.Favorites.GetFavoritesByUser(user.ID); err != nil || len(favorites) != 0 {
+		t.Errorf("favorites after the listing was deleted = %d, %v, want none", len(favorites), err)
+	}
+}
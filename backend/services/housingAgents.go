@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// assignAgent checks that the listing's AgentID, if set, is an agent account
+// and copies the agent's contact details onto the listing
+func (s *HousingService) assignAgent(ctx context.Context, property *models.Housing) error {
+	if property.AgentID == nil {
+		return nil
+	}
+
+	agent, err := s.users.FindByID(ctx, *property.AgentID)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	if err == ErrNotFound || agent.Role != models.RoleAgent {
+		return InvalidField("agentId", "agent", "agentId must be the ID of an agent account")
+	}
+
+	property.Agent = agent.AgentContact()
+	return nil
+}
+
+// UnassignAgent unlinks a listing from its agent account. The listing keeps
+// the agent's contact details as its embedded agent
+func (s *HousingService) UnassignAgent(id string) (*models.Housing, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	property, err := s.GetPropertyByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if property.AgentID == nil {
+		return property, nil
+	}
+
+	_, property, err = s.saveListing(ctx, id, func(property *models.Housing) error {
+		property.AgentID = nil
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.listingSaved(*property)
+	return property, nil
+}
+
+// GetPropertiesByAgent returns the listings an agent manages, newest first
+func (s *HousingService) GetPropertiesByAgent(agentID string) ([]models.Housing, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(agentID)
+	if err != nil {
+		return nil, invalidID("agent")
+	}
+
+	return s.repo.Search(ctx, HousingFilter{AgentID: &objID})
+}
@@ -0,0 +1,128 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// createAgent registers a user with the agent role and returns its ID
+func createAgent(t *testing.T, svc *services.Services, email string) primitive.ObjectID {
+	t.Helper()
+	user := createUser(t, svc, email, "Str0ngPass")
+	if _, err := svc.Users.SetUserRole(user.ID, models.RoleAgent); err != nil {
+		t.Fatalf("SetUserRole(%q) error = %v", email, err)
+	}
+	id, _ := primitive.ObjectIDFromHex(user.ID)
+	return id
+}
+
+func TestAssignAgent(t *testing.T) {
+	svc := newServices(t)
+	agent := createAgent(t, svc, "agent@ufl.edu")
+	user, _ := primitive.ObjectIDFromHex(createUser(t, svc, "user@ufl.edu", "Str0ngPass").ID)
+
+	tests := []struct {
+		name    string
+		agentID primitive.ObjectID
+		wantErr bool
+	}{
+		{name: "agent", agentID: agent},
+		{name: "not an agent", agentID: user, wantErr: true},
+		{name: "no account", agentID: primitive.NewObjectID(), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			property, err := svc.Housing.CreateProperty(models.Housing{
+				Name: "Pool House", Address: "1 Main St", County: "Alachua", PriceCents: 100000,
+				Agent:   models.Agent{Name: "Old Contact"},
+				AgentID: &tt.agentID,
+			})
+			if tt.wantErr {
+				if !errors.Is(err, services.ErrValidation) {
+					t.Fatalf("CreateProperty() error = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateProperty() error = %v", err)
+			}
+			if property.Agent.Name != "Test User" {
+				t.Errorf("agent name = %q, want the agent account's name", property.Agent.Name)
+			}
+		})
+	}
+}
+
+func TestUnassignAgent(t *testing.T) {
+	svc := newServices(t)
+	agent := createAgent(t, svc, "agent@ufl.edu")
+	property, err := svc.Housing.CreateProperty(models.Housing{Name: "Pool House", Address: "1 Main St", County: "Alachua", PriceCents: 100000, AgentID: &agent})
+	if err != nil {
+		t.Fatalf("CreateProperty() error = %v", err)
+	}
+
+	unlinked, err := svc.Housing.UnassignAgent(property.ID.Hex())
+	if err != nil {
+		t.Fatalf("UnassignAgent() error = %v", err)
+	}
+	if unlinked.AgentID != nil {
+		t.Errorf("agentId = %v, want none", unlinked.AgentID.Hex())
+	}
+	if unlinked.Agent.Name != "Test User" {
+		t.Errorf("agent name = %q, want the contact details kept", unlinked.Agent.Name)
+	}
+
+	listings, err := svc.Housing.GetPropertiesByAgent(agent.Hex())
+	if err != nil || len(listings) != 0 {
+		t.Errorf("GetPropertiesByAgent() = %d listings, %v, want none", len(listings), err)
+	}
+}
+
+func TestGetPropertiesByAgent(t *testing.T) {
+	svc := newServices(t)
+	ann := createAgent(t, svc, "ann@ufl.edu")
+	bob := createAgent(t, svc, "bob@ufl.edu")
+	admin := createUser(t, svc, "admin@ufl.edu", "Str0ngPass")
+
+	createListing(t, svc, "Loft")
+	for _, listing := range []struct {
+		name  string
+		agent *primitive.ObjectID
+	}{
+		{"Pool House", &ann},
+		{"Studio", &bob},
+	} {
+		if _, err := svc.Housing.CreateProperty(models.Housing{Name: listing.name, Address: "1 Main St", County: "Alachua", PriceCents: 100000, AgentID: listing.agent}); err != nil {
+			t.Fatalf("CreateProperty(%q) error = %v", listing.name, err)
+		}
+	}
+
+	// Assigning the cabin adds it to Ann's listings
+	cabin := createListing(t, svc, "Cabin")
+	changes := *cabin
+	changes.AgentID = &ann
+	if _, err := svc.Housing.UpdateProperty(cabin.ID.Hex(), changes, admin.ID); err != nil {
+		t.Fatalf("UpdateProperty() error = %v", err)
+	}
+
+	listings, err := svc.Housing.GetPropertiesByAgent(ann.Hex())
+	if err != nil {
+		t.Fatalf("GetPropertiesByAgent() error = %v", err)
+	}
+	got := map[string]bool{}
+	for _, property := range listings {
+		got[property.Name] = true
+	}
+	if len(got) != 2 || !got["Pool House"] || !got["Cabin"] {
+		t.Errorf("Ann's listings = %v, want Pool House and Cabin", got)
+	}
+
+	if _, err := svc.Housing.GetPropertiesByAgent("not-an-id"); !errors.Is(err, services.ErrInvalidID) {
+		t.Errorf("GetPropertiesByAgent(bad ID) error = %v, want an invalid ID error", err)
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NumericRange is an inclusive range; a nil bound is left open
+type NumericRange struct {
+	Min *float64
+	Max *float64
+}
+
+// IsSet reports whether either bound of the range is present
+func (r NumericRange) IsSet() bool {
+	return r.Min != nil || r.Max != nil
+}
+
+// Contains reports whether value lies within the range
+func (r NumericRange) Contains(value float64) bool {
+	if r.Min != nil && value < *r.Min {
+		return false
+	}
+	if r.Max != nil && value > *r.Max {
+		return false
+	}
+	return true
+}
+
+// Scale multiplies both bounds of the range by factor
+func (r NumericRange) Scale(factor float64) NumericRange {
+	scaled := NumericRange{}
+	if r.Min != nil {
+		min := *r.Min * factor
+		scaled.Min = &min
+	}
+	if r.Max != nil {
+		max := *r.Max * factor
+		scaled.Max = &max
+	}
+	return scaled
+}
+
+// HousingFilter holds the structured criteria accepted by the housing search.
+// Price bounds are expressed in major currency units (e.g. dollars)
+type HousingFilter struct {
+	County string
+	Type   string
+	// AgentID restricts the search to the listings of one agent account
+	AgentID   *primitive.ObjectID
+	Price     NumericRange
+	Bedrooms  NumericRange
+	Bathrooms NumericRange
+	Year      NumericRange
+	Surface   NumericRange
+}
+
+// PriceCents returns the price range converted to the stored minor units
+func (f HousingFilter) PriceCents() NumericRange {
+	return f.Price.Scale(100)
+}
+
+// Matches reports whether a listing satisfies every criterion of the filter
+func (f HousingFilter) Matches(property models.Housing) bool {
+	if f.County != "" && property.County != f.County {
+		return false
+	}
+	if f.Type != "" && property.Type != f.Type {
+		return false
+	}
+	if f.AgentID != nil && (property.AgentID == nil || *property.AgentID != *f.AgentID) {
+		return false
+	}
+
+	return f.PriceCents().Contains(float64(property.PriceCents)) &&
+		f.Bedrooms.Contains(float64(property.Bedrooms)) &&
+		f.Bathrooms.Contains(property.Bathrooms) &&
+		f.Year.Contains(float64(property.Year)) &&
+		f.Surface.Contains(property.Surface)
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"gatorswamp/models"
+)
+
+// Geo search limits
+const (
+	DefaultGeoLimit    = 100
+	MaxGeoLimit        = 500
+	MaxGeoRadiusMeters = 100000
+)
+
+// LatLng is a geographic coordinate
+type LatLng struct {
+	Lat float64
+	Lng float64
+}
+
+// Validate checks latitude and longitude ranges
+func (p LatLng) Validate() error {
+	if p.Lat < -90 || p.Lat > 90 {
+		return InvalidField("lat", "out_of_range", "latitude must be between -90 and 90")
+	}
+	if p.Lng < -180 || p.Lng > 180 {
+		return InvalidField("lng", "out_of_range", "longitude must be between -180 and 180")
+	}
+	return nil
+}
+
+// HousingWithDistance is a property annotated with its distance from a query point
+type HousingWithDistance struct {
+	models.Housing `bson:",inline"`
+	DistanceMeters float64 `bson:"distanceMeters" json:"distanceMeters"`
+}
+
+// BoundingBox is a map viewport given by its south-west and north-east corners
+type BoundingBox struct {
+	SouthWest LatLng
+	NorthEast LatLng
+}
+
+// Validate checks that the box corners are valid coordinates in the right order
+func (b BoundingBox) Validate() error {
+	if err := b.SouthWest.Validate(); err != nil {
+		return err
+	}
+	if err := b.NorthEast.Validate(); err != nil {
+		return err
+	}
+	if b.SouthWest.Lat >= b.NorthEast.Lat || b.SouthWest.Lng >= b.NorthEast.Lng {
+		return InvalidField("bounds", "invalid", "south-west corner must be below and left of the north-east corner")
+	}
+	return nil
+}
+
+// Center returns the midpoint of the box
+func (b BoundingBox) Center() LatLng {
+	return LatLng{
+		Lat: (b.SouthWest.Lat + b.NorthEast.Lat) / 2,
+		Lng: (b.SouthWest.Lng + b.NorthEast.Lng) / 2,
+	}
+}
+
+// Contains reports whether p lies inside the box
+func (b BoundingBox) Contains(p LatLng) bool {
+	return p.Lat >= b.SouthWest.Lat && p.Lat <= b.NorthEast.Lat &&
+		p.Lng >= b.SouthWest.Lng && p.Lng <= b.NorthEast.Lng
+}
+
+// NearProperties returns properties within radiusMeters of origin, closest first
+func (s *HousingService) NearProperties(origin LatLng, radiusMeters float64, filter HousingFilter, limit int) ([]HousingWithDistance, error) {
+	if err := origin.Validate(); err != nil {
+		return nil, err
+	}
+	if radiusMeters <= 0 || radiusMeters > MaxGeoRadiusMeters {
+		return nil, InvalidField("radiusMeters", "out_of_range", "radiusMeters must be greater than 0 and at most %d", MaxGeoRadiusMeters)
+	}
+
+	return s.near(origin, radiusMeters, nil, filter, limit)
+}
+
+// PropertiesInBounds returns properties inside a map viewport, closest to
+// origin first. When origin is nil distances are measured from the centre
+// of the box
+func (s *HousingService) PropertiesInBounds(box BoundingBox, origin *LatLng, filter HousingFilter, limit int) ([]HousingWithDistance, error) {
+	if err := box.Validate(); err != nil {
+		return nil, err
+	}
+
+	from := box.Center()
+	if origin != nil {
+		if err := origin.Validate(); err != nil {
+			return nil, err
+		}
+		from = *origin
+	}
+
+	return s.near(from, 0, &box, filter, limit)
+}
+
+// near clamps the limit and runs the repository distance query
+func (s *HousingService) near(origin LatLng, maxDistance float64, box *BoundingBox, filter HousingFilter, limit int) ([]HousingWithDistance, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if limit <= 0 {
+		limit = DefaultGeoLimit
+	}
+	if limit > MaxGeoLimit {
+		limit = MaxGeoLimit
+	}
+
+	properties, err := s.repo.Near(ctx, origin, maxDistance, box, filter, limit)
+	if err != nil {
+		return nil, err
+	}
+	if properties == nil {
+		properties = []HousingWithDistance{}
+	}
+	return properties, nil
+}
@@ -0,0 +1,157 @@
+package services_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+)
+
+var (
+	downtown     = services.LatLng{Lat: 29.6516, Lng: -82.3248}
+	campus       = services.LatLng{Lat: 29.6436, Lng: -82.3549}
+	jacksonville = services.LatLng{Lat: 30.3322, Lng: -81.6557}
+)
+
+// createGeoListings adds listings downtown, on campus and in Jacksonville,
+// plus one without coordinates
+func createGeoListings(t *testing.T, svc *services.Services) {
+	t.Helper()
+	for _, property := range []models.Housing{
+		{Name: "Downtown", County: "Alachua", Type: "apartment", Latitude: downtown.Lat, Longitude: downtown.Lng},
+		{Name: "Campus", County: "Alachua", Type: "house", Latitude: campus.Lat, Longitude: campus.Lng},
+		{Name: "Jacksonville", County: "Duval", Type: "house", Latitude: jacksonville.Lat, Longitude: jacksonville.Lng},
+		{Name: "Nowhere", County: "Alachua", Type: "house"},
+	} {
+		property.Address = "1 Main St"
+		property.PriceCents = 100000
+		if _, err := svc.Housing.CreateProperty(property); err != nil {
+			t.Fatalf("CreateProperty(%q) error = %v", property.Name, err)
+		}
+	}
+}
+
+// names returns the names of the listings found, checking that they are
+// ordered by distance
+func names(t *testing.T, properties []services.HousingWithDistance) []string {
+	t.Helper()
+	result := []string{}
+	for i, property := range properties {
+		if i > 0 && property.DistanceMeters < properties[i-1].DistanceMeters {
+			t.Errorf("%s at %.0fm comes after %s at %.0fm", property.Name, property.DistanceMeters, properties[i-1].Name, properties[i-1].DistanceMeters)
+		}
+		result = append(result, property.Name)
+	}
+	return result
+}
+
+func TestNearProperties(t *testing.T) {
+	tests := []struct {
+		name    string
+		origin  services.LatLng
+		radius  float64
+		filter  services.HousingFilter
+		limit   int
+		want    []string
+		wantErr bool
+	}{
+		{name: "within 5km", origin: downtown, radius: 5000, want: []string{"Downtown", "Campus"}},
+		{name: "within 1km", origin: downtown, radius: 1000, want: []string{"Downtown"}},
+		{name: "closest first", origin: campus, radius: 5000, want: []string{"Campus", "Downtown"}},
+		{name: "whole state", origin: downtown, radius: services.MaxGeoRadiusMeters, want: []string{"Downtown", "Campus", "Jacksonville"}},
+		{name: "limit", origin: downtown, radius: 5000, limit: 1, want: []string{"Downtown"}},
+		{name: "filter", origin: downtown, radius: 5000, filter: services.HousingFilter{Type: "house"}, want: []string{"Campus"}},
+		{name: "nothing near", origin: services.LatLng{Lat: 0, Lng: 0}, radius: 1000, want: []string{}},
+		{name: "zero radius", origin: downtown, radius: 0, wantErr: true},
+		{name: "radius too large", origin: downtown, radius: services.MaxGeoRadiusMeters + 1, wantErr: true},
+		{name: "latitude out of range", origin: services.LatLng{Lat: 91, Lng: 0}, radius: 1000, wantErr: true},
+		{name: "longitude out of range", origin: services.LatLng{Lat: 0, Lng: -181}, radius: 1000, wantErr: true},
+	}
+
+	svc := newServices(t)
+	createGeoListings(t, svc)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			properties, err := svc.Housing.NearProperties(tt.origin, tt.radius, tt.filter, tt.limit)
+			if tt.wantErr {
+				if !errors.Is(err, services.ErrValidation) {
+					t.Fatalf("NearProperties() error = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NearProperties() error = %v", err)
+			}
+			if got := names(t, properties); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NearProperties() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNearPropertiesDistance(t *testing.T) {
+	svc := newServices(t)
+	createGeoListings(t, svc)
+
+	properties, err := svc.Housing.NearProperties(downtown, 5000, services.HousingFilter{}, 0)
+	if err != nil || len(properties) != 2 {
+		t.Fatalf("NearProperties() = %d listings, %v, want 2", len(properties), err)
+	}
+	if d := properties[0].DistanceMeters; d > 1 {
+		t.Errorf("distance to the origin = %.1fm, want 0", d)
+	}
+	// Downtown and campus are about 3.05km apart
+	if d := properties[1].DistanceMeters; d < 3000 || d > 3100 {
+		t.Errorf("distance to campus = %.0fm, want about 3050m", d)
+	}
+}
+
+func TestPropertiesInBounds(t *testing.T) {
+	gainesville := services.BoundingBox{
+		SouthWest: services.LatLng{Lat: 29.6, Lng: -82.4},
+		NorthEast: services.LatLng{Lat: 29.7, Lng: -82.3},
+	}
+	florida := services.BoundingBox{
+		SouthWest: services.LatLng{Lat: 24.5, Lng: -87.6},
+		NorthEast: services.LatLng{Lat: 31, Lng: -80},
+	}
+
+	tests := []struct {
+		name    string
+		box     services.BoundingBox
+		origin  *services.LatLng
+		want    []string
+		wantErr bool
+	}{
+		{name: "from the centre", box: gainesville, want: []string{"Campus", "Downtown"}},
+		{name: "from a point", box: gainesville, origin: &downtown, want: []string{"Downtown", "Campus"}},
+		{name: "state", box: florida, origin: &jacksonville, want: []string{"Jacksonville", "Downtown", "Campus"}},
+		{name: "corners swapped", box: services.BoundingBox{SouthWest: gainesville.NorthEast, NorthEast: gainesville.SouthWest}, wantErr: true},
+		{name: "empty box", box: services.BoundingBox{SouthWest: downtown, NorthEast: downtown}, wantErr: true},
+		{name: "origin out of range", box: gainesville, origin: &services.LatLng{Lat: 100}, wantErr: true},
+	}
+
+	svc := newServices(t)
+	createGeoListings(t, svc)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			properties, err := svc.Housing.PropertiesInBounds(tt.box, tt.origin, services.HousingFilter{}, 0)
+			if tt.wantErr {
+				if !errors.Is(err, services.ErrValidation) {
+					t.Fatalf("PropertiesInBounds() error = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PropertiesInBounds() error = %v", err)
+			}
+			if got := names(t, properties); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PropertiesInBounds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
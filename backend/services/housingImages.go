@@ -0,0 +1,297 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"gatorswamp/models"
+	"gatorswamp/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Gallery limits. An upload request carries at most MaxUploadBytes of
+// photos, so filling a gallery with large photos takes several requests
+const (
+	MaxImageBytes    = 10 << 20
+	MaxUploadBytes   = 40 << 20
+	MaxImagePixels   = 40000000
+	MaxHousingImages = 20
+	MaxCaptionLength = 500
+	ThumbnailSize    = 400
+)
+
+// Errors returned by the gallery methods
+var (
+	ErrUnsupportedImage = NewError(ErrUnsupported, "unsupported_image", "unsupported image type: upload JPEG or PNG files")
+	ErrImageTooLarge    = NewError(ErrTooLarge, "image_too_large", fmt.Sprintf("image exceeds the %d MB size limit", MaxImageBytes>>20))
+	ErrUploadTooLarge   = NewError(ErrTooLarge, "upload_too_large", fmt.Sprintf("upload exceeds the %d MB request limit, send the images in several requests", MaxUploadBytes>>20))
+	ErrTooManyImages    = NewError(ErrValidation, "too_many_images", fmt.Sprintf("a listing can have at most %d images", MaxHousingImages))
+	ErrImageNotFound    = NewError(ErrNotFound, "image_not_found", "image not found")
+	ErrNoImages         = NewError(ErrValidation, "no_images", "no images uploaded")
+	ErrInvalidPlacement = NewError(ErrValidation, "invalid_placement", "images must list every image of the listing exactly once")
+)
+
+// ImageUpload is an uploaded photo waiting to be added to a gallery
+type ImageUpload struct {
+	Data    []byte
+	Caption string
+}
+
+// ImagePlacement gives the caption of a gallery image; a list of
+// placements also fixes the gallery order
+type ImagePlacement struct {
+	ID      string `json:"id" validate:"required"`
+	Caption string `json:"caption" validate:"max=500"`
+}
+
+// processedImage is an upload that passed validation, ready to be stored
+type processedImage struct {
+	data        []byte
+	thumbnail   []byte
+	contentType string
+	extension   string
+	width       int
+	height      int
+}
+
+// AddImages validates and stores uploaded photos and appends them to the
+// listing's gallery. Nothing is stored unless every upload is valid
+func (s *HousingService) AddImages(id string, uploads []ImageUpload) (*models.Housing, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	property, err := s.GetPropertyByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(uploads) == 0 {
+		return nil, ErrNoImages
+	}
+	if len(property.Images)+len(uploads) > MaxHousingImages {
+		return nil, ErrTooManyImages
+	}
+
+	processed := make([]processedImage, len(uploads))
+	for i, upload := range uploads {
+		if utf8.RuneCountInString(upload.Caption) > MaxCaptionLength {
+			return nil, InvalidField(fmt.Sprintf("captions[%d]", i), "max", "caption must be at most %d characters", MaxCaptionLength)
+		}
+		if processed[i], err = processImage(upload.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	var added []models.HousingImage
+	for i, img := range processed {
+		imageID := primitive.NewObjectID()
+		key := fmt.Sprintf("housing/%s/%s%s", property.ID.Hex(), imageID.Hex(), img.extension)
+		thumbKey := fmt.Sprintf("housing/%s/%s_thumb.jpg", property.ID.Hex(), imageID.Hex())
+
+		err := s.blobs.Put(ctx, key, bytes.NewReader(img.data), img.contentType)
+		if err == nil {
+			err = s.blobs.Put(ctx, thumbKey, bytes.NewReader(img.thumbnail), "image/jpeg")
+		}
+		if err != nil {
+			s.deleteImageFiles(append(added, models.HousingImage{Key: key, ThumbnailKey: thumbKey}))
+			return nil, err
+		}
+
+		added = append(added, models.HousingImage{
+			ID:           imageID,
+			URL:          s.blobs.URL(key),
+			ThumbnailURL: s.blobs.URL(thumbKey),
+			Caption:      uploads[i].Caption,
+			Order:        len(property.Images) + i,
+			ContentType:  img.contentType,
+			Width:        img.width,
+			Height:       img.height,
+			Key:          key,
+			ThumbnailKey: thumbKey,
+			UploadedAt:   now,
+		})
+	}
+
+	// Other uploads may have grown the gallery meanwhile
+	_, property, err = s.saveListing(ctx, id, func(property *models.Housing) error {
+		if len(property.Images)+len(added) > MaxHousingImages {
+			return ErrTooManyImages
+		}
+		for i := range added {
+			added[i].Order = len(property.Images) + i
+		}
+		property.Images = append(property.Images, added...)
+		arrangeGallery(property)
+		return nil
+	})
+	if err != nil {
+		s.deleteImageFiles(added)
+		return nil, err
+	}
+
+	return property, nil
+}
+
+// ArrangeImages reorders a listing's gallery and updates its captions. The
+// placements must name every image of the gallery exactly once
+func (s *HousingService) ArrangeImages(id string, placements []ImagePlacement) (*models.Housing, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, property, err := s.saveListing(ctx, id, func(property *models.Housing) error {
+		if len(placements) != len(property.Images) {
+			return ErrInvalidPlacement
+		}
+
+		byID := map[string]models.HousingImage{}
+		for _, img := range property.Images {
+			byID[img.ID.Hex()] = img
+		}
+
+		arranged := make([]models.HousingImage, 0, len(placements))
+		for i, placement := range placements {
+			img, ok := byID[placement.ID]
+			if !ok {
+				return ErrInvalidPlacement
+			}
+			delete(byID, placement.ID)
+
+			img.Caption = placement.Caption
+			img.Order = i
+			arranged = append(arranged, img)
+		}
+
+		property.Images = arranged
+		arrangeGallery(property)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return property, nil
+}
+
+// DeleteImage removes one photo from a listing's gallery and its files
+// from the blob store
+func (s *HousingService) DeleteImage(id string, imageID string) (*models.Housing, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var removed []models.HousingImage
+	_, property, err := s.saveListing(ctx, id, func(property *models.Housing) error {
+		kept := []models.HousingImage{}
+		removed = nil
+		for _, img := range property.Images {
+			if img.ID.Hex() == imageID {
+				removed = append(removed, img)
+			} else {
+				kept = append(kept, img)
+			}
+		}
+		if len(removed) == 0 {
+			return ErrImageNotFound
+		}
+
+		// Drop the cover if it was the deleted photo
+		if property.Image == removed[0].URL {
+			property.Image = ""
+		}
+
+		property.Images = kept
+		arrangeGallery(property)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.deleteImageFiles(removed)
+	return property, nil
+}
+
+// arrangeGallery renumbers the gallery and makes its first photo the cover
+func arrangeGallery(property *models.Housing) {
+	sort.SliceStable(property.Images, func(i, j int) bool {
+		return property.Images[i].Order < property.Images[j].Order
+	})
+	for i := range property.Images {
+		property.Images[i].Order = i
+	}
+	if len(property.Images) > 0 {
+		property.Image = property.Images[0].URL
+	}
+}
+
+// deleteImageFiles removes the stored files of images. Failures are only
+// logged since the listing no longer references the files
+func (s *HousingService) deleteImageFiles(images []models.HousingImage) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, img := range images {
+		for _, key := range []string{img.Key, img.ThumbnailKey} {
+			if key == "" {
+				continue
+			}
+			if err := s.blobs.Delete(ctx, key); err != nil {
+				log.Printf("Failed to delete image %s: %v", key, err)
+			}
+		}
+	}
+}
+
+// processImage checks an upload's size, type and dimensions, strips location
+// metadata from it and renders its thumbnail
+func processImage(data []byte) (processedImage, error) {
+	if len(data) > MaxImageBytes {
+		return processedImage{}, ErrImageTooLarge
+	}
+
+	// Trust the file contents rather than the client's content type
+	img := processedImage{contentType: http.DetectContentType(data)}
+	switch img.contentType {
+	case "image/jpeg":
+		img.data = utils.StripJPEGMetadata(data)
+		img.extension = ".jpg"
+	case "image/png":
+		img.data = utils.StripPNGMetadata(data)
+		img.extension = ".png"
+	default:
+		return processedImage{}, ErrUnsupportedImage
+	}
+
+	// Check the dimensions before decoding so a small file cannot expand
+	// into a huge bitmap
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(img.data))
+	if err != nil {
+		return processedImage{}, ErrUnsupportedImage
+	}
+	if cfg.Width*cfg.Height > MaxImagePixels {
+		return processedImage{}, ErrImageTooLarge
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(img.data))
+	if err != nil {
+		return processedImage{}, ErrUnsupportedImage
+	}
+	img.width, img.height = cfg.Width, cfg.Height
+
+	// Re-encoding drops all metadata from the thumbnail
+	var thumb bytes.Buffer
+	if err := jpeg.Encode(&thumb, utils.Thumbnail(decoded, ThumbnailSize), &jpeg.Options{Quality: 80}); err != nil {
+		return processedImage{}, err
+	}
+	img.thumbnail = thumb.Bytes()
+
+	return img, nil
+}
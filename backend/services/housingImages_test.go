@@ -0,0 +1,141 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gatorswamp/blobstore"
+	"gatorswamp/repositories/memoryrepo"
+	"gatorswamp/services"
+)
+
+// pngImage encodes a width by height PNG
+func pngImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 200
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// hugePNG returns a PNG header claiming dimensions far beyond the pixel
+// limit, without the pixels
+func hugePNG() []byte {
+	header := binary.BigEndian.AppendUint32(nil, 13)
+	header = append(header, "IHDR"...)
+	header = binary.BigEndian.AppendUint32(header, 100000)
+	header = binary.BigEndian.AppendUint32(header, 100000)
+	header = append(header, 8, 0, 0, 0, 0)
+	header = binary.BigEndian.AppendUint32(header, crc32.ChecksumIEEE(header[4:]))
+	return append([]byte("\x89PNG\r\n\x1a\n"), header...)
+}
+
+func TestAddImages(t *testing.T) {
+	svc := newServices(t)
+	property := createListing(t, svc, "Pool House")
+
+	updated, err := svc.Housing.AddImages(property.ID.Hex(), []services.ImageUpload{
+		{Data: pngImage(t, 800, 200), Caption: "Front"},
+		{Data: pngImage(t, 10, 10)},
+	})
+	if err != nil {
+		t.Fatalf("AddImages() error = %v", err)
+	}
+	if len(updated.Images) != 2 {
+		t.Fatalf("gallery has %d images, want 2", len(updated.Images))
+	}
+	first := updated.Images[0]
+	if first.Caption != "Front" || first.Width != 800 || first.Height != 200 || first.ContentType != "image/png" {
+		t.Errorf("first image = %+v", first)
+	}
+	if first.ThumbnailURL == "" || first.ThumbnailURL == first.URL {
+		t.Errorf("thumbnail URL = %q, want one of its own", first.ThumbnailURL)
+	}
+	if updated.Image != first.URL {
+		t.Errorf("cover = %q, want the first image %q", updated.Image, first.URL)
+	}
+}
+
+func TestAddImagesRejects(t *testing.T) {
+	small := pngImage(t, 10, 10)
+
+	tests := []struct {
+		name    string
+		uploads []services.ImageUpload
+		want    error
+	}{
+		{"none", nil, services.ErrNoImages},
+		{"not an image", []services.ImageUpload{{Data: []byte("just some text")}}, services.ErrUnsupportedImage},
+		{"gif", []services.ImageUpload{{Data: []byte("GIF89a\x01\x00\x01\x00")}}, services.ErrUnsupportedImage},
+		{"truncated", []services.ImageUpload{{Data: []byte("\x89PNG\r\n\x1a\n")}}, services.ErrUnsupportedImage},
+		{"too many pixels", []services.ImageUpload{{Data: hugePNG()}}, services.ErrImageTooLarge},
+		{"too many bytes", []services.ImageUpload{{Data: make([]byte, services.MaxImageBytes+1)}}, services.ErrImageTooLarge},
+		{"too many images", make([]services.ImageUpload, services.MaxHousingImages+1), services.ErrTooManyImages},
+		{"long caption", []services.ImageUpload{{Data: small, Caption: strings.Repeat("é", services.MaxCaptionLength+1)}}, services.ErrValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newServices(t)
+			property := createListing(t, svc, "Pool House")
+
+			// A valid image ahead of the invalid one is not stored either
+			uploads := tt.uploads
+			if len(uploads) > 0 && len(uploads) <= services.MaxHousingImages {
+				uploads = append([]services.ImageUpload{{Data: small}}, uploads...)
+			}
+
+			if _, err := svc.Housing.AddImages(property.ID.Hex(), uploads); !errors.Is(err, tt.want) {
+				t.Fatalf("AddImages() error = %v, want %v", err, tt.want)
+			}
+			stored, err := svc.Housing.GetPropertyByID(property.ID.Hex())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(stored.Images) != 0 {
+				t.Errorf("gallery has %d images after a rejected upload, want 0", len(stored.Images))
+			}
+		})
+	}
+}
+
+func TestAddImagesThumbnail(t *testing.T) {
+	dir := t.TempDir()
+	svc := services.New(memoryrepo.NewRepositories(), blobstore.NewLocalStore(dir, "/uploads"))
+	property := createListing(t, svc, "Pool House")
+
+	updated, err := svc.Housing.AddImages(property.ID.Hex(), []services.ImageUpload{{Data: pngImage(t, 1600, 400)}})
+	if err != nil {
+		t.Fatalf("AddImages() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, updated.Images[0].ThumbnailKey))
+	if err != nil {
+		t.Fatalf("reading thumbnail: %v", err)
+	}
+	thumb, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("thumbnail does not decode: %v", err)
+	}
+	if got := thumb.Bounds().Size(); got != image.Pt(services.ThumbnailSize, 100) {
+		t.Errorf("thumbnail size = %v, want %dx100", got, services.ThumbnailSize)
+	}
+	if r, _, _, _ := thumb.At(10, 10).RGBA(); r>>8 < 190 || r>>8 > 210 {
+		t.Errorf("thumbnail colour = %v, want the grey of the photo", color.GrayModel.Convert(thumb.At(10, 10)))
+	}
+}
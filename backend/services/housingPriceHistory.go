@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PriceDropDays is how many days back price changes count towards the price
+// drop shown on a listing
+const PriceDropDays = 30
+
+// GetPriceHistory retrieves the price changes of a property, newest first
+func (s *HousingService) GetPriceHistory(id string) ([]models.PriceChange, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	property, err := s.GetPropertyByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := s.priceHistory.FindByProperty(ctx, property.ID)
+	if err != nil {
+		return nil, err
+	}
+	if changes == nil {
+		changes = []models.PriceChange{}
+	}
+
+	return changes, nil
+}
+
+// recordPriceChange adds a price history entry when an update changed the
+// price or currency of a listing. The update is already saved, so a failure
+// is only logged
+func (s *HousingService) recordPriceChange(ctx context.Context, before, after models.Housing, actorID primitive.ObjectID) {
+	if before.PriceCents == after.PriceCents && before.Currency == after.Currency {
+		return
+	}
+
+	change := models.PriceChange{
+		ID:            primitive.NewObjectID(),
+		PropertyID:    after.ID,
+		OldPriceCents: before.PriceCents,
+		OldCurrency:   before.Currency,
+		NewPriceCents: after.PriceCents,
+		NewCurrency:   after.Currency,
+		ChangedBy:     actorID,
+		ChangedAt:     after.UpdatedAt,
+	}
+	if err := s.priceHistory.Insert(ctx, change); err != nil {
+		log.Printf("Failed to record price change of property %s: %v", after.ID.Hex(), err)
+	}
+}
+
+// AddPriceDrops sets the PriceDrop of every listing from its price changes
+// of the last PriceDropDays days, using a single query. Only the handlers
+// serving listings to clients call it, so that internal lookups stay a
+// single read. The badge is not essential, so a failure is only logged
+func (s *HousingService) AddPriceDrops(properties ...*models.Housing) {
+	if len(properties) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ids := make([]primitive.ObjectID, len(properties))
+	for i, property := range properties {
+		ids[i] = property.ID
+	}
+
+	since := time.Now().AddDate(0, 0, -PriceDropDays)
+	changes, err := s.priceHistory.FindSince(ctx, ids, since)
+	if err != nil {
+		log.Printf("Failed to load price history: %v", err)
+		return
+	}
+
+	// The oldest change in the window holds the price the window started at
+	startPrices := make(map[primitive.ObjectID]models.PriceChange, len(changes))
+	for _, change := range changes {
+		if _, ok := startPrices[change.PropertyID]; !ok {
+			startPrices[change.PropertyID] = change
+		}
+	}
+
+	for _, property := range properties {
+		property.PriceDrop = nil
+		start, ok := startPrices[property.ID]
+		if !ok {
+			continue
+		}
+		property.PriceDrop = priceDrop(start, *property)
+	}
+}
+
+// priceDrop compares the price a listing had before start with its current
+// price, returning nil unless the price fell in the same currency
+func priceDrop(start models.PriceChange, property models.Housing) *models.PriceDrop {
+	if start.OldCurrency != property.Currency || start.OldPriceCents <= property.PriceCents {
+		return nil
+	}
+
+	reduction := float64(start.OldPriceCents-property.PriceCents) / float64(start.OldPriceCents) * 100
+	return &models.PriceDrop{
+		Percent:        math.Round(reduction*10) / 10,
+		FromPriceCents: start.OldPriceCents,
+		Days:           PriceDropDays,
+	}
+}
+
+// ListingsOf returns pointers to the elements of properties
+func ListingsOf(properties []models.Housing) []*models.Housing {
+	listings := make([]*models.Housing, len(properties))
+	for i := range properties {
+		listings[i] = &properties[i]
+	}
+	return listings
+}
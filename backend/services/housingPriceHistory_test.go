@@ -0,0 +1,109 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gatorswamp/blobstore"
+	"gatorswamp/models"
+	"gatorswamp/repositories/memoryrepo"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPriceHistory(t *testing.T) {
+	svc := newServices(t)
+	agent := createUser(t, svc, "agent@ufl.edu", "Str0ngPass")
+	property := createListing(t, svc, "Pool House")
+
+	update := func(priceCents int64, currency string) {
+		t.Helper()
+		changes := *property
+		changes.PriceCents = priceCents
+		changes.Currency = currency
+		updated, err := svc.Housing.UpdateProperty(property.ID.Hex(), changes, agent.ID)
+		if err != nil {
+			t.Fatalf("UpdateProperty() error = %v", err)
+		}
+		property = updated
+	}
+
+	update(90000, "USD")
+	update(90000, "USD")
+	update(90000, "EUR")
+
+	changes, err := svc.Housing.GetPriceHistory(property.ID.Hex())
+	if err != nil {
+		t.Fatalf("GetPriceHistory() error = %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("price history has %d changes, want 2: %+v", len(changes), changes)
+	}
+	newest, oldest := changes[0], changes[1]
+	if newest.OldCurrency != "USD" || newest.NewCurrency != "EUR" {
+		t.Errorf("newest change = %+v, want USD to EUR", newest)
+	}
+	if oldest.OldPriceCents != 100000 || oldest.NewPriceCents != 90000 || oldest.ChangedBy.Hex() != agent.ID {
+		t.Errorf("oldest change = %+v, want 100000 to 90000 by the agent", oldest)
+	}
+}
+
+func TestAddPriceDrops(t *testing.T) {
+	now := time.Now()
+	daysAgo := func(days int) primitive.DateTime {
+		return primitive.NewDateTimeFromTime(now.AddDate(0, 0, -days))
+	}
+	change := func(days int, oldPrice, newPrice int64) models.PriceChange {
+		return models.PriceChange{OldPriceCents: oldPrice, OldCurrency: "USD", NewPriceCents: newPrice, NewCurrency: "USD", ChangedAt: daysAgo(days)}
+	}
+
+	tests := []struct {
+		name     string
+		price    int64
+		currency string
+		changes  []models.PriceChange
+		want     *models.PriceDrop
+	}{
+		{"no changes", 90000, "USD", nil, nil},
+		{"dropped", 90000, "USD", []models.PriceChange{change(5, 100000, 90000)}, &models.PriceDrop{Percent: 10, FromPriceCents: 100000, Days: services.PriceDropDays}},
+		{"rounded", 66666, "USD", []models.PriceChange{change(5, 100000, 66666)}, &models.PriceDrop{Percent: 33.3, FromPriceCents: 100000, Days: services.PriceDropDays}},
+		{"from the start of the window", 90000, "USD", []models.PriceChange{change(20, 120000, 80000), change(2, 80000, 90000)}, &models.PriceDrop{Percent: 25, FromPriceCents: 120000, Days: services.PriceDropDays}},
+		{"raised", 110000, "USD", []models.PriceChange{change(5, 100000, 110000)}, nil},
+		{"dropped then raised back", 100000, "USD", []models.PriceChange{change(9, 100000, 90000), change(3, 90000, 100000)}, nil},
+		{"older than the window", 90000, "USD", []models.PriceChange{change(services.PriceDropDays+1, 100000, 90000)}, nil},
+		{"other currency", 90000, "EUR", []models.PriceChange{{OldPriceCents: 100000, OldCurrency: "USD", NewPriceCents: 90000, NewCurrency: "EUR", ChangedAt: daysAgo(5)}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos := memoryrepo.NewRepositories()
+			svc := services.New(repos, blobstore.NewLocalStore(t.TempDir(), "/uploads"))
+			property, err := svc.Housing.CreateProperty(models.Housing{Name: "Pool House", Address: "1 Main St", PriceCents: tt.price, Currency: tt.currency})
+			if err != nil {
+				t.Fatalf("CreateProperty() error = %v", err)
+			}
+			for _, change := range tt.changes {
+				change.ID = primitive.NewObjectID()
+				change.PropertyID = property.ID
+				if err := repos.PriceHistory.Insert(context.Background(), change); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// A listing without history in the same call is left alone
+			other := &models.Housing{ID: primitive.NewObjectID(), PriceCents: 1}
+			svc.Housing.AddPriceDrops(property, other)
+
+			if other.PriceDrop != nil {
+				t.Errorf("listing without history has price drop %+v", other.PriceDrop)
+			}
+			switch {
+			case tt.want == nil && property.PriceDrop != nil:
+				t.Errorf("PriceDrop = %+v, want none", property.PriceDrop)
+			case tt.want != nil && (property.PriceDrop == nil || *property.PriceDrop != *tt.want):
+				t.Errorf("PriceDrop = %+v, want %+v", property.PriceDrop, tt.want)
+			}
+		})
+	}
+}
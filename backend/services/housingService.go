@@ -2,48 +2,142 @@ package services
 
 import (
 	"context"
-	"errors"
+	"log"
+	"slices"
 	"time"
 
+	"gatorswamp/blobstore"
 	"gatorswamp/models"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // HousingService handles business logic for housing properties
 type HousingService struct {
-	collection *mongo.Collection
+	repo         HousingRepository
+	favorites    FavoriteRepository
+	priceHistory PriceHistoryRepository
+	users        UserRepository
+	blobs        blobstore.BlobStore
+	// listeners are called with every listing that is created or updated
+	listeners []func(models.Housing)
+	// deleteListeners are called with every listing that is deleted
+	deleteListeners []func(models.Housing)
 }
 
-// NewHousingService creates a new housing service
-func NewHousingService(collection *mongo.Collection) *HousingService {
+// NewHousingService creates a new housing service storing gallery images in
+// blobs and price changes in priceHistory. Favorites and price history of
+// deleted listings are removed, and agents are looked up in users
+func NewHousingService(repo HousingRepository, favorites FavoriteRepository, priceHistory PriceHistoryRepository, users UserRepository, blobs blobstore.BlobStore) *HousingService {
 	return &HousingService{
-		collection: collection,
+		repo:         repo,
+		favorites:    favorites,
+		priceHistory: priceHistory,
+		users:        users,
+		blobs:        blobs,
 	}
 }
 
-// GetAllProperties retrieves all properties with optional filtering
-func (s *HousingService) GetAllProperties(filter bson.M) ([]models.Housing, error) {
+// Subscribe registers fn to be called with every listing that is created or
+// updated. It must be called before the service handles requests
+func (s *HousingService) Subscribe(fn func(models.Housing)) {
+	s.listeners = append(s.listeners, fn)
+}
+
+// listingSaved passes a created or updated listing to the listeners
+func (s *HousingService) listingSaved(property models.Housing) {
+	for _, fn := range s.listeners {
+		fn(property)
+	}
+}
+
+// SubscribeDeleted registers fn to be called with every listing that is
+// deleted. It must be called before the service handles requests
+func (s *HousingService) SubscribeDeleted(fn func(models.Housing)) {
+	s.deleteListeners = append(s.deleteListeners, fn)
+}
+
+// PropertyPage is a single page of properties plus the cursor for the next one
+type PropertyPage struct {
+	Items      []models.Housing `json:"items"`
+	NextCursor string           `json:"nextCursor,omitempty"`
+	Total      int64            `json:"total"`
+}
+
+// HousingFacets lists the values listings take for the fields the listing
+// filters offer a choice of
+type HousingFacets struct {
+	Counties []string `json:"counties"`
+	Types    []string `json:"types"`
+}
+
+// GetAllProperties retrieves one page of properties matching the filter
+func (s *HousingService) GetAllProperties(filter HousingFilter, page PageRequest) (*PropertyPage, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	findOptions := options.Find()
-	findOptions.SetSort(bson.D{{Key: "createdAt", Value: -1}}) // Sort by creation date (newest first)
+	page, err := page.Normalize()
+	if err != nil {
+		return nil, err
+	}
 
-	cursor, err := s.collection.Find(ctx, filter, findOptions)
+	var after *PageCursor
+	if page.Cursor != "" {
+		if after, err = decodeCursor(page); err != nil {
+			return nil, err
+		}
+	}
+
+	total, err := s.repo.Count(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
 
-	var properties []models.Housing
-	if err = cursor.All(ctx, &properties); err != nil {
+	// Fetch one extra listing to find out whether another page exists
+	properties, err := s.repo.List(ctx, filter, page, after, page.Limit+1)
+	if err != nil {
 		return nil, err
 	}
 
-	return properties, nil
+	result := &PropertyPage{Items: properties, Total: total}
+	if len(properties) > page.Limit {
+		result.Items = properties[:page.Limit]
+		if result.NextCursor, err = encodeCursor(page, result.Items[page.Limit-1]); err != nil {
+			return nil, err
+		}
+	}
+	if result.Items == nil {
+		result.Items = []models.Housing{}
+	}
+
+	return result, nil
+}
+
+// GetFacets returns the counties and types of the listings, sorted, for
+// filling filter menus without loading every listing
+func (s *HousingService) GetFacets() (*HousingFacets, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	facets, err := s.repo.Facets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	facets.Counties = sortedValues(facets.Counties)
+	facets.Types = sortedValues(facets.Types)
+	return facets, nil
+}
+
+// sortedValues returns the non-empty values sorted, without duplicates
+func sortedValues(values []string) []string {
+	result := []string{}
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	slices.Sort(result)
+	return slices.Compact(result)
 }
 
 // GetPropertyByID retrieves a single property by ID
@@ -53,19 +147,54 @@ func (s *HousingService) GetPropertyByID(id string) (*models.Housing, error) {
 
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, errors.New("invalid ID format")
+		return nil, invalidID("property")
 	}
 
-	var property models.Housing
-	err = s.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&property)
+	property, err := s.repo.FindByID(ctx, objID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("property not found")
+		if err == ErrNotFound {
+			return nil, ErrPropertyNotFound
 		}
 		return nil, err
 	}
 
-	return &property, nil
+	return property, nil
+}
+
+// maxListingSaves is how many times an edit of a listing is applied before
+// giving up when other writes keep saving the listing first
+const maxListingSaves = 3
+
+// saveListing reads a listing, applies edit to it and saves it, returning
+// the listing as read and as saved. When another write saved the listing in
+// between, edit is applied again to the fresh listing, so that concurrent
+// edits keep each other's changes
+func (s *HousingService) saveListing(ctx context.Context, id string, edit func(property *models.Housing) error) (models.Housing, *models.Housing, error) {
+	for attempt := 0; attempt < maxListingSaves; attempt++ {
+		property, err := s.GetPropertyByID(id)
+		if err != nil {
+			return models.Housing{}, nil, err
+		}
+		before := *property
+
+		if err := edit(property); err != nil {
+			return models.Housing{}, nil, err
+		}
+		property.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+		switch err := s.repo.Replace(ctx, *property); err {
+		case nil:
+			property.Version++
+			return before, property, nil
+		case ErrConflict:
+			continue
+		case ErrNotFound:
+			return models.Housing{}, nil, ErrPropertyNotFound
+		default:
+			return models.Housing{}, nil, err
+		}
+	}
+	return models.Housing{}, nil, ErrListingChanged
 }
 
 // CreateProperty creates a new property listing
@@ -73,96 +202,118 @@ func (s *HousingService) CreateProperty(property models.Housing) (*models.Housin
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if property.Currency == "" {
+		property.Currency = models.DefaultCurrency
+	}
+
+	property.Location = models.LocationOf(property.Latitude, property.Longitude)
+
+	if err := s.assignAgent(ctx, &property); err != nil {
+		return nil, err
+	}
+
 	// Set metadata
 	property.ID = primitive.NewObjectID()
 	property.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
 	property.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
 
-	_, err := s.collection.InsertOne(ctx, property)
+	err := s.repo.Insert(ctx, property)
 	if err != nil {
 		return nil, err
 	}
 
+	s.listingSaved(property)
 	return &property, nil
 }
 
-// UpdateProperty updates an existing property
-func (s *HousingService) UpdateProperty(id string, updates bson.M) (*models.Housing, error) {
+// UpdateProperty replaces the editable fields of an existing property on
+// behalf of actorID, recording any price change in the price history. The
+// listing keeps its agent account unless changes names another one
+func (s *HousingService) UpdateProperty(id string, changes models.Housing, actorID string) (*models.Housing, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	objID, err := primitive.ObjectIDFromHex(id)
+	actorObjID, err := primitive.ObjectIDFromHex(actorID)
 	if err != nil {
-		return nil, errors.New("invalid ID format")
+		return nil, invalidID("user")
 	}
 
-	// Add updated timestamp
-	if updates["$set"] != nil {
-		updates["$set"].(bson.M)["updatedAt"] = primitive.NewDateTimeFromTime(time.Now())
+	if changes.Currency == "" {
+		changes.Currency = models.DefaultCurrency
 	}
 
-	_, err = s.collection.UpdateOne(
-		ctx,
-		bson.M{"_id": objID},
-		updates,
-	)
+	before, property, err := s.saveListing(ctx, id, func(property *models.Housing) error {
+		applyChanges(property, changes)
+		return s.assignAgent(ctx, property)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Get the updated property
-	var property models.Housing
-	err = s.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&property)
-	if err != nil {
-		return nil, err
+	s.recordPriceChange(ctx, before, *property, actorObjID)
+	s.listingSaved(*property)
+	return property, nil
+}
+
+// applyChanges copies the editable fields of changes onto a listing
+func applyChanges(property *models.Housing, changes models.Housing) {
+	property.Type = changes.Type
+	property.Name = changes.Name
+	// Once a gallery exists the cover always comes from it
+	if len(property.Images) == 0 {
+		property.Image = changes.Image
+	}
+	property.County = changes.County
+	property.Address = changes.Address
+	property.Description = changes.Description
+	property.Bedrooms = changes.Bedrooms
+	property.Bathrooms = changes.Bathrooms
+	property.Surface = changes.Surface
+	property.Year = changes.Year
+	property.PriceCents = changes.PriceCents
+	property.Currency = changes.Currency
+	property.Latitude = changes.Latitude
+	property.Longitude = changes.Longitude
+	property.Agent = changes.Agent
+	if changes.AgentID != nil {
+		property.AgentID = changes.AgentID
 	}
 
-	return &property, nil
+	// Keep the GeoJSON location in sync
+	property.Location = models.LocationOf(property.Latitude, property.Longitude)
 }
 
-// DeleteProperty removes a property listing
+// DeleteProperty removes a property listing, its gallery images and the
+// favorites pointing at it
 func (s *HousingService) DeleteProperty(id string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	objID, err := primitive.ObjectIDFromHex(id)
+	property, err := s.GetPropertyByID(id)
 	if err != nil {
-		return errors.New("invalid ID format")
+		return err
 	}
 
-	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": objID})
+	err = s.repo.Delete(ctx, property.ID)
 	if err != nil {
+		if err == ErrNotFound {
+			return ErrPropertyNotFound
+		}
 		return err
 	}
 
-	if result.DeletedCount == 0 {
-		return errors.New("property not found")
-	}
-
-	return nil
-}
-
-// SearchProperties searches for properties based on various criteria
-func (s *HousingService) SearchProperties(searchParams bson.M) ([]models.Housing, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	s.deleteImageFiles(property.Images)
 
-	// Build search filter
-	filter := bson.M{}
-	for key, value := range searchParams {
-		filter[key] = value
+	// The listing is gone either way, so a failure only leaves favorites
+	// that are skipped when listed
+	if _, err := s.favorites.DeleteByProperty(ctx, property.ID); err != nil {
+		log.Printf("Failed to delete favorites of property %s: %v", property.ID.Hex(), err)
 	}
-
-	cursor, err := s.collection.Find(ctx, filter)
-	if err != nil {
-		return nil, err
+	if err := s.priceHistory.DeleteByProperty(ctx, property.ID); err != nil {
+		log.Printf("Failed to delete price history of property %s: %v", property.ID.Hex(), err)
 	}
-	defer cursor.Close(ctx)
-
-	var properties []models.Housing
-	if err = cursor.All(ctx, &properties); err != nil {
-		return nil, err
+	for _, fn := range s.deleteListeners {
+		fn(*property)
 	}
-
-	return properties, nil
+	return nil
 }
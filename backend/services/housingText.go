@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"html"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gatorswamp/models"
+)
+
+// Text search limits
+const (
+	DefaultTextLimit = 50
+	MaxTextLimit     = 200
+	MaxTextQueryLen  = 200
+	// MinPrefixLen is the shortest prefix a search may use, so that a single
+	// letter does not match nearly every listing
+	MinPrefixLen = 2
+	// MaxTextCandidates is how many of the newest listings matching a search
+	// made only of prefixes are scored, since no index can rank them
+	MaxTextCandidates = 1000
+	// snippetRadius is how many characters of context a description snippet
+	// keeps around its first match
+	snippetRadius = 60
+)
+
+// TextField is a listing field covered by the free-text search, named by its
+// document field, with the weight of a match in it
+type TextField struct {
+	Name   string
+	Weight int
+	value  func(models.Housing) string
+}
+
+// TextFields lists the searched fields, most relevant first. The MongoDB
+// text index uses the same weights
+var TextFields = []TextField{
+	{"name", 10, func(h models.Housing) string { return h.Name }},
+	{"address", 5, func(h models.Housing) string { return h.Address }},
+	{"county", 3, func(h models.Housing) string { return h.County }},
+	{"description", 1, func(h models.Housing) string { return h.Description }},
+}
+
+// TextQuery is a parsed free-text search. Quoted phrases must all appear,
+// words ending in * match any word they start, and plain terms rank the
+// listings that contain any of them. Everything is lower case
+type TextQuery struct {
+	Terms    []string
+	Phrases  []string
+	Prefixes []string
+}
+
+// HousingMatch is a listing found by a free-text search with its relevance
+// score and the fields that matched, marked up with <mark> tags
+type HousingMatch struct {
+	models.Housing `bson:",inline"`
+	Score          float64           `bson:"score" json:"score"`
+	Highlights     map[string]string `bson:"-" json:"highlights,omitempty"`
+}
+
+// ErrEmptyTextQuery is returned for a search query without any word
+var ErrEmptyTextQuery = InvalidField("q", "required", "q must contain at least one word")
+
+// ParseTextQuery parses a search query such as
+//
+//	"pool house" campus gain*
+func ParseTextQuery(q string) (TextQuery, error) {
+	var query TextQuery
+	if len(q) > MaxTextQueryLen {
+		return query, InvalidField("q", "max", "q must be at most %d characters", MaxTextQueryLen)
+	}
+
+	// Odd pieces are the quoted phrases; an unclosed quote runs to the end
+	for i, piece := range strings.Split(q, `"`) {
+		if i%2 == 1 {
+			if words := textWords(piece); len(words) > 0 {
+				query.Phrases = append(query.Phrases, strings.Join(wordTexts(words), " "))
+			}
+			continue
+		}
+
+		for _, field := range strings.Fields(piece) {
+			words := wordTexts(textWords(field))
+			if len(words) == 0 {
+				continue
+			}
+			if strings.HasSuffix(field, "*") {
+				// Only the last word of "st.john*" is a prefix
+				last := words[len(words)-1]
+				if len([]rune(last)) >= MinPrefixLen {
+					query.Prefixes = append(query.Prefixes, last)
+				}
+				words = words[:len(words)-1]
+			}
+			query.Terms = append(query.Terms, words...)
+		}
+	}
+
+	if len(query.Terms) == 0 && len(query.Phrases) == 0 && len(query.Prefixes) == 0 {
+		return query, ErrEmptyTextQuery
+	}
+	return query, nil
+}
+
+// SearchString returns the terms and quoted phrases in the syntax of a
+// MongoDB $text search, or an empty string when the query only has prefixes
+func (q TextQuery) SearchString() string {
+	parts := append([]string(nil), q.Terms...)
+	for _, phrase := range q.Phrases {
+		parts = append(parts, `"`+phrase+`"`)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Matches reports whether property satisfies the query: every phrase and
+// prefix appears in some field, and so does a term unless phrases decide
+func (q TextQuery) Matches(property models.Housing) bool {
+	found := func(match func(words []textWord, i int) int) bool {
+		for _, field := range TextFields {
+			words := textWords(field.value(property))
+			for i := range words {
+				if match(words, i) > 0 {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for _, phrase := range q.Phrases {
+		if !found(TextQuery{Phrases: []string{phrase}}.matchAt) {
+			return false
+		}
+	}
+	for _, prefix := range q.Prefixes {
+		if !found(TextQuery{Prefixes: []string{prefix}}.matchAt) {
+			return false
+		}
+	}
+	if len(q.Phrases) == 0 && len(q.Terms) > 0 {
+		return found(TextQuery{Terms: q.Terms}.matchAt)
+	}
+	return true
+}
+
+// Score returns the weighted number of matches of the query in property
+func (q TextQuery) Score(property models.Housing) float64 {
+	score := 0
+	for _, field := range TextFields {
+		score += field.Weight * len(q.spans(field.value(property)))
+	}
+	return float64(score)
+}
+
+// Highlights returns the fields of property that match the query with their
+// matches wrapped in <mark> tags and the rest HTML-escaped. Descriptions are
+// cut down to a snippet around the first match
+func (q TextQuery) Highlights(property models.Housing) map[string]string {
+	var highlights map[string]string
+	for _, field := range TextFields {
+		value := field.value(property)
+		spans := q.spans(value)
+		if len(spans) == 0 {
+			continue
+		}
+
+		start, end := 0, len(value)
+		if field.Name == "description" {
+			start, end = snippetBounds(value, spans[0])
+		}
+		if highlights == nil {
+			highlights = map[string]string{}
+		}
+		highlights[field.Name] = markSpans(value, spans, start, end)
+	}
+	return highlights
+}
+
+// textWord is a word of a field and its byte offsets
+type textWord struct {
+	text       string
+	start, end int
+}
+
+// textWords splits s into lower case words of letters and digits
+func textWords(s string) []textWord {
+	var words []textWord
+	start := -1
+	for i, r := range s {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			words = append(words, textWord{strings.ToLower(s[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, textWord{strings.ToLower(s[start:]), start, len(s)})
+	}
+	return words
+}
+
+// wordTexts returns the text of each word
+func wordTexts(words []textWord) []string {
+	texts := make([]string, len(words))
+	for i, word := range words {
+		texts[i] = word.text
+	}
+	return texts
+}
+
+// matchAt returns how many words starting at words[i] match the query, or
+// zero. Terms also match longer forms of a word, such as "apartments" for
+// "apartment", much like the stemming of the MongoDB text index
+func (q TextQuery) matchAt(words []textWord, i int) int {
+	for _, phrase := range q.Phrases {
+		parts := strings.Fields(phrase)
+		if i+len(parts) > len(words) {
+			continue
+		}
+		matched := true
+		for j, part := range parts {
+			if words[i+j].text != part {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return len(parts)
+		}
+	}
+
+	for _, term := range q.Terms {
+		if strings.HasPrefix(words[i].text, term) {
+			return 1
+		}
+	}
+	for _, prefix := range q.Prefixes {
+		if strings.HasPrefix(words[i].text, prefix) {
+			return 1
+		}
+	}
+	return 0
+}
+
+// textSpan is a matched range of bytes
+type textSpan struct {
+	start, end int
+}
+
+// spans returns the ranges of s that match the query, in order
+func (q TextQuery) spans(s string) []textSpan {
+	var spans []textSpan
+	words := textWords(s)
+	for i := 0; i < len(words); i++ {
+		if n := q.matchAt(words, i); n > 0 {
+			spans = append(spans, textSpan{words[i].start, words[i+n-1].end})
+			i += n - 1
+		}
+	}
+	return spans
+}
+
+// snippetBounds returns the byte range of s reaching snippetRadius
+// characters either side of span, widened to whole words
+func snippetBounds(s string, span textSpan) (int, int) {
+	start := span.start
+	for n := 0; start > 0; n++ {
+		r, size := utf8.DecodeLastRuneInString(s[:start])
+		if n >= snippetRadius && unicode.IsSpace(r) {
+			break
+		}
+		start -= size
+	}
+
+	end := span.end
+	for n := 0; end < len(s); n++ {
+		r, size := utf8.DecodeRuneInString(s[end:])
+		if n >= snippetRadius && unicode.IsSpace(r) {
+			break
+		}
+		end += size
+	}
+	return start, end
+}
+
+// markSpans HTML-escapes s[start:end] and wraps the spans inside it in
+// <mark> tags, adding an ellipsis where the text was cut
+func markSpans(s string, spans []textSpan, start, end int) string {
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+
+	pos := start
+	for _, span := range spans {
+		if span.start < start || span.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(s[pos:span.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(s[span.start:span.end]))
+		b.WriteString("</mark>")
+		pos = span.end
+	}
+	b.WriteString(html.EscapeString(s[pos:end]))
+
+	if end < len(s) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// SortMatches orders matches by descending score, newest first on ties
+func SortMatches(matches []HousingMatch) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].CreatedAt > matches[j].CreatedAt
+	})
+}
+
+// SearchPropertiesText returns up to limit properties matching both the
+// free-text query and filter, most relevant first, with highlighted matches
+func (s *HousingService) SearchPropertiesText(text TextQuery, filter HousingFilter, limit int) ([]HousingMatch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if limit <= 0 {
+		limit = DefaultTextLimit
+	}
+	if limit > MaxTextLimit {
+		limit = MaxTextLimit
+	}
+
+	matches, err := s.repo.TextSearch(ctx, text, filter, limit)
+	if err != nil {
+		return nil, err
+	}
+	if matches == nil {
+		matches = []HousingMatch{}
+	}
+
+	for i := range matches {
+		matches[i].Highlights = text.Highlights(matches[i].Housing)
+	}
+	return matches, nil
+}
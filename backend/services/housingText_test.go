@@ -0,0 +1,172 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gatorswamp/models"
+)
+
+func TestParseTextQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		q       string
+		want    TextQuery
+		wantErr bool
+	}{
+		{"terms", "Pool House", TextQuery{Terms: []string{"pool", "house"}}, false},
+		{"phrase", `"pool house" campus`, TextQuery{Terms: []string{"campus"}, Phrases: []string{"pool house"}}, false},
+		{"unclosed quote", `campus "pool house`, TextQuery{Terms: []string{"campus"}, Phrases: []string{"pool house"}}, false},
+		{"prefix", "gain* pool", TextQuery{Terms: []string{"pool"}, Prefixes: []string{"gain"}}, false},
+		{"last word is the prefix", "st.john*", TextQuery{Terms: []string{"st"}, Prefixes: []string{"john"}}, false},
+		{"short prefix dropped", "g* pool", TextQuery{Terms: []string{"pool"}}, false},
+		{"punctuation split", "2-bed, near-UF", TextQuery{Terms: []string{"2", "bed", "near", "uf"}}, false},
+		{"empty", "", TextQuery{}, true},
+		{"only punctuation", `"" -- *`, TextQuery{}, true},
+		{"only short prefix", "a*", TextQuery{}, true},
+		{"too long", strings.Repeat("a", MaxTextQueryLen+1), TextQuery{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTextQuery(tt.q)
+			if tt.wantErr {
+				if !errors.Is(err, ErrValidation) {
+					t.Fatalf("ParseTextQuery(%q) error = %v, want a validation error", tt.q, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTextQuery(%q) error = %v", tt.q, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTextQuery(%q) = %+v, want %+v", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+// textListing is the listing the text search tests run against
+var textListing = models.Housing{
+	Name:        "Pool House",
+	Address:     "12 University Ave, Gainesville",
+	County:      "Alachua",
+	Description: "Bright house with a swimming pool & garden, five minutes from campus.",
+}
+
+func TestTextQueryMatchesAndScore(t *testing.T) {
+	tests := []struct {
+		q       string
+		matches bool
+		score   float64
+	}{
+		// name 10 + description 1 for each word
+		{"pool", true, 11},
+		{"pool house", true, 22},
+		// "houses" is not a form of "house"
+		{"houses", false, 0},
+		{`"swimming pool"`, true, 1},
+		{`"pool swimming"`, false, 0},
+		{"gain*", true, 5},
+		{"gain* pool", true, 16},
+		// Prefixes narrow the listings down but a term must still match
+		{"gain* beach", false, 5},
+		{"tampa*", false, 0},
+		{"alachua", true, 3},
+		{"beach", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			query, err := ParseTextQuery(tt.q)
+			if err != nil {
+				t.Fatalf("ParseTextQuery(%q) error = %v", tt.q, err)
+			}
+			if got := query.Matches(textListing); got != tt.matches {
+				t.Errorf("Matches() = %v, want %v", got, tt.matches)
+			}
+			if got := query.Score(textListing); got != tt.score {
+				t.Errorf("Score() = %v, want %v", got, tt.score)
+			}
+		})
+	}
+}
+
+func TestTextQueryHighlights(t *testing.T) {
+	long := models.Housing{
+		Name:        "Loft",
+		Description: strings.Repeat("quiet street ", 10) + "rooftop pool <b>" + strings.Repeat(" near shops", 10),
+	}
+
+	tests := []struct {
+		name     string
+		q        string
+		property models.Housing
+		want     map[string]string
+	}{
+		{
+			name:     "fields that match",
+			q:        "pool gain*",
+			property: textListing,
+			want: map[string]string{
+				"name":        "<mark>Pool</mark> House",
+				"address":     "12 University Ave, <mark>Gainesville</mark>",
+				"description": "Bright house with a swimming <mark>pool</mark> &amp; garden, five minutes from campus.",
+			},
+		},
+		{
+			name:     "phrase",
+			q:        `"swimming pool"`,
+			property: textListing,
+			want: map[string]string{
+				"description": "Bright house with a <mark>swimming pool</mark> &amp; garden, five minutes from campus.",
+			},
+		},
+		{
+			name:     "snippet",
+			q:        "rooftop",
+			property: long,
+			want: map[string]string{
+				"description": "…quiet street quiet street quiet street quiet street quiet street <mark>rooftop</mark> pool &lt;b&gt; near shops near shops near shops near shops near shops…",
+			},
+		},
+		{
+			name:     "no match",
+			q:        "beach",
+			property: textListing,
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ParseTextQuery(tt.q)
+			if err != nil {
+				t.Fatalf("ParseTextQuery(%q) error = %v", tt.q, err)
+			}
+			if got := query.Highlights(tt.property); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Highlights() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortMatches(t *testing.T) {
+	matches := []HousingMatch{
+		{Housing: models.Housing{Name: "old", CreatedAt: 1}, Score: 5},
+		{Housing: models.Housing{Name: "best", CreatedAt: 1}, Score: 10},
+		{Housing: models.Housing{Name: "new", CreatedAt: 2}, Score: 5},
+	}
+
+	SortMatches(matches)
+
+	var names []string
+	for _, match := range matches {
+		names = append(names, match.Name)
+	}
+	if want := []string{"best", "new", "old"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("SortMatches() order = %v, want %v", names, want)
+	}
+}
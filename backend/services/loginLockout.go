@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gatorswamp/models"
+)
+
+// Login lockout rules. After LoginLockoutThreshold failed logins for an
+// email the account is locked for LoginLockoutBase, doubling with every
+// further failure up to LoginLockoutMax. Failures are forgotten
+// LoginFailureWindow after the last one, and a successful login or a
+// password reset clears them
+const (
+	LoginLockoutThreshold = 5
+	LoginLockoutBase      = time.Minute
+	LoginLockoutMax       = time.Hour
+	LoginFailureWindow    = 24 * time.Hour
+)
+
+// loginLocked is returned while an email's logins are refused
+func loginLocked(retryAfter time.Duration) *Error {
+	return TooManyRequests("login_locked", "too many failed logins, try again later", retryAfter)
+}
+
+// loginKey normalizes an email address for counting failed logins
+func loginKey(email string) string {
+	return models.NormalizeEmail(email)
+}
+
+// lockoutDuration returns how long failures failed logins lock an account
+func lockoutDuration(failures int) time.Duration {
+	lockout := LoginLockoutBase
+	for i := LoginLockoutThreshold; i < failures && lockout < LoginLockoutMax; i++ {
+		lockout *= 2
+	}
+	return min(lockout, LoginLockoutMax)
+}
+
+// checkLoginLockout returns an error while the logins of email are locked
+func (s *UserService) checkLoginLockout(ctx context.Context, email string, now time.Time) error {
+	attempts, err := s.attempts.Find(ctx, loginKey(email), now)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	if attempts.LockedUntil != nil && attempts.LockedUntil.Time().After(now) {
+		return loginLocked(attempts.LockedUntil.Time().Sub(now))
+	}
+	return nil
+}
+
+// recordFailedLogin counts a failed login for email and locks the account
+// once it reaches the threshold. It returns the lockout error when this
+// failure locked the account, and loginErr otherwise
+func (s *UserService) recordFailedLogin(ctx context.Context, email string, now time.Time, loginErr error) error {
+	key := loginKey(email)
+	attempts, err := s.attempts.RecordFailure(ctx, key, now, LoginFailureWindow)
+	if err != nil {
+		return err
+	}
+
+	if attempts.Failures < LoginLockoutThreshold {
+		return loginErr
+	}
+
+	lockout := lockoutDuration(attempts.Failures)
+	if err := s.attempts.Lock(ctx, key, now.Add(lockout)); err != nil {
+		return err
+	}
+	slog.Warn("login locked",
+		slog.String("email", key),
+		slog.Int("failures", attempts.Failures),
+		slog.Duration("lockout", lockout),
+	)
+	return loginLocked(lockout)
+}
+
+// UnlockLogin forgets the failed logins of a user, lifting any lockout
+func (s *UserService) UnlockLogin(userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	return s.attempts.Clear(ctx, loginKey(user.Email))
+}
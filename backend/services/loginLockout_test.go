@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{LoginLockoutThreshold, time.Minute},
+		{LoginLockoutThreshold + 1, 2 * time.Minute},
+		{LoginLockoutThreshold + 2, 4 * time.Minute},
+		{LoginLockoutThreshold + 5, 32 * time.Minute},
+		{LoginLockoutThreshold + 6, time.Hour},
+		{LoginLockoutThreshold + 100, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := lockoutDuration(tt.failures); got != tt.want {
+			t.Errorf("lockoutDuration(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginKey(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"user@ufl.edu", "user@ufl.edu"},
+		{"User@UFL.edu", "user@ufl.edu"},
+		{"  user@ufl.edu\n", "user@ufl.edu"},
+	}
+
+	for _, tt := range tests {
+		if got := loginKey(tt.email); got != tt.want {
+			t.Errorf("loginKey(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
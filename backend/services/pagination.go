@@ -0,0 +1,131 @@
+package services
+
+import (
+	"encoding/base64"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Pagination defaults
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Sort orders
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// Housing sort keys
+const (
+	SortNewest   = "newest"
+	SortPrice    = "price"
+	SortBedrooms = "bedrooms"
+	SortSurface  = "surface"
+)
+
+// defaultSortOrders maps the public sort keys to their default order
+var defaultSortOrders = map[string]string{
+	SortNewest:   SortDesc,
+	SortPrice:    SortAsc,
+	SortBedrooms: SortDesc,
+	SortSurface:  SortDesc,
+}
+
+// ErrInvalidCursor is returned when a cursor token cannot be decoded or
+// does not belong to the requested sort
+var ErrInvalidCursor = InvalidField("cursor", "invalid", "invalid cursor")
+
+// PageRequest describes which slice of a sorted listing to return
+type PageRequest struct {
+	Limit  int
+	Cursor string
+	Sort   string
+	Order  string
+}
+
+// Normalize applies defaults and validates the sort key, order and limit
+func (p PageRequest) Normalize() (PageRequest, error) {
+	if p.Sort == "" {
+		p.Sort = SortNewest
+	}
+	order, ok := defaultSortOrders[p.Sort]
+	if !ok {
+		return p, InvalidField("sort", "oneof", "invalid sort key")
+	}
+
+	if p.Order == "" {
+		p.Order = order
+	}
+	if p.Order != SortAsc && p.Order != SortDesc {
+		return p, InvalidField("order", "oneof", "invalid sort order")
+	}
+
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		p.Limit = MaxPageLimit
+	}
+
+	return p, nil
+}
+
+// HousingSortValue returns the value a listing is ordered by for a sort key.
+// Ties are broken on the listing ID
+func HousingSortValue(property models.Housing, sort string) float64 {
+	switch sort {
+	case SortPrice:
+		return float64(property.PriceCents)
+	case SortBedrooms:
+		return float64(property.Bedrooms)
+	case SortSurface:
+		return property.Surface
+	default:
+		return float64(property.CreatedAt)
+	}
+}
+
+// PageCursor is the decoded form of an opaque cursor token: the sort value
+// and ID of the last listing on the previous page
+type PageCursor struct {
+	Sort  string             `bson:"s"`
+	Order string             `bson:"o"`
+	Value float64            `bson:"v"`
+	ID    primitive.ObjectID `bson:"id"`
+}
+
+// encodeCursor serialises the position after the given listing
+func encodeCursor(p PageRequest, property models.Housing) (string, error) {
+	data, err := bson.Marshal(PageCursor{
+		Sort:  p.Sort,
+		Order: p.Order,
+		Value: HousingSortValue(property, p.Sort),
+		ID:    property.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor parses a cursor token and checks it matches the page's sort
+func decodeCursor(p PageRequest) (*PageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c PageCursor
+	if err := bson.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != p.Sort || c.Order != p.Order || c.ID.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
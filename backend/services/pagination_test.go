@@ -0,0 +1,116 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPageRequestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    PageRequest
+		want    PageRequest
+		wantErr bool
+	}{
+		{"defaults", PageRequest{}, PageRequest{Limit: DefaultPageLimit, Sort: SortNewest, Order: SortDesc}, false},
+		{"price ascends", PageRequest{Sort: SortPrice}, PageRequest{Limit: DefaultPageLimit, Sort: SortPrice, Order: SortAsc}, false},
+		{"explicit order", PageRequest{Sort: SortPrice, Order: SortDesc, Limit: 5}, PageRequest{Limit: 5, Sort: SortPrice, Order: SortDesc}, false},
+		{"limit capped", PageRequest{Limit: 1000}, PageRequest{Limit: MaxPageLimit, Sort: SortNewest, Order: SortDesc}, false},
+		{"unknown sort", PageRequest{Sort: "name"}, PageRequest{}, true},
+		{"unknown order", PageRequest{Order: "up"}, PageRequest{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.page.Normalize()
+			if tt.wantErr {
+				if !errors.Is(err, ErrValidation) {
+					t.Fatalf("Normalize() error = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	property := models.Housing{
+		ID:         primitive.NewObjectID(),
+		PriceCents: 125000,
+		Bedrooms:   3,
+		Surface:    82.5,
+		CreatedAt:  primitive.DateTime(1700000000000),
+	}
+
+	tests := []struct {
+		sort  string
+		order string
+		value float64
+	}{
+		{SortNewest, SortDesc, 1700000000000},
+		{SortPrice, SortAsc, 125000},
+		{SortBedrooms, SortDesc, 3},
+		{SortSurface, SortAsc, 82.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort+" "+tt.order, func(t *testing.T) {
+			page := PageRequest{Sort: tt.sort, Order: tt.order}
+			token, err := encodeCursor(page, property)
+			if err != nil {
+				t.Fatalf("encodeCursor() error = %v", err)
+			}
+
+			page.Cursor = token
+			cursor, err := decodeCursor(page)
+			if err != nil {
+				t.Fatalf("decodeCursor() error = %v", err)
+			}
+			want := PageCursor{Sort: tt.sort, Order: tt.order, Value: tt.value, ID: property.ID}
+			if *cursor != want {
+				t.Errorf("decodeCursor() = %+v, want %+v", *cursor, want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	property := models.Housing{ID: primitive.NewObjectID(), PriceCents: 100}
+	priceAsc := PageRequest{Sort: SortPrice, Order: SortAsc}
+	token, err := encodeCursor(priceAsc, property)
+	if err != nil {
+		t.Fatalf("encodeCursor() error = %v", err)
+	}
+
+	withoutID, _ := bson.Marshal(PageCursor{Sort: SortPrice, Order: SortAsc, Value: 100})
+
+	tests := []struct {
+		name string
+		page PageRequest
+	}{
+		{"not base64", PageRequest{Sort: SortPrice, Order: SortAsc, Cursor: "!!!"}},
+		{"not bson", PageRequest{Sort: SortPrice, Order: SortAsc, Cursor: base64.RawURLEncoding.EncodeToString([]byte("cursor"))}},
+		{"other sort", PageRequest{Sort: SortNewest, Order: SortAsc, Cursor: token}},
+		{"other order", PageRequest{Sort: SortPrice, Order: SortDesc, Cursor: token}},
+		{"no ID", PageRequest{Sort: SortPrice, Order: SortAsc, Cursor: base64.RawURLEncoding.EncodeToString(withoutID)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.page); err != ErrInvalidCursor {
+				t.Errorf("decodeCursor() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
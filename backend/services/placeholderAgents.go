@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotPlaceholderAgent is returned when claiming an account that is not
+// an unclaimed placeholder agent
+var ErrNotPlaceholderAgent = NewError(ErrConflict, "not_placeholder_agent", "user is not an unclaimed placeholder agent")
+
+// ClaimPlaceholderAgent hands a placeholder agent account created by the
+// embedded agents migration to the agent it stands for. The account gets the
+// agent's email and is enabled, and a password reset link is emailed so the
+// agent can choose a password. Its listings and tours stay with it. Agents
+// who already have an account should instead be given the listings by
+// reassigning them
+func (s *UserService) ClaimPlaceholderAgent(userID string, email string) (*models.Users, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, invalidID("user")
+	}
+
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if !user.IsPlaceholderAgent() {
+		return nil, ErrNotPlaceholderAgent
+	}
+
+	email = models.NormalizeEmail(email)
+	if strings.HasSuffix(email, "@"+models.PlaceholderAgentDomain) {
+		return nil, InvalidField("email", "email", "email must be an address the agent receives mail at")
+	}
+	if _, err := s.repo.FindByEmail(ctx, email); err != ErrNotFound {
+		if err == nil {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+
+	// The password stays unknown until the agent resets it
+	disabled, resetRequired, verified := false, true, false
+	user, err = s.repo.Update(ctx, id, UserPatch{
+		Email:                 &email,
+		Disabled:              &disabled,
+		PasswordResetRequired: &resetRequired,
+		EmailVerified:         &verified,
+	})
+	if err != nil {
+		switch err {
+		case ErrNotFound:
+			return nil, ErrUserNotFound
+		case ErrConflict:
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+
+	// The account is claimed either way; the agent can ask for another
+	// link from the sign-in page
+	if err := s.RequestPasswordReset(email); err != nil {
+		log.Printf("Failed to send password reset to claimed agent %s: %v", user.ID.Hex(), err)
+	}
+
+	return user, nil
+}
@@ -2,27 +2,32 @@ package services
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"time"
 
 	"gatorswamp/models"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Errors returned by the property request service
+var (
+	// ErrInvalidTransition is returned when a request cannot move to the requested status
+	ErrInvalidTransition = NewError(ErrConflict, "invalid_transition", "invalid status transition")
+	// ErrNotRequestOwner is returned when a user acts on another user's request
+	ErrNotRequestOwner = NewError(ErrForbidden, "not_request_owner", "request belongs to another user")
 )
 
 // PropertyRequestService handles business logic for property requests
 type PropertyRequestService struct {
-	collection     *mongo.Collection
+	repo           RequestRepository
 	userService    *UserService
 	housingService *HousingService
 }
 
 // NewPropertyRequestService creates a new property request service
-func NewPropertyRequestService(collection *mongo.Collection, userService *UserService, housingService *HousingService) *PropertyRequestService {
+func NewPropertyRequestService(repo RequestRepository, userService *UserService, housingService *HousingService) *PropertyRequestService {
 	return &PropertyRequestService{
-		collection:     collection,
+		repo:           repo,
 		userService:    userService,
 		housingService: housingService,
 	}
@@ -36,17 +41,23 @@ func (s *PropertyRequestService) CreateRequest(request models.PropertyRequest) (
 	// Validate property exists
 	_, err := s.housingService.GetPropertyByID(request.PropertyID.Hex())
 	if err != nil {
-		return nil, errors.New("property not found")
+		return nil, ErrPropertyNotFound
 	}
 
 	// Set metadata
+	now := primitive.NewDateTimeFromTime(time.Now())
 	request.ID = primitive.NewObjectID()
 	request.Status = models.StatusPending
-	request.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
-	request.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	request.History = []models.RequestStatusChange{{
+		To:        models.StatusPending,
+		ChangedBy: request.UserID,
+		ChangedAt: now,
+	}}
+	request.CreatedAt = now
+	request.UpdatedAt = now
 
 	// Insert into database
-	_, err = s.collection.InsertOne(ctx, request)
+	err = s.repo.Insert(ctx, request)
 	if err != nil {
 		return nil, err
 	}
@@ -61,19 +72,18 @@ func (s *PropertyRequestService) GetRequestByID(id string) (*models.PropertyRequ
 
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, errors.New("invalid ID format")
+		return nil, invalidID("request")
 	}
 
-	var request models.PropertyRequest
-	err = s.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&request)
+	request, err := s.repo.FindByID(ctx, objID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("request not found")
+		if err == ErrNotFound {
+			return nil, ErrRequestNotFound
 		}
 		return nil, err
 	}
 
-	return &request, nil
+	return request, nil
 }
 
 // GetRequestsByUser retrieves all requests for a specific user
@@ -83,98 +93,153 @@ func (s *PropertyRequestService) GetRequestsByUser(userID string) ([]models.Prop
 
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return nil, errors.New("invalid user ID format")
-	}
-
-	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
-	cursor, err := s.collection.Find(ctx, bson.M{"userId": objID}, opts)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	var requests []models.PropertyRequest
-	if err = cursor.All(ctx, &requests); err != nil {
-		return nil, err
+		return nil, invalidID("user")
 	}
 
-	return requests, nil
+	return s.repo.FindByUser(ctx, objID)
 }
 
 // GetAllRequests retrieves all requests (admin function)
-func (s *PropertyRequestService) GetAllRequests(filter bson.M) ([]models.PropertyRequest, error) {
+func (s *PropertyRequestService) GetAllRequests() ([]models.PropertyRequest, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
-	cursor, err := s.collection.Find(ctx, filter, opts)
+	return s.repo.FindAll(ctx)
+}
+
+// GetRequestsForAgent retrieves the requests for the listings an agent manages
+func (s *PropertyRequestService) GetRequestsForAgent(agentID string) ([]models.PropertyRequest, error) {
+	properties, err := s.housingService.GetPropertiesByAgent(agentID)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
 
-	var requests []models.PropertyRequest
-	if err = cursor.All(ctx, &requests); err != nil {
-		return nil, err
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	propertyIDs := make([]primitive.ObjectID, len(properties))
+	for i, property := range properties {
+		propertyIDs[i] = property.ID
 	}
+	return s.repo.FindByProperties(ctx, propertyIDs)
+}
 
-	return requests, nil
+// UpdateRequestStatus moves a request to a new status on behalf of a
+// reviewer. Only the requester may withdraw a request, through WithdrawRequest
+func (s *PropertyRequestService) UpdateRequestStatus(id string, status string, actorID string, reason string) (*models.PropertyRequest, error) {
+	if status == models.StatusWithdrawn {
+		return nil, InvalidField("status", "oneof", "only the requester can withdraw a request")
+	}
+	return s.changeStatus(id, status, actorID, reason)
 }
 
-// UpdateRequestStatus updates the status of a request
-func (s *PropertyRequestService) UpdateRequestStatus(id string, status string, adminID string) (*models.PropertyRequest, error) {
+// changeStatus moves a request to a new status, enforcing the allowed
+// transitions and appending the change to the request's history
+func (s *PropertyRequestService) changeStatus(id string, status string, actorID string, reason string) (*models.PropertyRequest, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	// Validate status
-	validStatuses := map[string]bool{
-		models.StatusPending:  true,
-		models.StatusApproved: true,
-		models.StatusRejected: true,
+	if !models.IsValidRequestStatus(status) {
+		return nil, InvalidField("status", "oneof", "invalid status value")
 	}
-
-	if !validStatuses[status] {
-		return nil, errors.New("invalid status value")
+	if status == models.StatusRejected && reason == "" {
+		return nil, InvalidField("reason", "required", "a reason is required when rejecting a request")
 	}
 
 	// Validate IDs
 	requestID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, errors.New("invalid request ID format")
+		return nil, invalidID("request")
 	}
 
-	adminObjID, err := primitive.ObjectIDFromHex(adminID)
+	actorObjID, err := primitive.ObjectIDFromHex(actorID)
 	if err != nil {
-		return nil, errors.New("invalid admin ID format")
+		return nil, invalidID("user")
 	}
 
-	// Update request
-	now := primitive.NewDateTimeFromTime(time.Now())
-	update := bson.M{
-		"$set": bson.M{
-			"status":      status,
-			"updatedAt":   now,
-			"processedBy": adminObjID,
-		},
+	current, err := s.GetRequestByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !models.CanTransitionRequest(current.Status, status) {
+		return nil, fmt.Errorf("%w: cannot change a %s request to %s", ErrInvalidTransition, current.Status, status)
+	}
+
+	change := models.RequestStatusChange{
+		From:      current.Status,
+		To:        status,
+		ChangedBy: actorObjID,
+		Reason:    reason,
+		ChangedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+	rejectionReason := ""
+	if status == models.StatusRejected {
+		rejectionReason = reason
 	}
 
-	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": requestID}, update)
+	// The repository only applies the change while the request is still in
+	// the status we validated against, so a concurrent change cannot be
+	// overwritten
+	err = s.repo.Transition(ctx, requestID, change, rejectionReason)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, fmt.Errorf("%w: request status changed concurrently", ErrInvalidTransition)
+		}
+		return nil, err
+	}
+
+	return s.GetRequestByID(id)
+}
+
+// UpdateRequestMessage changes the message of a pending request owned by userID
+func (s *PropertyRequestService) UpdateRequestMessage(id string, userID string, message string) (*models.PropertyRequest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	current, err := s.getOwnedRequest(id, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	if result.ModifiedCount == 0 {
-		return nil, errors.New("request not found")
+	if current.Status != models.StatusPending {
+		return nil, fmt.Errorf("%w: only pending requests can be edited", ErrInvalidTransition)
 	}
 
-	// Get updated request
-	var request models.PropertyRequest
-	err = s.collection.FindOne(ctx, bson.M{"_id": requestID}).Decode(&request)
+	// Only update while the request is still pending
+	err = s.repo.UpdateMessage(ctx, current.ID, models.StatusPending, message, time.Now())
 	if err != nil {
+		if err == ErrNotFound {
+			return nil, fmt.Errorf("%w: only pending requests can be edited", ErrInvalidTransition)
+		}
 		return nil, err
 	}
 
-	return &request, nil
+	return s.GetRequestByID(id)
+}
+
+// WithdrawRequest marks a pending request owned by userID as withdrawn
+func (s *PropertyRequestService) WithdrawRequest(id string, userID string) (*models.PropertyRequest, error) {
+	if _, err := s.getOwnedRequest(id, userID); err != nil {
+		return nil, err
+	}
+
+	return s.changeStatus(id, models.StatusWithdrawn, userID, "")
+}
+
+// getOwnedRequest loads a request and checks that it belongs to userID
+func (s *PropertyRequestService) getOwnedRequest(id string, userID string) (*models.PropertyRequest, error) {
+	request, err := s.GetRequestByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.UserID.Hex() != userID {
+		return nil, ErrNotRequestOwner
+	}
+
+	return request, nil
 }
 
 // DeleteRequest removes a request
@@ -184,17 +249,16 @@ func (s *PropertyRequestService) DeleteRequest(id string) error {
 
 	requestID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return errors.New("invalid request ID format")
+		return invalidID("request")
 	}
 
-	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": requestID})
+	err = s.repo.Delete(ctx, requestID)
 	if err != nil {
+		if err == ErrNotFound {
+			return ErrRequestNotFound
+		}
 		return err
 	}
 
-	if result.DeletedCount == 0 {
-		return errors.New("request not found")
-	}
-
 	return nil
 }
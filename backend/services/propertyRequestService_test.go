@@ -0,0 +1,150 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRequestStatusChanges(t *testing.T) {
+	// Each step changes the request as the reviewer, or withdraws it as
+	// the user named by withdrawBy
+	type step struct {
+		status     string
+		reason     string
+		withdrawBy string
+		wantErr    error
+	}
+	const requester, stranger = "requester", "stranger"
+
+	tests := []struct {
+		name       string
+		steps      []step
+		wantStatus string
+	}{
+		{
+			name:       "approve then sign",
+			steps:      []step{{status: models.StatusApproved}, {status: models.StatusLeaseSigned}},
+			wantStatus: models.StatusLeaseSigned,
+		},
+		{
+			name:       "approve then cancel",
+			steps:      []step{{status: models.StatusApproved}, {status: models.StatusCancelled, reason: "fell through"}},
+			wantStatus: models.StatusCancelled,
+		},
+		{
+			name:       "reject with a reason",
+			steps:      []step{{status: models.StatusRejected, reason: "already let"}},
+			wantStatus: models.StatusRejected,
+		},
+		{
+			name:       "reject needs a reason",
+			steps:      []step{{status: models.StatusRejected, wantErr: services.ErrValidation}},
+			wantStatus: models.StatusPending,
+		},
+		{
+			name:       "rejected is final",
+			steps:      []step{{status: models.StatusRejected, reason: "no"}, {status: models.StatusApproved, wantErr: services.ErrInvalidTransition}},
+			wantStatus: models.StatusRejected,
+		},
+		{
+			name:       "sign needs approval",
+			steps:      []step{{status: models.StatusLeaseSigned, wantErr: services.ErrInvalidTransition}},
+			wantStatus: models.StatusPending,
+		},
+		{
+			name:       "unknown status",
+			steps:      []step{{status: "archived", wantErr: services.ErrValidation}},
+			wantStatus: models.StatusPending,
+		},
+		{
+			name:       "reviewers cannot withdraw",
+			steps:      []step{{status: models.StatusWithdrawn, wantErr: services.ErrValidation}},
+			wantStatus: models.StatusPending,
+		},
+		{
+			name:       "requester withdraws",
+			steps:      []step{{withdrawBy: requester}},
+			wantStatus: models.StatusWithdrawn,
+		},
+		{
+			name:       "only the requester withdraws",
+			steps:      []step{{withdrawBy: stranger, wantErr: services.ErrNotRequestOwner}},
+			wantStatus: models.StatusPending,
+		},
+		{
+			name:       "approved requests cannot be withdrawn",
+			steps:      []step{{status: models.StatusApproved}, {withdrawBy: requester, wantErr: services.ErrInvalidTransition}},
+			wantStatus: models.StatusApproved,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newServices(t)
+			property := createListing(t, svc, "Pool House")
+			users := map[string]string{
+				requester: primitive.NewObjectID().Hex(),
+				stranger:  primitive.NewObjectID().Hex(),
+			}
+			reviewer := primitive.NewObjectID().Hex()
+
+			userID, _ := primitive.ObjectIDFromHex(users[requester])
+			request, err := svc.Requests.CreateRequest(models.PropertyRequest{UserID: userID, PropertyID: property.ID})
+			if err != nil {
+				t.Fatalf("CreateRequest() error = %v", err)
+			}
+			id := request.ID.Hex()
+
+			changes := 0
+			for i, s := range tt.steps {
+				if s.withdrawBy != "" {
+					_, err = svc.Requests.WithdrawRequest(id, users[s.withdrawBy])
+				} else {
+					_, err = svc.Requests.UpdateRequestStatus(id, s.status, reviewer, s.reason)
+				}
+				if s.wantErr != nil {
+					if !errors.Is(err, s.wantErr) {
+						t.Fatalf("step %d: error = %v, want %v", i, err, s.wantErr)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("step %d: error = %v", i, err)
+				}
+				changes++
+			}
+
+			got, err := svc.Requests.GetRequestByID(id)
+			if err != nil {
+				t.Fatalf("GetRequestByID() error = %v", err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			// The history starts with the creation and records every change
+			if len(got.History) != changes+1 {
+				t.Fatalf("history has %d entries, want %d", len(got.History), changes+1)
+			}
+			for i := 1; i < len(got.History); i++ {
+				if got.History[i].From != got.History[i-1].To {
+					t.Errorf("history entry %d is from %s, want %s", i, got.History[i].From, got.History[i-1].To)
+				}
+			}
+			if last := got.History[len(got.History)-1]; last.To != tt.wantStatus {
+				t.Errorf("last history entry is to %s, want %s", last.To, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCreateRequestNeedsListing(t *testing.T) {
+	svc := newServices(t)
+	_, err := svc.Requests.CreateRequest(models.PropertyRequest{UserID: primitive.NewObjectID(), PropertyID: primitive.NewObjectID()})
+	if !errors.Is(err, services.ErrPropertyNotFound) {
+		t.Errorf("CreateRequest() error = %v, want ErrPropertyNotFound", err)
+	}
+}
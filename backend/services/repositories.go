@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"gatorswamp/blobstore"
+	"gatorswamp/models"
+	"gatorswamp/ratelimit"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HousingRepository stores housing listings
+type HousingRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Insert(ctx context.Context, property models.Housing) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Housing, error)
+	// Replace overwrites a stored listing whose version is still
+	// property.Version and increments its version. It returns ErrConflict
+	// when the listing was saved since it was read
+	Replace(ctx context.Context, property models.Housing) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// Search returns every listing matching filter, newest first
+	Search(ctx context.Context, filter HousingFilter) ([]models.Housing, error)
+	// List returns up to limit listings matching filter in page order,
+	// starting strictly after the cursor position when after is set
+	List(ctx context.Context, filter HousingFilter, page PageRequest, after *PageCursor, limit int) ([]models.Housing, error)
+	Count(ctx context.Context, filter HousingFilter) (int64, error)
+	// Facets returns the counties and types listings have, in any order
+	Facets(ctx context.Context) (*HousingFacets, error)
+	// Near returns listings matching filter ordered by distance from origin.
+	// A zero maxDistance is unbounded and a nil box does not restrict the area
+	Near(ctx context.Context, origin LatLng, maxDistance float64, box *BoundingBox, filter HousingFilter, limit int) ([]HousingWithDistance, error)
+	// TextSearch returns up to limit listings matching both text and filter,
+	// most relevant first
+	TextSearch(ctx context.Context, text TextQuery, filter HousingFilter, limit int) ([]HousingMatch, error)
+}
+
+// UserRepository stores user accounts
+type UserRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	// Insert adds a new user, or returns ErrConflict when another user has
+	// its email. Emails are stored normalized by models.NormalizeEmail
+	Insert(ctx context.Context, user models.Users) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Users, error)
+	// FindByEmail returns the user registered with email in any case
+	FindByEmail(ctx context.Context, email string) (*models.Users, error)
+	// List returns the requested page of users matching query, newest first,
+	// and the total number of matches
+	List(ctx context.Context, query UserQuery) ([]models.Users, int64, error)
+	// Update applies the set fields of patch and returns the updated user. It
+	// returns ErrConflict when another user has the new email
+	Update(ctx context.Context, id primitive.ObjectID, patch UserPatch) (*models.Users, error)
+}
+
+// UserPatch lists the user fields to change; nil fields are left alone
+type UserPatch struct {
+	Email                 *string
+	Role                  *string
+	Password              *string
+	Disabled              *bool
+	PasswordResetRequired *bool
+	EmailVerified         *bool
+}
+
+// RequestRepository stores property requests
+type RequestRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Insert(ctx context.Context, request models.PropertyRequest) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.PropertyRequest, error)
+	// FindByUser returns a user's requests, newest first
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.PropertyRequest, error)
+	// FindAll returns every request, newest first
+	FindAll(ctx context.Context) ([]models.PropertyRequest, error)
+	// FindByProperties returns the requests for any of the listings, newest first
+	FindByProperties(ctx context.Context, propertyIDs []primitive.ObjectID) ([]models.PropertyRequest, error)
+	// Transition applies change only if the request is still in change.From,
+	// returning ErrNotFound otherwise
+	Transition(ctx context.Context, id primitive.ObjectID, change models.RequestStatusChange, rejectionReason string) error
+	// UpdateMessage changes the message only while the request has status,
+	// returning ErrNotFound otherwise
+	UpdateMessage(ctx context.Context, id primitive.ObjectID, status string, message string, at time.Time) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// SessionRepository stores login sessions
+type SessionRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Insert(ctx context.Context, session models.Session) error
+	// Rotate swaps the refresh token of the active session holding oldHash
+	// and returns the updated session, or ErrNotFound
+	Rotate(ctx context.Context, oldHash string, newHash string, now time.Time, expiresAt time.Time) (*models.Session, error)
+	IsActive(ctx context.Context, id primitive.ObjectID, now time.Time) (bool, error)
+	// Revoke revokes the active sessions matching match and returns how many
+	Revoke(ctx context.Context, match SessionMatch, reason string, now time.Time) (int64, error)
+}
+
+// SessionMatch selects sessions by exactly one of its fields
+type SessionMatch struct {
+	ID                *primitive.ObjectID
+	UserID            *primitive.ObjectID
+	RefreshTokenHash  string
+	PreviousTokenHash string
+}
+
+// AccountTokenRepository stores single-use account tokens
+type AccountTokenRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Insert(ctx context.Context, token models.AccountToken) error
+	// InvalidateUnused marks every unused token of userID for purpose as used
+	InvalidateUnused(ctx context.Context, userID primitive.ObjectID, purpose string, now time.Time) error
+	// Consume marks the unused, unexpired token with hash as used and
+	// returns it, or ErrNotFound
+	Consume(ctx context.Context, hash string, purpose string, now time.Time) (*models.AccountToken, error)
+}
+
+// LoginAttemptRepository counts failed logins per email address
+type LoginAttemptRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	// Find returns the failed logins of email that are not yet forgotten at
+	// now, or ErrNotFound
+	Find(ctx context.Context, email string, now time.Time) (*models.LoginAttempts, error)
+	// RecordFailure counts a failed login at `at` and returns the updated
+	// record. Failures are forgotten window after the last one
+	RecordFailure(ctx context.Context, email string, at time.Time, window time.Duration) (*models.LoginAttempts, error)
+	// Lock refuses the logins of email until until
+	Lock(ctx context.Context, email string, until time.Time) error
+	// Clear forgets the failed logins of email
+	Clear(ctx context.Context, email string) error
+}
+
+// FavoriteRepository stores the listings users saved for later
+type FavoriteRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	// Add saves favorite unless its user already saved the listing, and
+	// returns the stored favorite either way
+	Add(ctx context.Context, favorite models.Favorite) (*models.Favorite, error)
+	// Remove deletes a user's favorite of a listing, or returns ErrNotFound
+	Remove(ctx context.Context, userID primitive.ObjectID, propertyID primitive.ObjectID) error
+	// FindByUser returns a user's favorites, newest first
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Favorite, error)
+	CountByProperty(ctx context.Context, propertyID primitive.ObjectID) (int64, error)
+	// DeleteByProperty removes every favorite of a listing and returns how many
+	DeleteByProperty(ctx context.Context, propertyID primitive.ObjectID) (int64, error)
+}
+
+// SavedSearchRepository stores users' saved searches
+type SavedSearchRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Insert(ctx context.Context, search models.SavedSearch) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.SavedSearch, error)
+	// FindByUser returns a user's saved searches, newest first
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.SavedSearch, error)
+	// FindAll returns every saved search
+	FindAll(ctx context.Context) ([]models.SavedSearch, error)
+	Replace(ctx context.Context, search models.SavedSearch) error
+	// SetLastNotified records when the user was last alerted about a search
+	SetLastNotified(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// ListingAlertRepository stores the listings that matched saved searches
+type ListingAlertRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	// Insert records alert and reports false without error when the listing
+	// already alerted the same search
+	Insert(ctx context.Context, alert models.ListingAlert) (bool, error)
+	// FindByUser returns up to limit of a user's alerts, newest first
+	FindByUser(ctx context.Context, userID primitive.ObjectID, limit int) ([]models.ListingAlert, error)
+	// FindUndelivered returns the alerts of a search not yet sent, oldest first
+	FindUndelivered(ctx context.Context, savedSearchID primitive.ObjectID) ([]models.ListingAlert, error)
+	MarkDelivered(ctx context.Context, ids []primitive.ObjectID, at time.Time) error
+	// DeleteBySearch removes every alert of a saved search
+	DeleteBySearch(ctx context.Context, savedSearchID primitive.ObjectID) error
+}
+
+// PriceHistoryRepository stores the price changes of listings
+type PriceHistoryRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Insert(ctx context.Context, change models.PriceChange) error
+	// FindByProperty returns the price changes of a listing, newest first
+	FindByProperty(ctx context.Context, propertyID primitive.ObjectID) ([]models.PriceChange, error)
+	// FindSince returns the price changes of the given listings made at or
+	// after since, oldest first
+	FindSince(ctx context.Context, propertyIDs []primitive.ObjectID, since time.Time) ([]models.PriceChange, error)
+	// DeleteByProperty removes the price history of a listing
+	DeleteByProperty(ctx context.Context, propertyID primitive.ObjectID) error
+}
+
+// AvailabilityRepository stores the periods in which listings can be toured
+type AvailabilityRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	// Insert adds a window, or returns ErrConflict when it overlaps another
+	// window of the same agent. Window bounds are whole minutes
+	Insert(ctx context.Context, window models.AvailabilityWindow) error
+	// FindByProperty returns the windows of a listing that overlap the
+	// period from from to to, in start order
+	FindByProperty(ctx context.Context, propertyID primitive.ObjectID, from time.Time, to time.Time) ([]models.AvailabilityWindow, error)
+	// Delete removes a window of a listing, or returns ErrNotFound
+	Delete(ctx context.Context, propertyID primitive.ObjectID, id primitive.ObjectID) error
+	// DeleteByProperty removes every window of a listing
+	DeleteByProperty(ctx context.Context, propertyID primitive.ObjectID) error
+}
+
+// TourRepository stores booked tours. An agent has at most one booked tour
+// starting at a time, across all of their listings; storing a second one
+// fails with ErrConflict
+type TourRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	// Insert stores a booked tour, or returns ErrConflict when its slot is taken
+	Insert(ctx context.Context, tour models.Tour) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Tour, error)
+	// FindByUser returns a user's tours, latest start first
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Tour, error)
+	// FindAll returns every tour, latest start first
+	FindAll(ctx context.Context) ([]models.Tour, error)
+	// FindByAgent returns the tours of an agent starting at or after since,
+	// in start order
+	FindByAgent(ctx context.Context, agentKey string, since time.Time) ([]models.Tour, error)
+	// FindBooked returns the booked tours of an agent that overlap the
+	// period from from to to, in start order
+	FindBooked(ctx context.Context, agentKey string, from time.Time, to time.Time) ([]models.Tour, error)
+	// Reschedule moves a booked tour to another slot and returns it. It
+	// returns ErrNotFound when the tour is not booked and ErrConflict when
+	// the slot is taken
+	Reschedule(ctx context.Context, id primitive.ObjectID, start time.Time, end time.Time, at time.Time) (*models.Tour, error)
+	// Cancel cancels a booked tour and returns it, or returns ErrNotFound
+	Cancel(ctx context.Context, id primitive.ObjectID, at time.Time) (*models.Tour, error)
+	// CancelByProperty cancels every booked tour of a listing
+	CancelByProperty(ctx context.Context, propertyID primitive.ObjectID, at time.Time) error
+}
+
+// Repositories bundles the storage backends the services run against
+type Repositories struct {
+	Housing       HousingRepository
+	Users         UserRepository
+	Requests      RequestRepository
+	Sessions      SessionRepository
+	AccountTokens AccountTokenRepository
+	LoginAttempts LoginAttemptRepository
+	Favorites     FavoriteRepository
+	SavedSearches SavedSearchRepository
+	Alerts        ListingAlertRepository
+	PriceHistory  PriceHistoryRepository
+	Availability  AvailabilityRepository
+	Tours         TourRepository
+}
+
+// Services bundles the services built on a set of repositories
+type Services struct {
+	Housing       *HousingService
+	Users         *UserService
+	Requests      *PropertyRequestService
+	Sessions      *SessionService
+	AccountTokens *AccountTokenService
+	Favorites     *FavoriteService
+	SavedSearches *SavedSearchService
+	Alerts        *AlertService
+	Tours         *TourService
+	// RateLimits keeps the rate limit buckets of the API. The in-memory
+	// default suits a single node
+	RateLimits ratelimit.Store
+	repos      Repositories
+}
+
+// New wires up all services on top of repos, keeping uploaded files in blobs
+func New(repos Repositories, blobs blobstore.BlobStore) *Services {
+	housingService := NewHousingService(repos.Housing, repos.Favorites, repos.PriceHistory, repos.Users, blobs)
+	sessionService := NewSessionService(repos.Sessions)
+	tokenService := NewAccountTokenService(repos.AccountTokens)
+	userService := NewUserService(repos.Users, repos.LoginAttempts, sessionService, tokenService)
+
+	// Matching listings are alerted in the background
+	alertService := NewAlertService(repos.SavedSearches, repos.Alerts, userService, housingService)
+	housingService.Subscribe(alertService.ListingSaved)
+
+	tourService := NewTourService(repos.Tours, repos.Availability, userService, housingService)
+	housingService.SubscribeDeleted(tourService.ListingDeleted)
+
+	return &Services{
+		Housing:       housingService,
+		Users:         userService,
+		Requests:      NewPropertyRequestService(repos.Requests, userService, housingService),
+		Sessions:      sessionService,
+		AccountTokens: tokenService,
+		Favorites:     NewFavoriteService(repos.Favorites, housingService),
+		SavedSearches: NewSavedSearchService(repos.SavedSearches, repos.Alerts),
+		Alerts:        alertService,
+		Tours:         tourService,
+		RateLimits:    ratelimit.NewMemoryStore(),
+		repos:         repos,
+	}
+}
+
+// EnsureIndexes creates the indexes every repository relies on
+func (s *Services) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, repo := range []interface {
+		EnsureIndexes(ctx context.Context) error
+	}{s.repos.Housing, s.repos.Users, s.repos.Requests, s.repos.Sessions, s.repos.AccountTokens, s.repos.LoginAttempts, s.repos.Favorites,
+		s.repos.SavedSearches, s.repos.Alerts, s.repos.PriceHistory, s.repos.Availability, s.repos.Tours} {
+		if err := repo.EnsureIndexes(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxSavedSearches is how many searches a user can save
+const MaxSavedSearches = 20
+
+// maxAlertsListed is how many recent alerts a user is shown
+const maxAlertsListed = 100
+
+// Errors returned by the saved search service
+var (
+	ErrSavedSearchNotFound = NewError(ErrNotFound, "saved_search_not_found", "saved search not found")
+	// ErrNotSavedSearchOwner is returned when a user acts on another user's search
+	ErrNotSavedSearchOwner = NewError(ErrForbidden, "not_saved_search_owner", "saved search belongs to another user")
+	ErrSavedSearchLimit    = NewError(ErrConflict, "saved_search_limit", fmt.Sprintf("at most %d searches can be saved", MaxSavedSearches))
+)
+
+// SavedSearchService handles business logic for saved searches
+type SavedSearchService struct {
+	repo   SavedSearchRepository
+	alerts ListingAlertRepository
+}
+
+// NewSavedSearchService creates a new saved search service
+func NewSavedSearchService(repo SavedSearchRepository, alerts ListingAlertRepository) *SavedSearchService {
+	return &SavedSearchService{
+		repo:   repo,
+		alerts: alerts,
+	}
+}
+
+// SearchFilter converts saved search criteria to the equivalent housing filter
+func SearchFilter(criteria models.SearchCriteria) HousingFilter {
+	toRange := func(r models.SearchRange) NumericRange {
+		return NumericRange{Min: r.Min, Max: r.Max}
+	}
+
+	return HousingFilter{
+		County:    criteria.County,
+		Type:      criteria.Type,
+		Price:     toRange(criteria.Price),
+		Bedrooms:  toRange(criteria.Bedrooms),
+		Bathrooms: toRange(criteria.Bathrooms),
+		Year:      toRange(criteria.Year),
+		Surface:   toRange(criteria.Surface),
+	}
+}
+
+// checkCriteria rejects ranges whose minimum is greater than their maximum
+func checkCriteria(criteria models.SearchCriteria) error {
+	var fields []FieldError
+	for _, rg := range []struct {
+		name  string
+		value models.SearchRange
+	}{
+		{"price", criteria.Price},
+		{"bedrooms", criteria.Bedrooms},
+		{"bathrooms", criteria.Bathrooms},
+		{"year", criteria.Year},
+		{"surface", criteria.Surface},
+	} {
+		if rg.value.Min != nil && rg.value.Max != nil && *rg.value.Min > *rg.value.Max {
+			fields = append(fields, FieldError{
+				Field:   "criteria." + rg.name,
+				Code:    "range",
+				Message: fmt.Sprintf("%s min must not be greater than max", rg.name),
+			})
+		}
+	}
+
+	if len(fields) > 0 {
+		return ValidationError(fields...)
+	}
+	return nil
+}
+
+// CreateSavedSearch saves a search for a user. Searches are delivered
+// instantly unless another delivery is chosen
+func (s *SavedSearchService) CreateSavedSearch(userID string, search models.SavedSearch) (*models.SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, invalidID("user")
+	}
+
+	if err := checkCriteria(search.Criteria); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.FindByUser(ctx, userObjID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= MaxSavedSearches {
+		return nil, ErrSavedSearchLimit
+	}
+
+	if search.Delivery == "" {
+		search.Delivery = models.DeliveryInstant
+	}
+
+	// Set metadata
+	now := primitive.NewDateTimeFromTime(time.Now())
+	search.ID = primitive.NewObjectID()
+	search.UserID = userObjID
+	search.LastNotifiedAt = nil
+	search.CreatedAt = now
+	search.UpdatedAt = now
+
+	if err := s.repo.Insert(ctx, search); err != nil {
+		return nil, err
+	}
+
+	return &search, nil
+}
+
+// GetSavedSearchesByUser retrieves a user's saved searches, newest first
+func (s *SavedSearchService) GetSavedSearchesByUser(userID string) ([]models.SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, invalidID("user")
+	}
+
+	return s.repo.FindByUser(ctx, objID)
+}
+
+// UpdateSavedSearch replaces the name, criteria and delivery of a search
+// owned by userID
+func (s *SavedSearchService) UpdateSavedSearch(id string, userID string, changes models.SavedSearch) (*models.SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	search, err := s.getOwnedSearch(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkCriteria(changes.Criteria); err != nil {
+		return nil, err
+	}
+
+	if changes.Delivery == "" {
+		changes.Delivery = models.DeliveryInstant
+	}
+
+	search.Name = changes.Name
+	search.Criteria = changes.Criteria
+	search.Delivery = changes.Delivery
+	search.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	if err := s.repo.Replace(ctx, *search); err != nil {
+		if err == ErrNotFound {
+			return nil, ErrSavedSearchNotFound
+		}
+		return nil, err
+	}
+
+	return search, nil
+}
+
+// DeleteSavedSearch removes a search owned by userID and its alerts
+func (s *SavedSearchService) DeleteSavedSearch(id string, userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	search, err := s.getOwnedSearch(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, search.ID); err != nil {
+		if err == ErrNotFound {
+			return ErrSavedSearchNotFound
+		}
+		return err
+	}
+
+	return s.alerts.DeleteBySearch(ctx, search.ID)
+}
+
+// GetAlertsByUser retrieves a user's most recent listing alerts, newest first
+func (s *SavedSearchService) GetAlertsByUser(userID string) ([]models.ListingAlert, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, invalidID("user")
+	}
+
+	return s.alerts.FindByUser(ctx, objID, maxAlertsListed)
+}
+
+// getOwnedSearch loads a saved search and checks that it belongs to userID
+func (s *SavedSearchService) getOwnedSearch(ctx context.Context, id string, userID string) (*models.SavedSearch, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, invalidID("saved search")
+	}
+
+	search, err := s.repo.FindByID(ctx, objID)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrSavedSearchNotFound
+		}
+		return nil, err
+	}
+
+	if search.UserID.Hex() != userID {
+		return nil, ErrNotSavedSearchOwner
+	}
+
+	return search, nil
+}
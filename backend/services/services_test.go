@@ -0,0 +1,47 @@
+package services_test
+
+import (
+	"os"
+	"testing"
+
+	"gatorswamp/blobstore"
+	"gatorswamp/config"
+	"gatorswamp/models"
+	"gatorswamp/repositories/memoryrepo"
+	"gatorswamp/services"
+)
+
+func TestMain(m *testing.M) {
+	cfg := config.Defaults()
+	cfg.Storage = "memory"
+	cfg.JWTSecret = "test-secret-0123456789abcdef0123"
+	config.Set(cfg)
+
+	os.Exit(m.Run())
+}
+
+// newServices wires up the services on empty in-memory repositories
+func newServices(t *testing.T) *services.Services {
+	t.Helper()
+	return services.New(memoryrepo.NewRepositories(), blobstore.NewLocalStore(t.TempDir(), "/uploads"))
+}
+
+// createUser registers a user with the given email and password
+func createUser(t *testing.T, svc *services.Services, email, password string) services.UserResponse {
+	t.Helper()
+	res, err := svc.Users.CreateUser(models.Users{FirstName: "Test", LastName: "User", Email: email, Password: password})
+	if err != nil {
+		t.Fatalf("CreateUser(%q) error = %v", email, err)
+	}
+	return res.User
+}
+
+// createListing adds a listing to the housing service
+func createListing(t *testing.T, svc *services.Services, name string) *models.Housing {
+	t.Helper()
+	property, err := svc.Housing.CreateProperty(models.Housing{Name: name, Address: "1 Main St", County: "Alachua", PriceCents: 100000})
+	if err != nil {
+		t.Fatalf("CreateProperty(%q) error = %v", name, err)
+	}
+	return property
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshTokenTTL is how long a session survives without being refreshed
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+// Errors returned by the session service
+var (
+	ErrInvalidRefreshToken = NewError(ErrUnauthorized, "invalid_refresh_token", "invalid refresh token")
+	ErrRefreshTokenReused  = NewError(ErrUnauthorized, "refresh_token_reused", "refresh token reuse detected")
+)
+
+// SessionService issues, rotates and revokes login sessions
+type SessionService struct {
+	repo SessionRepository
+}
+
+// SessionTokens is the token pair handed to a client for a session
+type SessionTokens struct {
+	SessionID    string
+	AccessToken  string
+	RefreshToken string
+}
+
+// NewSessionService creates a new session service
+func NewSessionService(repo SessionRepository) *SessionService {
+	return &SessionService{
+		repo: repo,
+	}
+}
+
+// CreateSession starts a new session for a user
+func (s *SessionService) CreateSession(userID primitive.ObjectID) (*SessionTokens, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		ID:                  primitive.NewObjectID(),
+		UserID:              userID,
+		RefreshTokenHash:    utils.HashToken(refreshToken),
+		PreviousTokenHashes: []string{},
+		CreatedAt:           primitive.NewDateTimeFromTime(now),
+		LastUsedAt:          primitive.NewDateTimeFromTime(now),
+		ExpiresAt:           primitive.NewDateTimeFromTime(now.Add(RefreshTokenTTL)),
+	}
+
+	if err := s.repo.Insert(ctx, session); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := utils.GenerateToken(userID.Hex(), session.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionTokens{
+		SessionID:    session.ID.Hex(),
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. Presenting a
+// refresh token that has already been rotated out revokes the session
+func (s *SessionService) Refresh(refreshToken string) (*SessionTokens, *models.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if refreshToken == "" {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	hash := utils.HashToken(refreshToken)
+	newToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	session, err := s.repo.Rotate(ctx, hash, utils.HashToken(newToken), now, now.Add(RefreshTokenTTL))
+	if err == ErrNotFound {
+		// A rotated-out token means it leaked: kill the whole session
+		revoked, rerr := s.repo.Revoke(ctx, SessionMatch{PreviousTokenHash: hash}, "refresh token reused", now)
+		if rerr != nil {
+			return nil, nil, rerr
+		}
+		if revoked > 0 {
+			return nil, nil, ErrRefreshTokenReused
+		}
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accessToken, err := utils.GenerateToken(session.UserID.Hex(), session.ID.Hex())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &SessionTokens{
+		SessionID:    session.ID.Hex(),
+		AccessToken:  accessToken,
+		RefreshToken: newToken,
+	}, session, nil
+}
+
+// IsActive reports whether a session exists and has not been revoked or expired
+func (s *SessionService) IsActive(sessionID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	id, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return false, nil
+	}
+
+	return s.repo.IsActive(ctx, id, time.Now())
+}
+
+// RevokeSession revokes a single session
+func (s *SessionService) RevokeSession(sessionID string, reason string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	id, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return invalidID("session")
+	}
+
+	_, err = s.repo.Revoke(ctx, SessionMatch{ID: &id}, reason, time.Now())
+	return err
+}
+
+// RevokeSessionByRefreshToken revokes the session a refresh token currently belongs to
+func (s *SessionService) RevokeSessionByRefreshToken(refreshToken string, reason string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := s.repo.Revoke(ctx, SessionMatch{RefreshTokenHash: utils.HashToken(refreshToken)}, reason, time.Now())
+	return err
+}
+
+// RevokeAllForUser revokes every active session of a user
+func (s *SessionService) RevokeAllForUser(userID primitive.ObjectID, reason string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return s.repo.Revoke(ctx, SessionMatch{UserID: &userID}, reason, time.Now())
+}
@@ -0,0 +1,123 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSessionRefresh(t *testing.T) {
+	// Each step presents the refresh token issued by the numbered step,
+	// 0 being the token the session was created with
+	type step struct {
+		token   int
+		wantErr error
+	}
+	tests := []struct {
+		name   string
+		steps  []step
+		active bool
+	}{
+		{
+			name:   "rotation",
+			steps:  []step{{0, nil}, {1, nil}, {2, nil}},
+			active: true,
+		},
+		{
+			name:   "reuse of the previous token revokes the session",
+			steps:  []step{{0, nil}, {0, services.ErrRefreshTokenReused}, {1, services.ErrInvalidRefreshToken}},
+			active: false,
+		},
+		{
+			name:   "reuse of an older token revokes the session",
+			steps:  []step{{0, nil}, {1, nil}, {0, services.ErrRefreshTokenReused}, {2, services.ErrInvalidRefreshToken}},
+			active: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := newServices(t).Sessions
+			created, err := sessions.CreateSession(primitive.NewObjectID())
+			if err != nil {
+				t.Fatalf("CreateSession() error = %v", err)
+			}
+
+			tokens := []string{created.RefreshToken}
+			for i, s := range tt.steps {
+				refreshed, _, err := sessions.Refresh(tokens[s.token])
+				if s.wantErr != nil {
+					if !errors.Is(err, s.wantErr) {
+						t.Fatalf("step %d: Refresh() error = %v, want %v", i, err, s.wantErr)
+					}
+					tokens = append(tokens, "")
+					continue
+				}
+				if err != nil {
+					t.Fatalf("step %d: Refresh() error = %v", i, err)
+				}
+				if refreshed.SessionID != created.SessionID {
+					t.Fatalf("step %d: Refresh() session = %s, want %s", i, refreshed.SessionID, created.SessionID)
+				}
+				if refreshed.RefreshToken == tokens[s.token] {
+					t.Fatalf("step %d: Refresh() did not rotate the refresh token", i)
+				}
+				tokens = append(tokens, refreshed.RefreshToken)
+			}
+
+			active, err := sessions.IsActive(created.SessionID)
+			if err != nil {
+				t.Fatalf("IsActive() error = %v", err)
+			}
+			if active != tt.active {
+				t.Errorf("IsActive() = %v, want %v", active, tt.active)
+			}
+		})
+	}
+}
+
+func TestSessionRefreshRejectsUnknownTokens(t *testing.T) {
+	sessions := newServices(t).Sessions
+	if _, err := sessions.CreateSession(primitive.NewObjectID()); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	for _, token := range []string{"", "not-a-token"} {
+		if _, _, err := sessions.Refresh(token); !errors.Is(err, services.ErrInvalidRefreshToken) {
+			t.Errorf("Refresh(%q) error = %v, want ErrInvalidRefreshToken", token, err)
+		}
+	}
+}
+
+func TestRevokeAllForUser(t *testing.T) {
+	sessions := newServices(t).Sessions
+	userID := primitive.NewObjectID()
+
+	first, err := sessions.CreateSession(userID)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	second, err := sessions.CreateSession(userID)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	other, err := sessions.CreateSession(primitive.NewObjectID())
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	if revoked, err := sessions.RevokeAllForUser(userID, "test"); err != nil || revoked != 2 {
+		t.Fatalf("RevokeAllForUser() = %d, %v, want 2", revoked, err)
+	}
+	if _, _, err := sessions.Refresh(first.RefreshToken); !errors.Is(err, services.ErrInvalidRefreshToken) {
+		t.Errorf("Refresh() of a revoked session error = %v, want ErrInvalidRefreshToken", err)
+	}
+	for _, tokens := range []*services.SessionTokens{first, second, other} {
+		active, _ := sessions.IsActive(tokens.SessionID)
+		if want := tokens == other; active != want {
+			t.Errorf("IsActive(%s) = %v, want %v", tokens.SessionID, active, want)
+		}
+	}
+}
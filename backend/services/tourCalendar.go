@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"gatorswamp/calendar"
+	"gatorswamp/config"
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FeedHistory is how far back an agent's tour feed reaches
+const FeedHistory = 30 * 24 * time.Hour
+
+// ErrFeedNotFound is returned for an agent feed URL with a wrong token. It
+// does not tell whether the agent exists
+var ErrFeedNotFound = NewError(ErrNotFound, "feed_not_found", "calendar feed not found")
+
+// feedToken signs an agent key so that the feed URL cannot be guessed.
+// Calendar applications cannot send credentials, so the URL is the secret
+func feedToken(agentKey string) string {
+	mac := hmac.New(sha256.New, []byte(config.JwtSecretKey()))
+	mac.Write([]byte("tour-feed:" + agentKey))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// AgentFeedToken returns the key of a listing's agent and the token that
+// authorizes reading the agent's tour feed
+func (s *TourService) AgentFeedToken(propertyID string) (agentKey string, token string, err error) {
+	property, err := s.housingService.GetPropertyByID(propertyID)
+	if err != nil {
+		return "", "", err
+	}
+
+	agentKey = property.AgentKey()
+	if agentKey == "" {
+		return "", "", ErrNoAgent
+	}
+	return agentKey, feedToken(agentKey), nil
+}
+
+// AgentFeed returns the calendar of an agent's tours from FeedHistory ago
+// on, cancelled ones included so that subscribers drop them
+func (s *TourService) AgentFeed(agentKey string, token string) (*calendar.Calendar, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if agentKey == "" || !hmac.Equal([]byte(token), []byte(feedToken(agentKey))) {
+		return nil, ErrFeedNotFound
+	}
+
+	tours, err := s.repo.FindByAgent(ctx, agentKey, time.Now().Add(-FeedHistory))
+	if err != nil {
+		return nil, err
+	}
+
+	cal := &calendar.Calendar{Name: "GatorSwamp tours"}
+	if len(tours) > 0 && tours[len(tours)-1].Agent.Name != "" {
+		cal.Name += ": " + tours[len(tours)-1].Agent.Name
+	}
+
+	listings := map[primitive.ObjectID]*models.Housing{}
+	for _, tour := range tours {
+		if _, ok := listings[tour.PropertyID]; !ok {
+			listings[tour.PropertyID], _ = s.housingService.GetPropertyByID(tour.PropertyID.Hex())
+		}
+
+		event := tourEvent(tour, listings[tour.PropertyID])
+		var details []string
+		if visitor := s.describeUser(tour.UserID); visitor != "" {
+			details = append(details, "Visitor: "+visitor)
+		}
+		if tour.Note != "" {
+			details = append(details, "Note: "+tour.Note)
+		}
+		event.Description = strings.Join(details, "\n")
+		cal.Events = append(cal.Events, event)
+	}
+
+	return cal, nil
+}
+
+// TourCalendar returns a calendar holding the single event of a tour, as
+// seen by the user who booked it
+func (s *TourService) TourCalendar(tour models.Tour) *calendar.Calendar {
+	property, _ := s.housingService.GetPropertyByID(tour.PropertyID.Hex())
+
+	event := tourEvent(tour, property)
+	var details []string
+	if tour.Agent.Name != "" || tour.Agent.Phone != "" {
+		details = append(details, strings.TrimSpace("Agent: "+tour.Agent.Name+" "+tour.Agent.Phone))
+	}
+	if tour.Note != "" {
+		details = append(details, "Note: "+tour.Note)
+	}
+	event.Description = strings.Join(details, "\n")
+
+	return &calendar.Calendar{Events: []calendar.Event{event}}
+}
+
+// tourEvent builds the calendar event of a tour of property, which is nil
+// when the listing no longer exists
+func tourEvent(tour models.Tour, property *models.Housing) calendar.Event {
+	event := calendar.Event{
+		UID:       tour.ID.Hex() + "@gatorswamp",
+		Summary:   "Property tour",
+		Start:     tour.Start.Time(),
+		End:       tour.End.Time(),
+		Stamp:     tour.UpdatedAt.Time(),
+		Sequence:  tour.Sequence,
+		Cancelled: tour.Status == models.TourCancelled,
+	}
+	if property != nil {
+		event.Summary = "Tour of " + property.Name
+		event.Location = property.Address
+		event.URL = strings.TrimSuffix(config.AppBaseURL(), "/") + "/property/" + property.ID.Hex()
+	}
+	return event
+}
+
+// describeUser names a user for a calendar event, or returns an empty string
+// when the user cannot be found
+func (s *TourService) describeUser(userID primitive.ObjectID) string {
+	user, err := s.userService.GetUserByID(userID.Hex())
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s %s <%s>", user.FirstName, user.LastName, user.Email)
+}
@@ -0,0 +1,470 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gatorswamp/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Limits of availability windows and the slots they are split into
+const (
+	DefaultSlotMinutes = 30
+	MinSlotMinutes     = 15
+	MaxSlotMinutes     = 240
+	// MaxWindowLength is the longest single availability window
+	MaxWindowLength = 12 * time.Hour
+)
+
+// Periods of availability that can be listed at once
+const (
+	// DefaultAvailabilityPeriod is listed when no end is given
+	DefaultAvailabilityPeriod = 14 * 24 * time.Hour
+	MaxAvailabilityPeriod     = 60 * 24 * time.Hour
+)
+
+// Errors returned by the tour service
+var (
+	ErrTourNotFound         = NewError(ErrNotFound, "tour_not_found", "tour not found")
+	ErrAvailabilityNotFound = NewError(ErrNotFound, "availability_not_found", "availability window not found")
+	// ErrNotTourOwner is returned when a user acts on another user's tour
+	ErrNotTourOwner = NewError(ErrForbidden, "not_tour_owner", "tour belongs to another user")
+	// ErrSlotTaken is returned when another tour holds the requested slot
+	ErrSlotTaken = NewError(ErrConflict, "slot_taken", "this time slot is already booked")
+	// ErrSlotUnavailable is returned for a start time that is not the start
+	// of a slot the agent offers
+	ErrSlotUnavailable     = NewError(ErrValidation, "slot_unavailable", "the agent is not available at this time")
+	ErrSlotInPast          = NewError(ErrValidation, "slot_in_past", "tours must be booked in the future")
+	ErrTourNotBooked       = NewError(ErrConflict, "tour_not_booked", "tour was cancelled")
+	ErrAvailabilityOverlap = NewError(ErrConflict, "availability_overlap", "window overlaps another availability window of this agent")
+	// ErrNoAgent is returned when availability is published for a listing
+	// without an agent to give the tours
+	ErrNoAgent = NewError(ErrConflict, "no_agent", "listing has no agent")
+)
+
+// Availability lists the availability windows of a listing in a period and
+// the slots still free to book
+type Availability struct {
+	Windows []models.AvailabilityWindow `json:"windows"`
+	Slots   []models.TimeSlot           `json:"slots"`
+}
+
+// TourService handles business logic for listing tours
+type TourService struct {
+	repo           TourRepository
+	availability   AvailabilityRepository
+	userService    *UserService
+	housingService *HousingService
+}
+
+// NewTourService creates a new tour service
+func NewTourService(repo TourRepository, availability AvailabilityRepository, userService *UserService, housingService *HousingService) *TourService {
+	return &TourService{
+		repo:           repo,
+		availability:   availability,
+		userService:    userService,
+		housingService: housingService,
+	}
+}
+
+// AddAvailability publishes a window in which the agent of a listing gives
+// tours. A zero slotMinutes uses DefaultSlotMinutes. The window may not
+// overlap another window of the agent, on any of their listings
+func (s *TourService) AddAvailability(propertyID string, actorID string, start time.Time, end time.Time, slotMinutes int) (*models.AvailabilityWindow, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	actorObjID, err := primitive.ObjectIDFromHex(actorID)
+	if err != nil {
+		return nil, invalidID("user")
+	}
+
+	property, err := s.housingService.GetPropertyByID(propertyID)
+	if err != nil {
+		return nil, err
+	}
+	if property.AgentKey() == "" {
+		return nil, ErrNoAgent
+	}
+
+	if slotMinutes == 0 {
+		slotMinutes = DefaultSlotMinutes
+	}
+	if err := checkWindow(start, end, slotMinutes); err != nil {
+		return nil, err
+	}
+
+	window := models.AvailabilityWindow{
+		ID:          primitive.NewObjectID(),
+		PropertyID:  property.ID,
+		AgentKey:    property.AgentKey(),
+		Start:       primitive.NewDateTimeFromTime(start),
+		End:         primitive.NewDateTimeFromTime(end),
+		SlotMinutes: slotMinutes,
+		CreatedBy:   actorObjID,
+		CreatedAt:   primitive.NewDateTimeFromTime(time.Now()),
+	}
+	// The repository refuses a window overlapping another of the agent's,
+	// so two windows published at once cannot both be stored
+	if err := s.availability.Insert(ctx, window); err != nil {
+		if err == ErrConflict {
+			return nil, ErrAvailabilityOverlap
+		}
+		return nil, err
+	}
+
+	return &window, nil
+}
+
+// checkWindow validates the period and slot length of an availability window
+func checkWindow(start time.Time, end time.Time, slotMinutes int) error {
+	slot := time.Duration(slotMinutes) * time.Minute
+	switch {
+	case !start.Equal(start.Truncate(time.Minute)):
+		return InvalidField("start", "minute", "start must be a whole minute")
+	case !end.Equal(end.Truncate(time.Minute)):
+		return InvalidField("end", "minute", "end must be a whole minute")
+	case slotMinutes < MinSlotMinutes || slotMinutes > MaxSlotMinutes:
+		return InvalidField("slotMinutes", "range", "slotMinutes must be between %d and %d", MinSlotMinutes, MaxSlotMinutes)
+	case !end.After(start):
+		return InvalidField("end", "range", "end must be after start")
+	case end.Sub(start) > MaxWindowLength:
+		return InvalidField("end", "range", "a window can be at most %s long", MaxWindowLength)
+	case end.Sub(start) < slot:
+		return InvalidField("end", "range", "a window must fit at least one %d minute slot", slotMinutes)
+	case !end.After(time.Now()):
+		return InvalidField("end", "range", "end must be in the future")
+	}
+	return nil
+}
+
+// RemoveAvailability removes an availability window of a listing. Tours
+// already booked in it are kept
+func (s *TourService) RemoveAvailability(propertyID string, windowID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	propertyObjID, err := primitive.ObjectIDFromHex(propertyID)
+	if err != nil {
+		return invalidID("property")
+	}
+
+	windowObjID, err := primitive.ObjectIDFromHex(windowID)
+	if err != nil {
+		return invalidID("availability window")
+	}
+
+	err = s.availability.Delete(ctx, propertyObjID, windowObjID)
+	if err != nil {
+		if err == ErrNotFound {
+			return ErrAvailabilityNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetAvailability returns the availability windows of a listing that overlap
+// the period from from to to and the future slots in it that do not overlap
+// a tour booked with the agent on any of their listings.
+// A zero from is now and a zero to is DefaultAvailabilityPeriod after from
+func (s *TourService) GetAvailability(propertyID string, from time.Time, to time.Time) (*Availability, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	property, err := s.housingService.GetPropertyByID(propertyID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if from.IsZero() {
+		from = now
+	}
+	if to.IsZero() {
+		to = from.Add(DefaultAvailabilityPeriod)
+	}
+	if !to.After(from) {
+		return nil, InvalidField("to", "range", "to must be after from")
+	}
+	if to.Sub(from) > MaxAvailabilityPeriod {
+		return nil, InvalidField("to", "range", "at most %d days can be listed at once", int(MaxAvailabilityPeriod.Hours()/24))
+	}
+
+	windows, err := s.availability.FindByProperty(ctx, property.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	booked, err := s.repo.FindBooked(ctx, property.AgentKey(), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := []models.TimeSlot{}
+	for _, window := range windows {
+		for _, slot := range windowSlots(window) {
+			start := slot.Start.Time()
+			if start.Before(from) || !start.Before(to) || !start.After(now) || overlapsTour(slot, booked, primitive.NilObjectID) {
+				continue
+			}
+			slots = append(slots, slot)
+		}
+	}
+
+	return &Availability{Windows: windows, Slots: slots}, nil
+}
+
+// windowSlots splits a window into its slots. A remainder shorter than a
+// slot is not offered
+func windowSlots(window models.AvailabilityWindow) []models.TimeSlot {
+	length := time.Duration(window.SlotMinutes) * time.Minute
+	if length <= 0 {
+		return nil
+	}
+
+	var slots []models.TimeSlot
+	end := window.End.Time()
+	for start := window.Start.Time(); !start.Add(length).After(end); start = start.Add(length) {
+		slots = append(slots, models.TimeSlot{
+			Start: primitive.NewDateTimeFromTime(start),
+			End:   primitive.NewDateTimeFromTime(start.Add(length)),
+		})
+	}
+	return slots
+}
+
+// overlapsTour reports whether a tour in tours other than except overlaps slot
+func overlapsTour(slot models.TimeSlot, tours []models.Tour, except primitive.ObjectID) bool {
+	for _, tour := range tours {
+		if tour.ID != except && tour.Start < slot.End && tour.End > slot.Start {
+			return true
+		}
+	}
+	return false
+}
+
+// findSlot returns the slot of a listing's availability starting at start.
+// Slots overlapping another tour booked with the agent are taken; the
+// repository also refuses a second tour of the agent at the same start, so
+// that two users racing for a slot cannot both get it
+func (s *TourService) findSlot(ctx context.Context, propertyID primitive.ObjectID, agentKey string, start time.Time, except primitive.ObjectID) (*models.TimeSlot, error) {
+	if !start.After(time.Now()) {
+		return nil, ErrSlotInPast
+	}
+
+	windows, err := s.availability.FindByProperty(ctx, propertyID, start, start.Add(time.Millisecond))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, window := range windows {
+		for _, slot := range windowSlots(window) {
+			if !slot.Start.Time().Equal(start) {
+				continue
+			}
+
+			booked, err := s.repo.FindBooked(ctx, agentKey, slot.Start.Time(), slot.End.Time())
+			if err != nil {
+				return nil, err
+			}
+			if overlapsTour(slot, booked, except) {
+				return nil, ErrSlotTaken
+			}
+			return &slot, nil
+		}
+	}
+	return nil, ErrSlotUnavailable
+}
+
+// BookTour books the slot of a listing starting at start for a user
+func (s *TourService) BookTour(userID string, propertyID string, start time.Time, note string) (*models.Tour, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, invalidID("user")
+	}
+
+	property, err := s.housingService.GetPropertyByID(propertyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if property.AgentKey() == "" {
+		return nil, ErrNoAgent
+	}
+
+	slot, err := s.findSlot(ctx, property.ID, property.AgentKey(), start, primitive.NilObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	tour := models.Tour{
+		ID:         primitive.NewObjectID(),
+		PropertyID: property.ID,
+		UserID:     userObjID,
+		Agent:      property.Agent,
+		AgentKey:   property.AgentKey(),
+		Start:      slot.Start,
+		End:        slot.End,
+		Status:     models.TourBooked,
+		Note:       note,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	if err := s.repo.Insert(ctx, tour); err != nil {
+		if err == ErrConflict {
+			return nil, ErrSlotTaken
+		}
+		return nil, err
+	}
+
+	return &tour, nil
+}
+
+// GetTourByID retrieves a tour by ID
+func (s *TourService) GetTourByID(id string) (*models.Tour, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, invalidID("tour")
+	}
+
+	tour, err := s.repo.FindByID(ctx, objID)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrTourNotFound
+		}
+		return nil, err
+	}
+
+	return tour, nil
+}
+
+// GetOwnedTour retrieves a tour and checks that it belongs to userID
+func (s *TourService) GetOwnedTour(id string, userID string) (*models.Tour, error) {
+	tour, err := s.GetTourByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if tour.UserID.Hex() != userID {
+		return nil, ErrNotTourOwner
+	}
+
+	return tour, nil
+}
+
+// GetToursByUser retrieves a user's tours, latest first
+func (s *TourService) GetToursByUser(userID string) ([]models.Tour, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, invalidID("user")
+	}
+
+	return s.repo.FindByUser(ctx, objID)
+}
+
+// GetAllTours retrieves every tour, latest first (admin function)
+func (s *TourService) GetAllTours() ([]models.Tour, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return s.repo.FindAll(ctx)
+}
+
+// RescheduleTour moves a booked tour owned by userID to the slot of the
+// same listing starting at start
+func (s *TourService) RescheduleTour(id string, userID string, start time.Time) (*models.Tour, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tour, err := s.GetOwnedTour(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tour.Status != models.TourBooked {
+		return nil, ErrTourNotBooked
+	}
+	if tour.Start.Time().Equal(start) {
+		return tour, nil
+	}
+
+	slot, err := s.findSlot(ctx, tour.PropertyID, tour.AgentKey, start, tour.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tour, err = s.repo.Reschedule(ctx, tour.ID, slot.Start.Time(), slot.End.Time(), time.Now())
+	if err != nil {
+		switch err {
+		case ErrConflict:
+			return nil, ErrSlotTaken
+		case ErrNotFound:
+			return nil, ErrTourNotBooked
+		}
+		return nil, err
+	}
+
+	return tour, nil
+}
+
+// CancelTour cancels a booked tour. The tour is kept so that calendars
+// subscribed to it remove the event
+func (s *TourService) CancelTour(id string) (*models.Tour, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tour, err := s.GetTourByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if tour.Status != models.TourBooked {
+		return nil, ErrTourNotBooked
+	}
+
+	tour, err = s.repo.Cancel(ctx, tour.ID, time.Now())
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrTourNotBooked
+		}
+		return nil, err
+	}
+
+	return tour, nil
+}
+
+// CancelOwnTour cancels a booked tour owned by userID
+func (s *TourService) CancelOwnTour(id string, userID string) (*models.Tour, error) {
+	if _, err := s.GetOwnedTour(id, userID); err != nil {
+		return nil, err
+	}
+
+	return s.CancelTour(id)
+}
+
+// ListingDeleted cancels the tours of a deleted listing and removes its
+// availability
+func (s *TourService) ListingDeleted(property models.Housing) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.repo.CancelByProperty(ctx, property.ID, time.Now()); err != nil {
+		log.Printf("Failed to cancel tours of property %s: %v", property.ID.Hex(), err)
+	}
+	if err := s.availability.DeleteByProperty(ctx, property.ID); err != nil {
+		log.Printf("Failed to delete availability of property %s: %v", property.ID.Hex(), err)
+	}
+}
@@ -0,0 +1,219 @@
+package services_test
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"gatorswamp/models"
+	"gatorswamp/services"
+)
+
+// tourFixture is an agent with two listings and a user to book tours
+type tourFixture struct {
+	svc      *services.Services
+	pool     *models.Housing
+	loft     *models.Housing
+	userID   string
+	tomorrow time.Time
+}
+
+// newTourFixture creates the listings of an agent and a user, and opens the
+// pool house for tours tomorrow from 10:00 to 12:00 UTC in 30 minute slots
+func newTourFixture(t *testing.T) *tourFixture {
+	t.Helper()
+	svc := newServices(t)
+	agent := models.Agent{Name: "Ann Agent", Phone: "352-555-0100"}
+	f := &tourFixture{
+		svc:      svc,
+		userID:   createUser(t, svc, "gator@ufl.edu", "Str0ngPass").ID,
+		tomorrow: time.Now().UTC().Add(24 * time.Hour).Truncate(24 * time.Hour),
+	}
+	for _, listing := range []**models.Housing{&f.pool, &f.loft} {
+		property, err := svc.Housing.CreateProperty(models.Housing{Name: "Listing", Address: "1 Main St", PriceCents: 100000, Agent: agent})
+		if err != nil {
+			t.Fatalf("CreateProperty() error = %v", err)
+		}
+		*listing = property
+	}
+	f.window(t, f.pool, 10, 12, 30)
+	return f
+}
+
+// at returns tomorrow's time at hour and minute
+func (f *tourFixture) at(hour, minute int) time.Time {
+	return f.tomorrow.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+// window opens property for tours tomorrow between two hours
+func (f *tourFixture) window(t *testing.T, property *models.Housing, from, to, slotMinutes int) *models.AvailabilityWindow {
+	t.Helper()
+	window, err := f.svc.Tours.AddAvailability(property.ID.Hex(), f.userID, f.at(from, 0), f.at(to, 0), slotMinutes)
+	if err != nil {
+		t.Fatalf("AddAvailability() error = %v", err)
+	}
+	return window
+}
+
+// slots returns the start times of the free slots of property tomorrow
+func (f *tourFixture) slots(t *testing.T, property *models.Housing) []string {
+	t.Helper()
+	availability, err := f.svc.Tours.GetAvailability(property.ID.Hex(), f.tomorrow, f.tomorrow.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetAvailability() error = %v", err)
+	}
+	starts := []string{}
+	for _, slot := range availability.Slots {
+		starts = append(starts, slot.Start.Time().UTC().Format("15:04"))
+	}
+	return starts
+}
+
+func TestBookTour(t *testing.T) {
+	f := newTourFixture(t)
+	other := createUser(t, f.svc, "alligator@ufl.edu", "Str0ngPass").ID
+
+	tour, err := f.svc.Tours.BookTour(f.userID, f.pool.ID.Hex(), f.at(10, 30), "")
+	if err != nil {
+		t.Fatalf("BookTour() error = %v", err)
+	}
+	if !tour.End.Time().Equal(f.at(11, 0)) || tour.Status != models.TourBooked {
+		t.Errorf("tour = %+v, want booked until 11:00", tour)
+	}
+
+	tests := []struct {
+		name     string
+		property *models.Housing
+		start    time.Time
+		want     error
+	}{
+		{"slot taken", f.pool, f.at(10, 30), services.ErrSlotTaken},
+		{"not a slot start", f.pool, f.at(10, 10), services.ErrSlotUnavailable},
+		{"outside the window", f.pool, f.at(13, 0), services.ErrSlotUnavailable},
+		{"other listing without availability", f.loft, f.at(10, 0), services.ErrSlotUnavailable},
+		{"in the past", f.pool, time.Now().Add(-time.Hour), services.ErrSlotInPast},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.svc.Tours.BookTour(other, tt.property.ID.Hex(), tt.start, ""); !errors.Is(err, tt.want) {
+				t.Errorf("BookTour() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if got, want := f.slots(t, f.pool), []string{"10:00", "11:00", "11:30"}; !slices.Equal(got, want) {
+		t.Errorf("free slots = %v, want %v", got, want)
+	}
+}
+
+func TestBookTourWithoutAgent(t *testing.T) {
+	svc := newServices(t)
+	user := createUser(t, svc, "gator@ufl.edu", "Str0ngPass")
+	property := createListing(t, svc, "Pool House")
+
+	if _, err := svc.Tours.BookTour(user.ID, property.ID.Hex(), time.Now().Add(time.Hour), ""); !errors.Is(err, services.ErrNoAgent) {
+		t.Errorf("BookTour() error = %v, want %v", err, services.ErrNoAgent)
+	}
+}
+
+func TestBookTourConcurrent(t *testing.T) {
+	const n = 8
+	f := newTourFixture(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := f.svc.Tours.BookTour(f.userID, f.pool.ID.Hex(), f.at(10, 0), "")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	booked := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			booked++
+		case !errors.Is(err, services.ErrSlotTaken):
+			t.Errorf("BookTour() error = %v, want %v", err, services.ErrSlotTaken)
+		}
+	}
+	if booked != 1 {
+		t.Errorf("booked the slot %d times, want once", booked)
+	}
+}
+
+func TestBookTourAcrossListings(t *testing.T) {
+	f := newTourFixture(t)
+
+	// The agent's windows cannot overlap across their listings
+	if _, err := f.svc.Tours.AddAvailability(f.loft.ID.Hex(), f.userID, f.at(11, 0), f.at(13, 0), 30); !errors.Is(err, services.ErrAvailabilityOverlap) {
+		t.Fatalf("AddAvailability() overlapping error = %v, want %v", err, services.ErrAvailabilityOverlap)
+	}
+
+	// A tour outlives its window, and keeps the agent busy when the time is
+	// offered again on another listing in longer slots
+	if _, err := f.svc.Tours.BookTour(f.userID, f.pool.ID.Hex(), f.at(10, 0), ""); err != nil {
+		t.Fatalf("BookTour() error = %v", err)
+	}
+	availability, err := f.svc.Tours.GetAvailability(f.pool.ID.Hex(), f.tomorrow, f.tomorrow.Add(24*time.Hour))
+	if err != nil || len(availability.Windows) != 1 {
+		t.Fatalf("GetAvailability() = %+v, %v, want one window", availability, err)
+	}
+	if err := f.svc.Tours.RemoveAvailability(f.pool.ID.Hex(), availability.Windows[0].ID.Hex()); err != nil {
+		t.Fatalf("RemoveAvailability() error = %v", err)
+	}
+	f.window(t, f.loft, 10, 12, 60)
+
+	if _, err := f.svc.Tours.BookTour(f.userID, f.loft.ID.Hex(), f.at(10, 0), ""); !errors.Is(err, services.ErrSlotTaken) {
+		t.Errorf("BookTour() over the other listing's tour error = %v, want %v", err, services.ErrSlotTaken)
+	}
+	if got, want := f.slots(t, f.loft), []string{"11:00"}; !slices.Equal(got, want) {
+		t.Errorf("free slots = %v, want %v", got, want)
+	}
+}
+
+func TestRescheduleAndCancelTour(t *testing.T) {
+	f := newTourFixture(t)
+	other := createUser(t, f.svc, "alligator@ufl.edu", "Str0ngPass").ID
+
+	mine, err := f.svc.Tours.BookTour(f.userID, f.pool.ID.Hex(), f.at(10, 0), "")
+	if err != nil {
+		t.Fatalf("BookTour() error = %v", err)
+	}
+	if _, err := f.svc.Tours.BookTour(other, f.pool.ID.Hex(), f.at(10, 30), ""); err != nil {
+		t.Fatalf("BookTour() error = %v", err)
+	}
+
+	if _, err := f.svc.Tours.RescheduleTour(mine.ID.Hex(), f.userID, f.at(10, 30)); !errors.Is(err, services.ErrSlotTaken) {
+		t.Errorf("RescheduleTour() to a taken slot error = %v, want %v", err, services.ErrSlotTaken)
+	}
+	if _, err := f.svc.Tours.RescheduleTour(mine.ID.Hex(), other, f.at(11, 0)); !errors.Is(err, services.ErrNotTourOwner) {
+		t.Errorf("RescheduleTour() by another user error = %v, want %v", err, services.ErrNotTourOwner)
+	}
+	if _, err := f.svc.Tours.RescheduleTour(mine.ID.Hex(), f.userID, f.at(11, 0)); err != nil {
+		t.Fatalf("RescheduleTour() error = %v", err)
+	}
+	if got, want := f.slots(t, f.pool), []string{"10:00", "11:30"}; !slices.Equal(got, want) {
+		t.Errorf("free slots after rescheduling = %v, want %v", got, want)
+	}
+
+	if _, err := f.svc.Tours.CancelOwnTour(mine.ID.Hex(), other); !errors.Is(err, services.ErrNotTourOwner) {
+		t.Errorf("CancelOwnTour() by another user error = %v, want %v", err, services.ErrNotTourOwner)
+	}
+	if _, err := f.svc.Tours.CancelOwnTour(mine.ID.Hex(), f.userID); err != nil {
+		t.Fatalf("CancelOwnTour() error = %v", err)
+	}
+	if _, err := f.svc.Tours.CancelOwnTour(mine.ID.Hex(), f.userID); !errors.Is(err, services.ErrTourNotBooked) {
+		t.Errorf("CancelOwnTour() again error = %v, want %v", err, services.ErrTourNotBooked)
+	}
+	if got, want := f.slots(t, f.pool), []string{"10:00", "11:00", "11:30"}; !slices.Equal(got, want) {
+		t.Errorf("free slots after cancelling = %v, want %v", got, want)
+	}
+}
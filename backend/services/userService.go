@@ -2,49 +2,99 @@ package services
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"log"
 	"time"
 
+	"gatorswamp/config"
+	"gatorswamp/mailer"
 	"gatorswamp/models"
-	"gatorswamp/utils"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService struct {
-	collection *mongo.Collection
+	repo           UserRepository
+	attempts       LoginAttemptRepository
+	sessionService *SessionService
+	tokenService   *AccountTokenService
+	mailer         mailer.Mailer
 }
 
 type UserResponse struct {
-	ID         string `json:"id"`
-	FirstName  string `json:"firstName"`
-	LastName   string `json:"lastName"`
-	Email      string `json:"email"`
-	Role       string `json:"role"`
+	ID                    string `json:"id"`
+	FirstName             string `json:"firstName"`
+	LastName              string `json:"lastName"`
+	Email                 string `json:"email"`
+	Phone                 string `json:"phone,omitempty"`
+	Role                  string `json:"role"`
+	EmailVerified         bool   `json:"emailVerified"`
+	Disabled              bool   `json:"disabled,omitempty"`
+	PasswordResetRequired bool   `json:"passwordResetRequired,omitempty"`
 }
 
+// UserQuery describes an admin search over users
+type UserQuery struct {
+	Search   string
+	Role     string
+	Disabled *bool
+	Page     int
+	Limit    int
+}
+
+// UserPage is a page of users returned to admins
+type UserPage struct {
+	Items []UserResponse `json:"items"`
+	Total int64          `json:"total"`
+	Page  int            `json:"page"`
+	Limit int            `json:"limit"`
+}
+
+// Errors returned by the user service
+var (
+	ErrUserNotFound         = NewError(ErrNotFound, "user_not_found", "user not found")
+	ErrAccountDisabled      = NewError(ErrForbidden, "account_disabled", "account disabled")
+	ErrPasswordResetNeeded  = NewError(ErrForbidden, "password_reset_required", "password reset required")
+	ErrInvalidCredentials   = NewError(ErrUnauthorized, "invalid_credentials", "invalid email or password")
+	ErrEmailTaken           = NewError(ErrConflict, "email_taken", "user with this email already exists")
+	ErrEmailAlreadyVerified = NewError(ErrConflict, "email_already_verified", "email already verified")
+)
+
 type AuthResponse struct {
-	User  UserResponse `json:"user"`
-	Token string       `json:"token"`
+	User         UserResponse `json:"user"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refreshToken"`
 }
 
-func NewUserService(collection *mongo.Collection) *UserService {
+func NewUserService(repo UserRepository, attempts LoginAttemptRepository, sessions *SessionService, tokens *AccountTokenService) *UserService {
 	return &UserService{
-		collection: collection,
+		repo:           repo,
+		attempts:       attempts,
+		sessionService: sessions,
+		tokenService:   tokens,
+		mailer:         mailer.Default(),
 	}
 }
 
+// Sessions returns the session service used to issue this service's tokens
+func (s *UserService) Sessions() *SessionService {
+	return s.sessionService
+}
+
 func (s *UserService) AuthenticateUser(email, password string) (*AuthResponse, error) {
-	var user models.Users
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	err := s.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	// Refuse locked accounts before spending time on the password
+	now := time.Now()
+	if err := s.checkLoginLockout(ctx, email, now); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.FindByEmail(ctx, email)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("invalid email or password")
+		if err == ErrNotFound {
+			return nil, s.recordFailedLogin(ctx, email, now, ErrInvalidCredentials)
 		}
 		return nil, err
 	}
@@ -52,24 +102,31 @@ func (s *UserService) AuthenticateUser(email, password string) (*AuthResponse, e
 	// Compare passwords
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		return nil, errors.New("invalid email or password")
+		return nil, s.recordFailedLogin(ctx, email, now, ErrInvalidCredentials)
+	}
+
+	if err := s.attempts.Clear(ctx, loginKey(email)); err != nil {
+		return nil, err
+	}
+
+	// Only reveal the account state once the password has been verified
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
+	if user.PasswordResetRequired {
+		return nil, ErrPasswordResetNeeded
 	}
 
-	// Generate token
-	token, err := utils.GenerateToken(user.ID.Hex())
+	// Start a session
+	tokens, err := s.sessionService.CreateSession(user.ID)
 	if err != nil {
 		return nil, err
 	}
 
 	return &AuthResponse{
-		User: UserResponse{
-			ID:         user.ID.Hex(),
-			FirstName:  user.FirstName,
-			LastName:   user.LastName,
-			Email:      user.Email,
-			Role:       user.Role,
-		},
-		Token: token,
+		User:         ToUserResponse(*user),
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}, nil
 }
 
@@ -77,10 +134,15 @@ func (s *UserService) CreateUser(userData models.Users) (*AuthResponse, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	userData.Email = models.NormalizeEmail(userData.Email)
+
 	// Check if user with same email already exists
-	existingUser := s.collection.FindOne(ctx, bson.M{"email": userData.Email})
-	if existingUser.Err() == nil {
-		return nil, errors.New("user with this email already exists")
+	_, err := s.repo.FindByEmail(ctx, userData.Email)
+	if err == nil {
+		return nil, ErrEmailTaken
+	}
+	if err != ErrNotFound {
+		return nil, err
 	}
 
 	// Hash the password
@@ -98,27 +160,35 @@ func (s *UserService) CreateUser(userData models.Users) (*AuthResponse, error) {
 		userData.Role = "user"
 	}
 
-	// Insert user into database
-	_, err = s.collection.InsertOne(ctx, userData)
+	// New accounts must prove they own their email address
+	userData.EmailVerified = false
+
+	// Insert user into database; the unique email index catches a
+	// registration that raced past the check above
+	err = s.repo.Insert(ctx, userData)
 	if err != nil {
+		if err == ErrConflict {
+			return nil, ErrEmailTaken
+		}
 		return nil, err
 	}
 
-	// Generate token
-	token, err := utils.GenerateToken(userData.ID.Hex())
+	// A failed email should not fail the registration; the user can ask
+	// for a new verification link
+	if err := s.SendVerificationEmail(&userData); err != nil {
+		log.Println("Failed to send verification email:", err)
+	}
+
+	// Start a session
+	tokens, err := s.sessionService.CreateSession(userData.ID)
 	if err != nil {
 		return nil, err
 	}
 
 	return &AuthResponse{
-		User: UserResponse{
-			ID:         userData.ID.Hex(),
-			FirstName:  userData.FirstName,
-			LastName:   userData.LastName,
-			Email:      userData.Email,
-			Role:       userData.Role,
-		},
-		Token: token,
+		User:         ToUserResponse(userData),
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}, nil
 }
 
@@ -128,15 +198,242 @@ func (s *UserService) GetUserByID(userID string) (*models.Users, error) {
 	defer cancel()
 
 	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, invalidID("user")
+	}
+
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ToUserResponse converts a user to its public representation
+func ToUserResponse(user models.Users) UserResponse {
+	return UserResponse{
+		ID:                    user.ID.Hex(),
+		FirstName:             user.FirstName,
+		LastName:              user.LastName,
+		Email:                 user.Email,
+		Phone:                 user.Phone,
+		Role:                  user.Role,
+		EmailVerified:         user.EmailVerified,
+		Disabled:              user.Disabled,
+		PasswordResetRequired: user.PasswordResetRequired,
+	}
+}
+
+// ListUsers returns a page of users matching the query, newest first
+func (s *UserService) ListUsers(query UserQuery) (*UserPage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultPageLimit
+	}
+	if query.Limit > MaxPageLimit {
+		query.Limit = MaxPageLimit
+	}
+
+	users, total, err := s.repo.List(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 
-	var user models.Users
-	err = s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+	page := &UserPage{Items: []UserResponse{}, Total: total, Page: query.Page, Limit: query.Limit}
+	for _, user := range users {
+		page.Items = append(page.Items, ToUserResponse(user))
+	}
+
+	return page, nil
+}
+
+// SetUserRole changes a user's role
+func (s *UserService) SetUserRole(userID string, role string) (*models.Users, error) {
+	if role != models.RoleAdmin && role != models.RoleAgent && role != models.RoleUser {
+		return nil, InvalidField("role", "oneof", "role must be admin, agent or user")
+	}
+
+	return s.updateUser(userID, UserPatch{Role: &role})
+}
+
+// SetUserDisabled disables or re-enables a user's account. Disabling an
+// account also revokes all of its sessions
+func (s *UserService) SetUserDisabled(userID string, disabled bool) (*models.Users, error) {
+	user, err := s.updateUser(userID, UserPatch{Disabled: &disabled})
+	if err != nil || !disabled {
+		return user, err
+	}
+
+	if _, err := s.sessionService.RevokeAllForUser(user.ID, "account disabled"); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// RequirePasswordReset forces the user to reset their password before
+// they can sign in again, revoking all of their sessions
+func (s *UserService) RequirePasswordReset(userID string) (*models.Users, error) {
+	required := true
+	user, err := s.updateUser(userID, UserPatch{PasswordResetRequired: &required})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.sessionService.RevokeAllForUser(user.ID, "password reset required"); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// updateUser applies patch to a user and returns the updated user
+func (s *UserService) updateUser(userID string, patch UserPatch) (*models.Users, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, invalidID("user")
+	}
+
+	user, err := s.repo.Update(ctx, id, patch)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// RefreshSession rotates a refresh token and returns a new token pair for
+// its user. Sessions of disabled or locked accounts are revoked instead
+func (s *UserService) RefreshSession(refreshToken string) (*AuthResponse, error) {
+	tokens, session, err := s.sessionService.Refresh(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.GetUserByID(session.UserID.Hex())
+	if err != nil {
+		s.sessionService.RevokeSession(tokens.SessionID, "user not found")
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if user.Disabled || user.PasswordResetRequired {
+		s.sessionService.RevokeSession(tokens.SessionID, "account locked")
+		if user.Disabled {
+			return nil, ErrAccountDisabled
+		}
+		return nil, ErrPasswordResetNeeded
+	}
+
+	return &AuthResponse{
+		User:         ToUserResponse(*user),
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+// RequestPasswordReset emails a password reset link to the account with the
+// given email. Unknown addresses are ignored so that callers cannot probe
+// which emails are registered
+func (s *UserService) RequestPasswordReset(email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	token, err := s.tokenService.Issue(user.ID, models.TokenPurposePasswordReset, PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your GatorSwamp password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s/reset-password?token=%s\n\nIf you did not ask for this, you can ignore this email.\n",
+			user.FirstName, PasswordResetTTL, config.AppBaseURL(), token),
+	})
+}
+
+// ResetPassword sets a new password using a password reset token. It clears
+// any admin-forced reset and login lockout and signs the user out everywhere
+func (s *UserService) ResetPassword(token string, newPassword string) error {
+	accountToken, err := s.tokenService.Consume(token, models.TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	password := string(hashedPassword)
+	required := false
+	user, err := s.updateUser(accountToken.UserID.Hex(), UserPatch{
+		Password:              &password,
+		PasswordResetRequired: &required,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.attempts.Clear(ctx, loginKey(user.Email)); err != nil {
+		return err
+	}
+
+	_, err = s.sessionService.RevokeAllForUser(user.ID, "password reset")
+	return err
+}
+
+// SendVerificationEmail emails a new email verification link to user
+func (s *UserService) SendVerificationEmail(user *models.Users) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := s.tokenService.Issue(user.ID, models.TokenPurposeEmailVerification, EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your GatorSwamp email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s/verify-email?token=%s\n",
+			user.FirstName, EmailVerificationTTL, config.AppBaseURL(), token),
+	})
+}
+
+// VerifyEmail marks the owner of an email verification token as verified
+func (s *UserService) VerifyEmail(token string) (*models.Users, error) {
+	accountToken, err := s.tokenService.Consume(token, models.TokenPurposeEmailVerification)
 	if err != nil {
 		return nil, err
 	}
 
-	return &user, nil
+	verified := true
+	return s.updateUser(accountToken.UserID.Hex(), UserPatch{EmailVerified: &verified})
 }
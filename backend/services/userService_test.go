@@ -0,0 +1,170 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gatorswamp/blobstore"
+	"gatorswamp/models"
+	"gatorswamp/repositories/memoryrepo"
+	"gatorswamp/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAuthenticateUserLockout(t *testing.T) {
+	const email, password = "gator@ufl.edu", "Str0ngPass"
+
+	// Each attempt logs in with the password and expects the error code,
+	// empty for a successful login
+	type attempt struct {
+		email    string
+		password string
+		wantCode string
+	}
+	wrong := attempt{email, "wrong", "invalid_credentials"}
+	locked := attempt{email, password, "login_locked"}
+	ok := attempt{email, password, ""}
+
+	tests := []struct {
+		name     string
+		attempts []attempt
+		unlock   bool
+	}{
+		{
+			name:     "locks on the fifth failure",
+			attempts: []attempt{wrong, wrong, wrong, wrong, {email, "wrong", "login_locked"}, locked},
+		},
+		{
+			name:     "success clears the failures",
+			attempts: []attempt{wrong, wrong, wrong, wrong, ok, wrong, wrong, wrong, wrong, ok},
+		},
+		{
+			name:     "emails differing in case share a count",
+			attempts: []attempt{wrong, wrong, wrong, wrong, {"Gator@UFL.edu", "wrong", "login_locked"}, locked},
+		},
+		{
+			name:     "unknown emails are counted",
+			attempts: []attempt{{"nobody@ufl.edu", "x", "invalid_credentials"}, {"nobody@ufl.edu", "x", "invalid_credentials"}, {"nobody@ufl.edu", "x", "invalid_credentials"}, {"nobody@ufl.edu", "x", "invalid_credentials"}, {"nobody@ufl.edu", "x", "login_locked"}, ok},
+		},
+		{
+			name:     "unlock lifts the lockout",
+			attempts: []attempt{wrong, wrong, wrong, wrong, {email, "wrong", "login_locked"}},
+			unlock:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newServices(t)
+			user := createUser(t, svc, email, password)
+
+			for i, a := range tt.attempts {
+				_, err := svc.Users.AuthenticateUser(a.email, a.password)
+				if a.wantCode == "" {
+					if err != nil {
+						t.Fatalf("attempt %d: AuthenticateUser() error = %v", i, err)
+					}
+					continue
+				}
+				var serr *services.Error
+				if !errors.As(err, &serr) || serr.Code != a.wantCode {
+					t.Fatalf("attempt %d: AuthenticateUser() error = %v, want %s", i, err, a.wantCode)
+				}
+				// The first lockout lasts a minute, counted from the locking failure
+				if a.wantCode == "login_locked" && (serr.RetryAfter <= 0 || serr.RetryAfter > time.Minute) {
+					t.Errorf("attempt %d: RetryAfter = %v, want at most %v", i, serr.RetryAfter, time.Minute)
+				}
+			}
+
+			if tt.unlock {
+				if err := svc.Users.UnlockLogin(user.ID); err != nil {
+					t.Fatalf("UnlockLogin() error = %v", err)
+				}
+				if _, err := svc.Users.AuthenticateUser(email, password); err != nil {
+					t.Errorf("AuthenticateUser() after unlock error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateUserEmailCase(t *testing.T) {
+	svc := newServices(t)
+	user := createUser(t, svc, " Gator@UFL.edu", "Str0ngPass")
+	if user.Email != "gator@ufl.edu" {
+		t.Errorf("stored email = %q, want gator@ufl.edu", user.Email)
+	}
+
+	_, err := svc.Users.CreateUser(models.Users{FirstName: "Test", LastName: "User", Email: "GATOR@ufl.edu", Password: "Str0ngPass"})
+	if !errors.Is(err, services.ErrEmailTaken) {
+		t.Errorf("CreateUser() with the email in another case error = %v, want %v", err, services.ErrEmailTaken)
+	}
+
+	if _, err := svc.Users.AuthenticateUser("gAtOr@ufl.EDU", "Str0ngPass"); err != nil {
+		t.Errorf("AuthenticateUser() in another case error = %v", err)
+	}
+}
+
+func TestCreateUserConcurrent(t *testing.T) {
+	const n = 8
+	svc := newServices(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.Users.CreateUser(models.Users{FirstName: "Test", LastName: "User", Email: "gator@ufl.edu", Password: "Str0ngPass"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, services.ErrEmailTaken):
+			t.Errorf("CreateUser() error = %v, want %v", err, services.ErrEmailTaken)
+		}
+	}
+	if created != 1 {
+		t.Errorf("created %d users with one email, want 1", created)
+	}
+}
+
+func TestClaimPlaceholderAgentEmail(t *testing.T) {
+	repos := memoryrepo.NewRepositories()
+	svc := services.New(repos, blobstore.NewLocalStore(t.TempDir(), "/uploads"))
+	createUser(t, svc, "taken@ufl.edu", "Str0ngPass")
+
+	placeholder := models.Users{
+		ID:        primitive.NewObjectID(),
+		FirstName: "Ann",
+		LastName:  "Agent",
+		Email:     "agent-1@" + models.PlaceholderAgentDomain,
+		Role:      models.RoleAgent,
+		Disabled:  true,
+	}
+	if err := repos.Users.Insert(context.Background(), placeholder); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	if _, err := svc.Users.ClaimPlaceholderAgent(placeholder.ID.Hex(), "Taken@UFL.edu"); !errors.Is(err, services.ErrEmailTaken) {
+		t.Errorf("ClaimPlaceholderAgent() with a taken email error = %v, want %v", err, services.ErrEmailTaken)
+	}
+
+	claimed, err := svc.Users.ClaimPlaceholderAgent(placeholder.ID.Hex(), " Ann@Realty.com ")
+	if err != nil {
+		t.Fatalf("ClaimPlaceholderAgent() error = %v", err)
+	}
+	if claimed.Email != "ann@realty.com" {
+		t.Errorf("claimed email = %q, want ann@realty.com", claimed.Email)
+	}
+}
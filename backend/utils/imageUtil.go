@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+)
+
+// exifHeader prefixes the EXIF payload of a JPEG APP1 segment
+var exifHeader = []byte("Exif\x00\x00")
+
+// xmpHeaders prefix the XMP packets of JPEG APP1 segments, the main packet
+// and the extensions it overflows into
+var xmpHeaders = [][]byte{
+	[]byte("http://ns.adobe.com/xap/1.0/\x00"),
+	[]byte("http://ns.adobe.com/xmp/extension/\x00"),
+}
+
+// pngMetadataKeywords are the keywords of PNG text chunks holding XMP or
+// EXIF metadata. Tools such as ImageMagick store raw profiles in text chunks
+var pngMetadataKeywords = []string{
+	"XML:com.adobe.xmp",
+	"Raw profile type exif",
+	"Raw profile type APP1",
+	"Raw profile type xmp",
+}
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// gpsIFDTag is the IFD0 tag pointing at the GPS information directory
+const gpsIFDTag = 0x8825
+
+// StripJPEGMetadata returns a copy of a JPEG file with the GPS directory of
+// its EXIF metadata blanked out and its XMP packets removed, since XMP can
+// repeat the position. Other EXIF metadata, such as the orientation, is left
+// alone
+func StripJPEGMetadata(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return append([]byte(nil), data...)
+	}
+
+	out := append([]byte(nil), data[:2]...)
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			break
+		}
+
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF:
+			// Fill byte before a marker
+			out = append(out, data[i])
+			i++
+			continue
+		case marker == 0xDA || marker == 0xD9:
+			// Metadata only appears before the start of scan
+			return append(out, data[i:]...)
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			// Markers without a length
+			out = append(out, data[i:i+2]...)
+			i += 2
+			continue
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			break
+		}
+
+		if marker == 0xE1 && isXMP(data[i+4:end]) {
+			i = end
+			continue
+		}
+
+		start := len(out)
+		out = append(out, data[i:end]...)
+		if segment := out[start+4:]; marker == 0xE1 && bytes.HasPrefix(segment, exifHeader) {
+			wipeExifGPS(segment[len(exifHeader):])
+		}
+		i = end
+	}
+
+	return append(out, data[i:]...)
+}
+
+// isXMP reports whether the payload of a JPEG APP1 segment is an XMP packet
+func isXMP(segment []byte) bool {
+	for _, header := range xmpHeaders {
+		if bytes.HasPrefix(segment, header) {
+			return true
+		}
+	}
+	return false
+}
+
+// StripPNGMetadata returns a copy of a PNG file without its eXIf chunks,
+// which is where PNG stores EXIF metadata including GPS positions, and
+// without the text chunks holding XMP or raw EXIF profiles
+func StripPNGMetadata(data []byte) []byte {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return append([]byte(nil), data...)
+	}
+
+	out := append([]byte(nil), pngSignature...)
+	for i := len(pngSignature); i < len(data); {
+		if i+8 > len(data) {
+			out = append(out, data[i:]...)
+			break
+		}
+
+		// Length, type, data and CRC
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:]))
+		if end > len(data) || end < i {
+			out = append(out, data[i:]...)
+			break
+		}
+
+		if !isPNGMetadata(string(data[i+4:i+8]), data[i+8:end-4]) {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+
+	return out
+}
+
+// isPNGMetadata reports whether a PNG chunk of the given type and data holds
+// EXIF or XMP metadata. Text chunks start with their keyword and a zero byte
+func isPNGMetadata(chunkType string, chunk []byte) bool {
+	switch chunkType {
+	case "eXIf":
+		return true
+	case "iTXt", "tEXt", "zTXt":
+		keyword, _, _ := bytes.Cut(chunk, []byte{0})
+		for _, metadata := range pngMetadataKeywords {
+			if string(keyword) == metadata {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wipeExifGPS zeroes the GPS directory of a TIFF-structured EXIF payload in
+// place, leaving an empty directory behind so offsets stay valid
+func wipeExifGPS(tiff []byte) {
+	if len(tiff) < 8 {
+		return
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return
+	}
+
+	ifd0 := int(order.Uint32(tiff[4:]))
+	if ifd0 < 8 || ifd0+2 > len(tiff) {
+		return
+	}
+
+	count := int(order.Uint16(tiff[ifd0:]))
+	for n := 0; n < count; n++ {
+		entry := ifd0 + 2 + n*12
+		if entry+12 > len(tiff) {
+			return
+		}
+		if order.Uint16(tiff[entry:]) == gpsIFDTag {
+			wipeIFD(tiff, order, int(order.Uint32(tiff[entry+8:])))
+		}
+	}
+}
+
+// wipeIFD zeroes every entry of the directory at offset and the values
+// they point to, then marks the directory empty
+func wipeIFD(tiff []byte, order binary.ByteOrder, offset int) {
+	if offset < 8 || offset+2 > len(tiff) {
+		return
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+	for n := 0; n < count; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			break
+		}
+
+		// Values wider than four bytes live elsewhere in the payload
+		size := tiffTypeSize(order.Uint16(tiff[entry+2:])) * int64(order.Uint32(tiff[entry+4:]))
+		if size > 4 {
+			value := int64(order.Uint32(tiff[entry+8:]))
+			if value+size <= int64(len(tiff)) {
+				clear(tiff[value : value+size])
+			}
+		}
+		clear(tiff[entry : entry+12])
+	}
+
+	order.PutUint16(tiff[offset:], 0)
+}
+
+// tiffTypeSize returns the size in bytes of one value of a TIFF field type
+func tiffTypeSize(fieldType uint16) int64 {
+	switch fieldType {
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11: // LONG, SLONG, FLOAT
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	default: // BYTE, ASCII, SBYTE, UNDEFINED
+		return 1
+	}
+}
+
+// thumbnailSamples is how many source pixels along each axis are averaged
+// into a thumbnail pixel. Large images are sampled rather than read whole
+const thumbnailSamples = 4
+
+// Thumbnail scales img down so that neither side exceeds maxSide, averaging
+// a grid of the source pixels covered by each thumbnail pixel. Smaller
+// images are copied at their original size. Pixels are read from img
+// directly, so no full size copy is made
+func Thumbnail(img image.Image, maxSide int) *image.RGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	thumbWidth, thumbHeight := width, height
+	if width > maxSide || height > maxSide {
+		if width >= height {
+			thumbWidth, thumbHeight = maxSide, max(1, height*maxSide/width)
+		} else {
+			thumbWidth, thumbHeight = max(1, width*maxSide/height), maxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for y := 0; y < thumbHeight; y++ {
+		y0, y1 := y*height/thumbHeight, max((y+1)*height/thumbHeight, y*height/thumbHeight+1)
+		for x := 0; x < thumbWidth; x++ {
+			x0, x1 := x*width/thumbWidth, max((x+1)*width/thumbWidth, x*width/thumbWidth+1)
+
+			// Colors are premultiplied 16-bit values, like the thumbnail's
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy += sampleStep(y0, y1) {
+				for sx := x0; sx < x1; sx += sampleStep(x0, x1) {
+					pr, pg, pb, pa := img.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+					r += pr
+					g += pg
+					b += pb
+					a += pa
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(b / n >> 8), uint8(a / n >> 8)})
+		}
+	}
+
+	return dst
+}
+
+// sampleStep returns the stride that visits at most thumbnailSamples
+// pixels between from and to
+func sampleStep(from, to int) int {
+	return max(1, (to-from+thumbnailSamples-1)/thumbnailSamples)
+}
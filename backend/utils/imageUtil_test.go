@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage returns a width by height image filled with c
+func testImage(width, height int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// exifTIFF is a big-endian EXIF payload whose first directory holds an
+// orientation and a pointer to a GPS directory with a latitude
+func exifTIFF() []byte {
+	tiff := make([]byte, 80)
+	order := binary.BigEndian
+	copy(tiff, "MM\x00\x2a")
+	order.PutUint32(tiff[4:], 8)
+
+	// IFD0: orientation 6 and the GPS directory at 38
+	order.PutUint16(tiff[8:], 2)
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], 6)
+	order.PutUint16(tiff[22:], gpsIFDTag)
+	order.PutUint16(tiff[24:], 4)
+	order.PutUint32(tiff[26:], 1)
+	order.PutUint32(tiff[30:], 38)
+
+	// GPS IFD: a latitude of three rationals stored at 56
+	order.PutUint16(tiff[38:], 1)
+	order.PutUint16(tiff[40:], 0x0002)
+	order.PutUint16(tiff[42:], 5)
+	order.PutUint32(tiff[44:], 3)
+	order.PutUint32(tiff[48:], 56)
+	for i, v := range []uint32{29, 1, 39, 1, 6, 1} {
+		order.PutUint32(tiff[56+i*4:], v)
+	}
+	return tiff
+}
+
+// jpegSegment returns a JPEG marker segment holding payload
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func TestStripJPEGMetadata(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, testImage(16, 16, color.White), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte{0xFF, 0xD8}
+	data = append(data, jpegSegment(0xE1, append(append([]byte(nil), exifHeader...), exifTIFF()...))...)
+	data = append(data, jpegSegment(0xE1, append(append([]byte(nil), xmpHeaders[0]...), "<x:xmpmeta>29.65,-82.32</x:xmpmeta>"...))...)
+	data = append(data, encoded.Bytes()[2:]...)
+
+	out := StripJPEGMetadata(data)
+
+	if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+		t.Fatalf("stripped JPEG does not decode: %v", err)
+	}
+	if bytes.Contains(out, []byte("xmpmeta")) {
+		t.Error("XMP packet was kept")
+	}
+
+	at := bytes.Index(out, exifHeader)
+	if at < 0 {
+		t.Fatal("EXIF segment was removed")
+	}
+	tiff := out[at+len(exifHeader) : at+len(exifHeader)+80]
+	if got := binary.BigEndian.Uint16(tiff[18:]); got != 6 {
+		t.Errorf("orientation = %d, want 6", got)
+	}
+	if got := binary.BigEndian.Uint16(tiff[38:]); got != 0 {
+		t.Errorf("GPS directory has %d entries, want 0", got)
+	}
+	if !bytes.Equal(tiff[38:], make([]byte, 42)) {
+		t.Errorf("GPS directory and values = %x, want zeros", tiff[38:])
+	}
+
+	if len(data) == len(out) {
+		t.Error("output is as long as the input")
+	}
+	if got := binary.BigEndian.Uint16(data[at+len(exifHeader)+18:]); got != 6 {
+		t.Error("input was modified")
+	}
+}
+
+// pngChunk returns a PNG chunk of the given type holding data
+func pngChunk(chunkType string, data string) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func TestStripPNGMetadata(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, testImage(16, 16, color.White)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Metadata chunks go after the 13 byte IHDR chunk
+	afterHeader := len(pngSignature) + 25
+	data := append([]byte(nil), encoded.Bytes()[:afterHeader]...)
+	data = append(data, pngChunk("eXIf", string(exifTIFF()))...)
+	data = append(data, pngChunk("tEXt", "XML:com.adobe.xmp\x00<x:xmpmeta/>")...)
+	data = append(data, pngChunk("tEXt", "Raw profile type exif\x00exif")...)
+	data = append(data, pngChunk("tEXt", "Comment\x00kept")...)
+	data = append(data, encoded.Bytes()[afterHeader:]...)
+
+	out := StripPNGMetadata(data)
+
+	if _, err := png.Decode(bytes.NewReader(out)); err != nil {
+		t.Fatalf("stripped PNG does not decode: %v", err)
+	}
+	for _, removed := range []string{"eXIf", "xmpmeta", "Raw profile"} {
+		if bytes.Contains(out, []byte(removed)) {
+			t.Errorf("%s was kept", removed)
+		}
+	}
+	if !bytes.Contains(out, []byte("Comment\x00kept")) {
+		t.Error("comment chunk was removed")
+	}
+}
+
+func TestStripMetadataOfOtherFiles(t *testing.T) {
+	for _, data := range [][]byte{nil, []byte("GIF89a"), {0xFF, 0xD8}} {
+		if out := StripJPEGMetadata(data); !bytes.Equal(out, data) {
+			t.Errorf("StripJPEGMetadata(%x) = %x, want it unchanged", data, out)
+		}
+		if out := StripPNGMetadata(data); !bytes.Equal(out, data) {
+			t.Errorf("StripPNGMetadata(%x) = %x, want it unchanged", data, out)
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		want          image.Point
+	}{
+		{"landscape", 800, 400, image.Pt(400, 200)},
+		{"portrait", 400, 4000, image.Pt(40, 400)},
+		{"sliver", 1, 1000, image.Pt(1, 400)},
+		{"small", 100, 50, image.Pt(100, 50)},
+	}
+
+	teal := color.RGBA{0, 128, 128, 255}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumb := Thumbnail(testImage(tt.width, tt.height, teal), 400)
+			if got := thumb.Bounds().Size(); got != tt.want {
+				t.Fatalf("size = %v, want %v", got, tt.want)
+			}
+			if got := thumb.RGBAAt(tt.want.X-1, tt.want.Y-1); got != teal {
+				t.Errorf("corner = %v, want %v", got, teal)
+			}
+		})
+	}
+}
+
+func TestThumbnailAverages(t *testing.T) {
+	// A black half and a white half average to grey
+	halves := image.NewGray(image.Rect(0, 0, 8, 2))
+	for x := 4; x < 8; x++ {
+		halves.SetGray(x, 0, color.Gray{255})
+		halves.SetGray(x, 1, color.Gray{255})
+	}
+	if got := Thumbnail(halves, 1).RGBAAt(0, 0); got != (color.RGBA{127, 127, 127, 255}) {
+		t.Errorf("average = %v, want grey", got)
+	}
+
+	// Sub-images are read from their own bounds
+	img := testImage(20, 20, color.White)
+	for y := 10; y < 20; y++ {
+		for x := 10; x < 20; x++ {
+			img.Set(x, y, color.Black)
+		}
+	}
+	corner := img.SubImage(image.Rect(10, 10, 20, 20))
+	if got := Thumbnail(corner, 5).RGBAAt(4, 4); got != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("sub-image thumbnail = %v, want black", got)
+	}
+}
@@ -1,15 +1,24 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"time"
 	"gatorswamp/config"
 	"github.com/dgrijalva/jwt-go"
 )
 
-func GenerateToken(userID string) (string, error) {
+// AccessTokenTTL is how long an access token stays valid
+const AccessTokenTTL = 15 * time.Minute
+
+// GenerateToken issues a short-lived access token bound to a session
+func GenerateToken(userID string, sessionID string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"userID": userID,
-		"exp":    time.Now().Add(time.Hour * 24).Unix(),
+		"sid":    sessionID,
+		"exp":    time.Now().Add(AccessTokenTTL).Unix(),
 	})
 	return token.SignedString([]byte(config.JwtSecretKey()))
 }
@@ -24,3 +33,18 @@ func ValidateToken(token string) (*jwt.MapClaims, error) {
 	}
 	return &claims, nil
 }
+
+// GenerateOpaqueToken returns a random URL-safe token
+func GenerateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex SHA-256 digest under which an opaque token is stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
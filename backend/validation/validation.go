@@ -0,0 +1,140 @@
+// Package validation checks request bodies against their validate struct
+// tags and reports every invalid field by its JSON name
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"gatorswamp/services"
+	"github.com/go-playground/validator/v10"
+)
+
+// Password policy enforced by the "password" tag. bcrypt ignores anything
+// past 72 bytes, so longer passwords are refused rather than truncated
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+var validate = newValidator()
+
+// newValidator creates a validator that names fields after their JSON keys
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	v.RegisterValidation("password", isStrongPassword)
+	return v
+}
+
+// isStrongPassword requires MinPasswordLength to MaxPasswordLength bytes with
+// an upper case letter, a lower case letter and a digit
+func isStrongPassword(fl validator.FieldLevel) bool {
+	password := fl.Field().String()
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return false
+	}
+
+	var upper, lower, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
+
+// Struct validates s and returns a services validation error listing every
+// invalid field, or nil when s is valid
+func Struct(s any) error {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return err
+	}
+
+	fields := make([]services.FieldError, 0, len(invalid))
+	for _, fe := range invalid {
+		fields = append(fields, services.FieldError{
+			Field:   fieldPath(fe),
+			Code:    fe.Tag(),
+			Message: message(fe),
+		})
+	}
+	return services.ValidationError(fields...)
+}
+
+// fieldPath returns the JSON path of the field, such as "images[0].id",
+// without the name of the validated struct
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+// message explains a failed rule in English
+func message(fe validator.FieldError) string {
+	field := fe.Field()
+	isString := fe.Kind() == reflect.String
+
+	switch fe.Tag() {
+	case "required", "required_if":
+		return fmt.Sprintf("%s is required", field)
+	case "email":
+		return fmt.Sprintf("%s must be a valid email address", field)
+	case "password":
+		return fmt.Sprintf("%s must be %d to %d characters and include an upper case letter, a lower case letter and a digit",
+			field, MinPasswordLength, MaxPasswordLength)
+	case "min":
+		if isString {
+			return fmt.Sprintf("%s must be at least %s characters", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must contain at least %s items", field, fe.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("%s must be at most %s characters", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must contain at most %s items", field, fe.Param())
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", field, fe.Param())
+	case "gte":
+		return fmt.Sprintf("%s must be at least %s", field, fe.Param())
+	case "lte":
+		return fmt.Sprintf("%s must be at most %s", field, fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, strings.Join(strings.Fields(fe.Param()), ", "))
+	case "latitude":
+		return fmt.Sprintf("%s must be between -90 and 90", field)
+	case "longitude":
+		return fmt.Sprintf("%s must be between -180 and 180", field)
+	case "iso4217":
+		return fmt.Sprintf("%s must be an ISO 4217 currency code", field)
+	case "mongodb":
+		return fmt.Sprintf("%s must be a valid ID", field)
+	default:
+		return fmt.Sprintf("%s is invalid", field)
+	}
+}
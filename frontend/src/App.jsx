@@ -5,6 +5,8 @@ import Footer from "./components/Footer";
 import Home from "./pages/Home";
 import PropertyDetails from "./pages/PropertyDetails";
 import HomeSlider from "./pages/HomeSlider";
+import ResetPassword from "./pages/ResetPassword";
+import VerifyEmail from "./pages/VerifyEmail";
 
 const App = () => {
   return (
@@ -14,6 +16,8 @@ const App = () => {
         <Route path="/" element={<HomeSlider />} />
         <Route path="/Home" element={<Home />} />
         <Route path="/property/:id" element={<PropertyDetails />} />
+        <Route path="/reset-password" element={<ResetPassword />} />
+        <Route path="/verify-email" element={<VerifyEmail />} />
       </Routes>
       <Footer />
     </div>
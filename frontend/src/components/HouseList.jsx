@@ -25,7 +25,8 @@ const calculateCenter = (properties) => {
 };
 
 const HouseList = () => {
-  const { houses, loading } = useContext(HouseContext);
+  const { houses, loading, total, hasMore, loadMore, loadingMore } =
+    useContext(HouseContext);
 
   const propertiesCenter = calculateCenter(houses);
 
@@ -122,7 +123,7 @@ const HouseList = () => {
                     <div className="cursor-pointer hover:scale-110 transition-transform">
                       <MapPin className="h-8 w-8 text-green-600" />
                       <span className="absolute text-xs font-bold text-black">
-                        ${(property.price / 1000).toFixed(0)}k
+                        ${(property.priceCents / 100000).toFixed(0)}k
                       </span>
                     </div>
                   </Marker>
@@ -157,7 +158,7 @@ const HouseList = () => {
         {/* Scrollable Properties List - Right Side */}
         <div className="md:w-1/2 lg:w-5/12 max-h-screen overflow-y-auto pb-8 pr-4">
           <h2 className="text-2xl font-semibold mb-4 text-gray-800">
-            Available Properties ({houses.length})
+            Available Properties ({total ?? houses.length})
           </h2>
           <div className="grid grid-cols-1 gap-6">
             {houses.map((house, index) => (
@@ -172,6 +173,20 @@ const HouseList = () => {
               </Link>
             ))}
           </div>
+          {hasMore && (
+            <button
+              type="button"
+              onClick={loadMore}
+              disabled={loadingMore}
+              className="w-full mt-6 py-3 rounded-lg bg-violet-700 text-white hover:bg-violet-800 transition disabled:opacity-60"
+            >
+              {loadingMore ? (
+                <ImSpinner2 className="mx-auto animate-spin text-xl" />
+              ) : (
+                "Load more"
+              )}
+            </button>
+          )}
         </div>
       </div>
     </div>
@@ -2,7 +2,8 @@ import { useState } from "react";
 import { Star } from "lucide-react";
 
 const PropertyCard = ({ house }) => {
-  const { image, type, name, address, bedrooms, bathrooms, price } = house;
+  const { image, type, name, address, bedrooms, bathrooms, priceCents, priceDrop, highlights } =
+    house;
 
   const [rating] = useState(() => Math.floor(Math.random() * 5) + 1);
   const [reviews] = useState(() => Math.floor(Math.random() * 100) + 1);
@@ -27,9 +28,21 @@ const PropertyCard = ({ house }) => {
             <h4 className="text-xs font-light leading-tight truncate">
               {address}
             </h4>
+            {highlights?.description && (
+              // The server escapes the snippet and only adds <mark> tags
+              <p
+                className="mt-1 text-xs text-gray-600 line-clamp-2"
+                dangerouslySetInnerHTML={{ __html: highlights.description }}
+              />
+            )}
             <div className="mt-1">
-              ${Number(price).toLocaleString()}{" "}
+              ${(priceCents / 100).toLocaleString()}{" "}
               <span className="text-gray-600 text-sm">/ month</span>
+              {priceDrop && (
+                <span className="ml-2 inline-block bg-red-100 text-red-700 text-xs px-2 rounded-full font-semibold">
+                  &darr; {priceDrop.percent}% in {priceDrop.days} days
+                </span>
+              )}
             </div>
             <div className="mt-2 flex items-center">
               {[...Array(5)].map((e, i) => (
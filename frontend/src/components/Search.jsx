@@ -11,10 +11,18 @@ import BedsDropdown from "./BedDropdown";
 import BathsDropdown from "./BathDropdown";
 
 const Search = () => {
-  const { handleClick } = useContext(HouseContext);
+  const { handleClick, query, setQuery } = useContext(HouseContext);
 
   return (
     <div className="px-[30px] py-6 max-w-[1320px] mx-auto flex flex-col lg:flex-row justify-between gap-4 lg:gap-x-3 relative lg:-top-4 lg:shadow-1 bg-white lg:bg-white/50 lg:backdrop-blur rounded-lg">
+      <input
+        type="search"
+        value={query}
+        onChange={(e) => setQuery(e.target.value)}
+        onKeyDown={(e) => e.key === "Enter" && handleClick()}
+        placeholder='Search, e.g. "pool house" gain*'
+        className="w-full lg:max-w-[296px] h-16 px-[18px] border rounded-lg text-[15px] focus:outline-none"
+      />
       <CountryDropdown />
       <PropertyDropdown />
       <PriceRangeDropdown />
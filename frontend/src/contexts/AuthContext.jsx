@@ -8,6 +8,25 @@ export function AuthProvider({ children }) {
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState("");
 
+  // Exchange the refresh token cookie for a new access token
+  const refreshSession = async () => {
+    const response = await fetch("/api/users/refresh", {
+      method: "POST",
+      headers: {
+        "Content-Type": "application/json",
+      },
+      credentials: "include",
+    });
+
+    if (!response.ok) {
+      throw new Error(`Session refresh failed: ${response.status}`);
+    }
+
+    const data = await response.json();
+    localStorage.setItem("authToken", data.token);
+    return data;
+  };
+
   // Check if user is authenticated on load
   useEffect(() => {
     const checkAuthStatus = async () => {
@@ -18,7 +37,7 @@ export function AuthProvider({ children }) {
           return;
         }
 
-        const response = await fetch("/api/users/auth/status", {
+        let response = await fetch("/api/users/auth/status", {
           method: "GET",
           headers: {
             "Content-Type": "application/json",
@@ -27,6 +46,19 @@ export function AuthProvider({ children }) {
           credentials: "include",
         });
 
+        // Access tokens are short-lived; try once to refresh the session
+        if (response.status === 401) {
+          const refreshed = await refreshSession();
+          response = await fetch("/api/users/auth/status", {
+            method: "GET",
+            headers: {
+              "Content-Type": "application/json",
+              Authorization: `Bearer ${refreshed.token}`,
+            },
+            credentials: "include",
+          });
+        }
+
         if (!response.ok) {
           throw new Error(`Authentication check failed: ${response.status}`);
         }
@@ -49,6 +81,22 @@ export function AuthProvider({ children }) {
     checkAuthStatus();
   }, []);
 
+  // Keep the access token fresh while signed in
+  useEffect(() => {
+    if (!isAuthenticated) return;
+
+    const interval = setInterval(() => {
+      refreshSession().catch((error) => {
+        console.error("Session refresh error:", error);
+        setCurrentUser(null);
+        setIsAuthenticated(false);
+        localStorage.removeItem("authToken");
+      });
+    }, 10 * 60 * 1000);
+
+    return () => clearInterval(interval);
+  }, [isAuthenticated]);
+
   // Login function
   const login = async (email, password) => {
     try {
@@ -64,7 +112,7 @@ export function AuthProvider({ children }) {
 
       if (!response.ok) {
         const errorData = await response.json();
-        throw new Error(errorData.error || "Login failed");
+        throw new Error(errorData.detail || "Login failed");
       }
 
       const data = await response.json();
@@ -100,7 +148,7 @@ export function AuthProvider({ children }) {
 
       if (!response.ok) {
         const errorData = await response.json();
-        throw new Error(errorData.error || "Registration failed");
+        throw new Error(errorData.detail || "Registration failed");
       }
 
       const data = await response.json();
@@ -3,7 +3,6 @@ import { useState, useEffect, createContext } from "react";
 export const HouseContext = createContext();
 
 const HouseContextProvider = ({ children }) => {
-  const [houses, setHouses] = useState([]);
   const [filteredHouses, setFilteredHouses] = useState([]);
   const [county, setCounty] = useState("Location (any)");
   const [counties, setCounties] = useState([]);
@@ -12,9 +11,15 @@ const HouseContextProvider = ({ children }) => {
   const [price, setPrice] = useState("Price range (any)");
   const [bath, setBath] = useState("Bathrooms count (any)");
   const [bed, setBed] = useState("Bedrooms count (any)");
+  const [query, setQuery] = useState("");
   const [loading, setLoading] = useState(false);
+  // The listing page shown and how to fetch the next one
+  const [pageUrl, setPageUrl] = useState("/api/housing/all");
+  const [nextCursor, setNextCursor] = useState("");
+  const [total, setTotal] = useState(null);
+  const [loadingMore, setLoadingMore] = useState(false);
 
-  // Fetch houses data
+  // Fetch the first page of listings
   useEffect(() => {
     const fetchHouses = async () => {
       setLoading(true);
@@ -22,8 +27,9 @@ const HouseContextProvider = ({ children }) => {
         const res = await fetch("/api/housing/all");
         if (!res.ok) throw new Error("Failed to fetch properties");
         const data = await res.json();
-        setHouses(data);
-        setFilteredHouses(data); // Initialize filtered houses with all houses
+        setFilteredHouses(data.items);
+        setNextCursor(data.nextCursor || "");
+        setTotal(data.total);
       } catch (error) {
         console.error("Error fetching housing data:", error);
       } finally {
@@ -34,66 +40,97 @@ const HouseContextProvider = ({ children }) => {
     fetchHouses();
   }, []);
 
-  // Extract unique counties
+  // Fill the county and property type dropdowns
   useEffect(() => {
-    if (!houses.length) return;
-
-    const uniqueCounties = [
-      "Location (any)",
-      ...new Set(houses.map((house) => house.county)),
-    ];
-    uniqueCounties.sort((a, b) => a.localeCompare(b));
-    setCounties(uniqueCounties);
-  }, [houses]);
+    const fetchFacets = async () => {
+      try {
+        const res = await fetch("/api/housing/facets");
+        if (!res.ok) throw new Error("Failed to fetch housing facets");
+        const data = await res.json();
+        setCounties(["Location (any)", ...data.counties]);
+        setProperties(["Property type (any)", ...data.types]);
+      } catch (error) {
+        console.error("Error fetching housing facets:", error);
+      }
+    };
 
-  // Extract unique property types
-  useEffect(() => {
-    if (!houses.length) return;
+    fetchFacets();
+  }, []);
 
-    const uniqueProperties = [
-      "Property type (any)",
-      ...new Set(houses.map((house) => house.type)),
-    ];
-    setProperties(uniqueProperties);
-  }, [houses]);
+  // Append the next page of the listings shown
+  const loadMore = async () => {
+    if (!nextCursor || loadingMore) return;
+
+    setLoadingMore(true);
+    try {
+      const separator = pageUrl.includes("?") ? "&" : "?";
+      const res = await fetch(
+        `${pageUrl}${separator}cursor=${encodeURIComponent(nextCursor)}`
+      );
+      if (!res.ok) throw new Error("Failed to fetch properties");
+      const data = await res.json();
+      setFilteredHouses((houses) => [...houses, ...data.items]);
+      setNextCursor(data.nextCursor || "");
+    } catch (error) {
+      console.error("Error loading more housing data:", error);
+    } finally {
+      setLoadingMore(false);
+    }
+  };
 
   // Helper function to check if a filter is in default state
   const isDefault = (str) => str.includes("(any)");
 
+  // Build the query string for the server-side search
+  const buildSearchParams = () => {
+    const params = new URLSearchParams();
+
+    // Free text ranks the results by relevance
+    if (query.trim()) params.set("q", query.trim());
+    if (!isDefault(county)) params.set("county", county);
+    if (!isDefault(property)) params.set("type", property);
+    if (!isDefault(price)) {
+      const [minPrice, maxPrice] = price.split(" - ");
+      params.set("minPrice", minPrice);
+      params.set("maxPrice", maxPrice);
+    }
+    if (!isDefault(bed)) {
+      params.set("minBedrooms", bed);
+      params.set("maxBedrooms", bed);
+    }
+    if (!isDefault(bath)) {
+      params.set("minBathrooms", bath);
+      params.set("maxBathrooms", bath);
+    }
+
+    return params;
+  };
+
   // Apply filters when the search button is clicked
-  const handleClick = () => {
+  const handleClick = async () => {
     setLoading(true);
-
-    // Parse price range
-    const minPrice = price.includes("(any)")
-      ? 0
-      : parseInt(price.split(" ")[0]);
-    const maxPrice = price.includes("(any)")
-      ? Number.MAX_SAFE_INTEGER
-      : parseInt(price.split(" ")[2]);
-
-    // Apply all filters
-    const filtered = houses.filter((house) => {
-      const housePrice = parseInt(house.price);
-
-      // Create an array of filter conditions
-      const conditions = [
-        isDefault(county) || house.county === county,
-        isDefault(property) || house.type === property,
-        isDefault(price) || (housePrice >= minPrice && housePrice <= maxPrice),
-        isDefault(bed) || house.bedrooms == bed,
-        isDefault(bath) || house.bathrooms == bath,
-      ];
-
-      // All conditions must be true for the house to be included
-      return conditions.every((condition) => condition === true);
-    });
-
-    // Update filtered houses with a small delay for UX feedback
-    setTimeout(() => {
-      setFilteredHouses(filtered);
+    try {
+      const url = `/api/housing/search?${buildSearchParams()}`;
+      const res = await fetch(url);
+      if (!res.ok) throw new Error("Failed to search properties");
+      const data = await res.json();
+
+      // Text searches answer ranked matches, filters a page of listings
+      if (Array.isArray(data)) {
+        setFilteredHouses(data);
+        setNextCursor("");
+        setTotal(data.length);
+      } else {
+        setFilteredHouses(data.items);
+        setNextCursor(data.nextCursor || "");
+        setTotal(data.total);
+      }
+      setPageUrl(url);
+    } catch (error) {
+      console.error("Error searching housing data:", error);
+    } finally {
       setLoading(false);
-    }, 1000);
+    }
   };
 
   return (
@@ -110,10 +147,16 @@ const HouseContextProvider = ({ children }) => {
         houses: filteredHouses, // Use filtered houses instead of all houses
         loading,
         handleClick,
+        total,
+        hasMore: nextCursor !== "",
+        loadMore,
+        loadingMore,
         bath,
         setBath,
         bed,
         setBed,
+        query,
+        setQuery,
       }}
     >
       {children}
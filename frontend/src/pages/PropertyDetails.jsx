@@ -122,6 +122,37 @@ const PropertyDetails = () => {
     }
   };
 
+  const handleWithdrawClick = async (e) => {
+    e.preventDefault();
+    if (!existingRequest) return;
+
+    try {
+      setFormSubmitting(true);
+      const token = localStorage.getItem("authToken");
+      const response = await fetch(`/api/requests/${existingRequest.id}`, {
+        method: "DELETE",
+        headers: {
+          "Content-Type": "application/json",
+          Authorization: `Bearer ${token}`,
+        },
+        credentials: "include",
+      });
+
+      if (!response.ok) {
+        throw new Error(`Withdrawal failed: ${response.status}`);
+      }
+
+      const res = await response.json();
+      setExistingRequest(res);
+      setRequestStatus(res.status);
+    } catch (err) {
+      console.error("Withdrawal error:", err);
+      setError(err.message);
+    } finally {
+      setFormSubmitting(false);
+    }
+  };
+
   const closeModal = () => {
     setShowModal(false);
   };
@@ -199,12 +230,18 @@ const PropertyDetails = () => {
       pending: "bg-yellow-100 text-yellow-700 border-yellow-300",
       approved: "bg-green-100 text-green-700 border-green-300",
       rejected: "bg-red-100 text-red-700 border-red-300",
+      withdrawn: "bg-gray-100 text-gray-700 border-gray-300",
+      lease_signed: "bg-green-100 text-green-700 border-green-300",
+      cancelled: "bg-gray-100 text-gray-700 border-gray-300",
     };
 
     const statusMessages = {
       pending: "Your application is pending review",
       approved: "Your application has been approved!",
       rejected: "Your application was not approved",
+      withdrawn: "You withdrew this application",
+      lease_signed: "Your lease has been signed!",
+      cancelled: "This application was cancelled",
     };
 
     return (
@@ -213,9 +250,15 @@ const PropertyDetails = () => {
       >
         <h3 className="font-medium text-lg">
           Application Status:{" "}
-          {requestStatus.charAt(0).toUpperCase() + requestStatus.slice(1)}
+          {requestStatus.charAt(0).toUpperCase() +
+            requestStatus.slice(1).replace("_", " ")}
         </h3>
         <p>{statusMessages[requestStatus]}</p>
+        {existingRequest.rejectionReason && (
+          <p className="text-sm mt-2">
+            Reason: {existingRequest.rejectionReason}
+          </p>
+        )}
         <p className="text-sm mt-2">
           Submitted on:{" "}
           {new Date(existingRequest.createdAt).toLocaleDateString()}
@@ -236,20 +279,22 @@ const PropertyDetails = () => {
     return null; // Or a fallback UI
   }
 
-  const Images = [
-    house.image,
-    "https://images.unsplash.com/photo-1616137466211-f939a420be84?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1632&q=80",
-    "https://images.unsplash.com/photo-1604709177225-055f99402ea3?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1770&q=80",
-    "https://images.unsplash.com/photo-1593696140826-c58b021acf8b?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1470&q=80",
-    "https://images.unsplash.com/photo-1616486029423-aaa4789e8c9a?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1632&q=80",
-    "https://images.unsplash.com/photo-1612965607446-25e1332775ae?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1470&q=80",
-    "https://images.unsplash.com/photo-1484154218962-a197022b5858?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1474&q=80",
-    "https://images.unsplash.com/photo-1560448204-61dc36dc98c8?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1470&q=80",
-    "https://images.unsplash.com/photo-1503174971373-b1f69850bded?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1513&q=80",
-    "https://images.unsplash.com/photo-1616137507072-f7276b168614?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1632&q=80",
-    "https://images.unsplash.com/photo-1432303492674-642e9d0944b2?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1774&q=80",
-    "https://images.unsplash.com/photo-1596178067639-5c6e68aea6dc?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1770&q=80",
-  ];
+  const Images = house.images?.length
+    ? house.images.map((image) => image.url)
+    : [
+        house.image,
+        "https://images.unsplash.com/photo-1616137466211-f939a420be84?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1632&q=80",
+        "https://images.unsplash.com/photo-1604709177225-055f99402ea3?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1770&q=80",
+        "https://images.unsplash.com/photo-1593696140826-c58b021acf8b?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1470&q=80",
+        "https://images.unsplash.com/photo-1616486029423-aaa4789e8c9a?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1632&q=80",
+        "https://images.unsplash.com/photo-1612965607446-25e1332775ae?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1470&q=80",
+        "https://images.unsplash.com/photo-1484154218962-a197022b5858?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1474&q=80",
+        "https://images.unsplash.com/photo-1560448204-61dc36dc98c8?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1470&q=80",
+        "https://images.unsplash.com/photo-1503174971373-b1f69850bded?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1513&q=80",
+        "https://images.unsplash.com/photo-1616137507072-f7276b168614?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1632&q=80",
+        "https://images.unsplash.com/photo-1432303492674-642e9d0944b2?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1774&q=80",
+        "https://images.unsplash.com/photo-1596178067639-5c6e68aea6dc?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=1770&q=80",
+      ];
 
   return (
     <section className="px-4 py-6 sm:px-6 lg:px-8">
@@ -266,7 +311,7 @@ const PropertyDetails = () => {
               </h3>
             </div>
             <div className="text-2xl sm:text-3xl font-semibold text-neutral-600">
-              ${Number(house.price).toLocaleString()}
+              ${(house.priceCents / 100).toLocaleString()}
               <span className="text-gray-600 font-medium text-sm">/ month</span>
             </div>
           </div>
@@ -315,25 +360,31 @@ const PropertyDetails = () => {
             <h1 className="antialiased text-gray-700 font-semibold text-2xl sm:text-3xl mb-4">
               About {house.name}
             </h1>
-            <div className="antialiased font-light leading-snug tracking-wide text-stone-900">
-              <div>
-                {`Welcome to a charming and cozy ${house.bedrooms}-bedroom, ${
-                  house.bathrooms
-                }-bathroom ${house.type.toLowerCase()}
-              situated in a quiet and peaceful neighborhood. From the moment you
-              step inside, you will be greeted by a warm and inviting living
-              room, complete with hardwood floors and plenty of natural light.
-             `}
+            {house.description ? (
+              <div className="antialiased font-light leading-snug tracking-wide text-stone-900 whitespace-pre-line">
+                {house.description}
               </div>
-              <div className="mt-3">
-                The kitchen is compact yet functional, with modern appliances,
-                ample cabinet space, and a cozy breakfast nook perfect for
-                enjoying your morning coffee. The two bedrooms are comfortable
-                and provide a peaceful retreat at the end of a long day. The
-                bathroom is stylishly designed and features a modern sink and a
-                walk-in shower.
+            ) : (
+              <div className="antialiased font-light leading-snug tracking-wide text-stone-900">
+                <div>
+                  {`Welcome to a charming and cozy ${house.bedrooms}-bedroom, ${
+                    house.bathrooms
+                  }-bathroom ${house.type.toLowerCase()}
+                situated in a quiet and peaceful neighborhood. From the moment you
+                step inside, you will be greeted by a warm and inviting living
+                room, complete with hardwood floors and plenty of natural light.
+               `}
+                </div>
+                <div className="mt-3">
+                  The kitchen is compact yet functional, with modern appliances,
+                  ample cabinet space, and a cozy breakfast nook perfect for
+                  enjoying your morning coffee. The two bedrooms are comfortable
+                  and provide a peaceful retreat at the end of a long day. The
+                  bathroom is stylishly designed and features a modern sink and a
+                  walk-in shower.
+                </div>
               </div>
-            </div>
+            )}
           </div>
 
           {/* Right Column - Contact Form */}
@@ -409,6 +460,14 @@ const PropertyDetails = () => {
                     >
                       {formSubmitting ? "Submitting..." : "Apply"}
                     </button>
+                  ) : requestStatus === "pending" ? (
+                    <button
+                      onClick={handleWithdrawClick}
+                      className="bg-gray-100 hover:bg-gray-200 text-gray-600 rounded p-3 text-sm w-full transition"
+                      disabled={formSubmitting}
+                    >
+                      {formSubmitting ? "Withdrawing..." : "Withdraw Application"}
+                    </button>
                   ) : (
                     <div className="bg-gray-100 text-gray-600 rounded p-3 text-sm w-full text-center">
                       Application Submitted
@@ -0,0 +1,123 @@
+import { useState } from "react";
+import { Link, useSearchParams } from "react-router-dom";
+
+const ResetPassword = () => {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get("token");
+
+  const [password, setPassword] = useState("");
+  const [confirm, setConfirm] = useState("");
+  const [submitting, setSubmitting] = useState(false);
+  const [done, setDone] = useState(false);
+  const [error, setError] = useState(
+    token ? null : "This reset link is incomplete."
+  );
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    if (password !== confirm) {
+      setError("The passwords do not match.");
+      return;
+    }
+
+    setSubmitting(true);
+    setError(null);
+    try {
+      const response = await fetch("/api/users/password/reset", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ token, password }),
+      });
+      const data = await response.json();
+      if (!response.ok) {
+        const fieldError = data.errors && data.errors[0];
+        throw new Error(
+          (fieldError && fieldError.message) ||
+            data.detail ||
+            "This reset link is invalid or has expired."
+        );
+      }
+      setDone(true);
+    } catch (err) {
+      setError(err.message);
+    } finally {
+      setSubmitting(false);
+    }
+  };
+
+  if (done) {
+    return (
+      <div className="container mx-auto max-w-md py-24 px-4 text-center">
+        <h1 className="text-2xl font-semibold text-sky-700 mb-6">
+          Password reset
+        </h1>
+        <p className="text-green-700 mb-6">
+          Your password has been changed. Sign in with your new password.
+        </p>
+        <Link to="/Home" className="text-violet-700 hover:underline">
+          Browse listings
+        </Link>
+      </div>
+    );
+  }
+
+  return (
+    <div className="container mx-auto max-w-md py-24 px-4">
+      <h1 className="text-2xl font-semibold text-sky-700 mb-6 text-center">
+        Choose a new password
+      </h1>
+      <form className="space-y-4" onSubmit={handleSubmit}>
+        <div>
+          <label
+            htmlFor="password"
+            className="block text-sm font-medium text-gray-700 mb-1"
+          >
+            New password
+          </label>
+          <input
+            type="password"
+            id="password"
+            value={password}
+            onChange={(e) => setPassword(e.target.value)}
+            minLength={8}
+            maxLength={72}
+            required
+            disabled={!token}
+            className="w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm focus:outline-none focus:ring-sky-500 focus:border-sky-500"
+          />
+          <p className="text-xs text-gray-500 mt-1">
+            At least 8 characters with an upper case letter, a lower case
+            letter and a digit.
+          </p>
+        </div>
+        <div>
+          <label
+            htmlFor="confirm"
+            className="block text-sm font-medium text-gray-700 mb-1"
+          >
+            Confirm password
+          </label>
+          <input
+            type="password"
+            id="confirm"
+            value={confirm}
+            onChange={(e) => setConfirm(e.target.value)}
+            required
+            disabled={!token}
+            className="w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm focus:outline-none focus:ring-sky-500 focus:border-sky-500"
+          />
+        </div>
+        {error && <p className="text-sm text-red-600">{error}</p>}
+        <button
+          type="submit"
+          disabled={!token || submitting}
+          className="w-full bg-violet-700 hover:bg-violet-800 text-white px-4 py-3 rounded-lg transition disabled:opacity-60"
+        >
+          {submitting ? "Saving..." : "Reset password"}
+        </button>
+      </form>
+    </div>
+  );
+};
+
+export default ResetPassword;
@@ -0,0 +1,72 @@
+import { useEffect, useRef, useState } from "react";
+import { Link, useSearchParams } from "react-router-dom";
+import { ImSpinner2 } from "react-icons/im";
+
+const VerifyEmail = () => {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get("token");
+
+  const [status, setStatus] = useState(token ? "verifying" : "error");
+  const [message, setMessage] = useState(
+    token ? "" : "This verification link is incomplete."
+  );
+  // Tokens work once, so the request must not be repeated on re-render
+  const submitted = useRef(false);
+
+  useEffect(() => {
+    if (!token || submitted.current) return;
+    submitted.current = true;
+
+    const verify = async () => {
+      try {
+        const response = await fetch("/api/users/verify-email", {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ token }),
+        });
+        const data = await response.json();
+        if (!response.ok) {
+          throw new Error(data.detail || "This verification link is invalid or has expired.");
+        }
+        setStatus("verified");
+        setMessage(data.message);
+      } catch (error) {
+        setStatus("error");
+        setMessage(error.message);
+      }
+    };
+
+    verify();
+  }, [token]);
+
+  return (
+    <div className="container mx-auto max-w-md py-24 px-4 text-center">
+      <h1 className="text-2xl font-semibold text-sky-700 mb-6">
+        Email verification
+      </h1>
+      {status === "verifying" && (
+        <ImSpinner2 className="mx-auto animate-spin text-violet-700 text-4xl" />
+      )}
+      {status === "verified" && (
+        <p className="text-green-700 mb-6">
+          {message}. You can now request properties and book tours.
+        </p>
+      )}
+      {status === "error" && (
+        <>
+          <p className="text-red-600 mb-2">{message}</p>
+          <p className="text-gray-600 mb-6">
+            Sign in to have a new link sent.
+          </p>
+        </>
+      )}
+      {status !== "verifying" && (
+        <Link to="/Home" className="text-violet-700 hover:underline">
+          Browse listings
+        </Link>
+      )}
+    </div>
+  );
+};
+
+export default VerifyEmail;
@@ -12,6 +12,10 @@ export default defineConfig({
         target: "http://localhost:5500",
         changeOrigin: true,
       },
+      "/uploads": {
+        target: "http://localhost:5500",
+        changeOrigin: true,
+      },
     },
   },
   build: {